@@ -0,0 +1,270 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package changeset
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// DiffType is the type of a field-level difference between two configurations
+type DiffType int
+
+// List of supported DiffTypes
+const (
+	FieldChanged DiffType = iota
+	FieldAdded
+	FieldRemoved
+)
+
+func (t DiffType) String() string {
+	switch t {
+	case FieldAdded:
+		return "+"
+	case FieldRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// A FieldDiff is a difference in a single field between two configurations
+type FieldDiff struct {
+	// Path is the path to the field, such as instances[foo].shards[2].state
+	Path string
+
+	// Type is the type of the difference
+	Type DiffType
+
+	// Old is the old value of the field, or nil if the field was added
+	Old interface{}
+
+	// New is the new value of the field, or nil if the field was removed
+	New interface{}
+}
+
+func (d FieldDiff) String() string {
+	switch d.Type {
+	case FieldAdded:
+		return fmt.Sprintf("%v %s: %s", d.Type, d.Path, formatDiffValue(d.New))
+	case FieldRemoved:
+		return fmt.Sprintf("%v %s: %s", d.Type, d.Path, formatDiffValue(d.Old))
+	default:
+		return fmt.Sprintf("%v %s: %s -> %s", d.Type, d.Path, formatDiffValue(d.Old), formatDiffValue(d.New))
+	}
+}
+
+// A Diff is the list of field-level differences between two configurations,
+// ordered by field
+type Diff []FieldDiff
+
+// NewDiff compares two proto messages of the same type field by field. A nil
+// message is reported as the whole other message being added or removed
+func NewDiff(oldMsg, newMsg proto.Message) Diff {
+	var d Diff
+	switch oldNil, newNil := isNilMessage(oldMsg), isNilMessage(newMsg); {
+	case oldNil && newNil:
+	case oldNil:
+		d.add("", FieldAdded, nil, newMsg)
+	case newNil:
+		d.add("", FieldRemoved, oldMsg, nil)
+	default:
+		d.diffValues("", reflect.ValueOf(oldMsg), reflect.ValueOf(newMsg))
+	}
+	return d
+}
+
+// isNilMessage returns true if the message is nil or a nil pointer
+func isNilMessage(msg proto.Message) bool {
+	if msg == nil {
+		return true
+	}
+	v := reflect.ValueOf(msg)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// Empty returns true if there are no differences
+func (d Diff) Empty() bool { return len(d) == 0 }
+
+func (d Diff) String() string {
+	lines := make([]string, 0, len(d))
+	for _, fd := range d {
+		lines = append(lines, fd.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (d *Diff) diffValues(path string, oldVal, newVal reflect.Value) {
+	switch oldVal.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		switch {
+		case oldVal.IsNil() && newVal.IsNil():
+		case oldVal.IsNil():
+			d.add(path, FieldAdded, nil, newVal.Interface())
+		case newVal.IsNil():
+			d.add(path, FieldRemoved, oldVal.Interface(), nil)
+		case oldVal.Elem().Type() != newVal.Elem().Type():
+			// Different oneof choices
+			d.add(path, FieldChanged, oldVal.Interface(), newVal.Interface())
+		default:
+			d.diffValues(path, oldVal.Elem(), newVal.Elem())
+		}
+
+	case reflect.Struct:
+		t := oldVal.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name, ok := protoFieldName(t.Field(i))
+			if !ok {
+				continue
+			}
+			d.diffValues(joinPath(path, name), oldVal.Field(i), newVal.Field(i))
+		}
+
+	case reflect.Slice:
+		if oldVal.Type().Elem().Kind() == reflect.Uint8 {
+			if !bytes.Equal(oldVal.Bytes(), newVal.Bytes()) {
+				d.add(path, FieldChanged, oldVal.Interface(), newVal.Interface())
+			}
+			return
+		}
+		for i := 0; i < oldVal.Len() || i < newVal.Len(); i++ {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= newVal.Len():
+				d.add(elemPath, FieldRemoved, oldVal.Index(i).Interface(), nil)
+			case i >= oldVal.Len():
+				d.add(elemPath, FieldAdded, nil, newVal.Index(i).Interface())
+			default:
+				d.diffValues(elemPath, oldVal.Index(i), newVal.Index(i))
+			}
+		}
+
+	case reflect.Map:
+		for _, key := range sortedMapKeys(oldVal, newVal) {
+			var (
+				elemPath = fmt.Sprintf("%s[%v]", path, key.Interface())
+				oldElem  = oldVal.MapIndex(key)
+				newElem  = newVal.MapIndex(key)
+			)
+			switch {
+			case !newElem.IsValid():
+				d.add(elemPath, FieldRemoved, oldElem.Interface(), nil)
+			case !oldElem.IsValid():
+				d.add(elemPath, FieldAdded, nil, newElem.Interface())
+			default:
+				d.diffValues(elemPath, oldElem, newElem)
+			}
+		}
+
+	default:
+		if oldVal.Interface() != newVal.Interface() {
+			d.add(path, FieldChanged, oldVal.Interface(), newVal.Interface())
+		}
+	}
+}
+
+func (d *Diff) add(path string, t DiffType, oldVal, newVal interface{}) {
+	*d = append(*d, FieldDiff{Path: path, Type: t, Old: oldVal, New: newVal})
+}
+
+// protoFieldName returns the proto name of a field of a generated message,
+// skipping fields that are not part of the message such as XXX_ fields
+func protoFieldName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" || strings.HasPrefix(f.Name, "XXX_") {
+		return "", false
+	}
+	if tag := f.Tag.Get("protobuf"); tag != "" {
+		for _, part := range strings.Split(tag, ",") {
+			if strings.HasPrefix(part, "name=") {
+				return strings.TrimPrefix(part, "name="), true
+			}
+		}
+	}
+	if f.Tag.Get("protobuf_oneof") != "" {
+		return f.Tag.Get("protobuf_oneof"), true
+	}
+	return "", false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func sortedMapKeys(maps ...reflect.Value) []reflect.Value {
+	var (
+		seen = make(map[interface{}]struct{})
+		keys []reflect.Value
+	)
+	for _, m := range maps {
+		for _, key := range m.MapKeys() {
+			if _, ok := seen[key.Interface()]; ok {
+				continue
+			}
+			seen[key.Interface()] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+	sort.Sort(mapKeysByValue(keys))
+	return keys
+}
+
+// mapKeysByValue orders map keys numerically for integer keys, lexically
+// for string keys, and false before true for bool keys, which covers all
+// the key types proto maps allow
+type mapKeysByValue []reflect.Value
+
+func (k mapKeysByValue) Len() int { return len(k) }
+
+func (k mapKeysByValue) Less(i, j int) bool {
+	switch k[i].Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return k[i].Int() < k[j].Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return k[i].Uint() < k[j].Uint()
+	case reflect.String:
+		return k[i].String() < k[j].String()
+	case reflect.Bool:
+		return !k[i].Bool() && k[j].Bool()
+	default:
+		return fmt.Sprint(k[i].Interface()) < fmt.Sprint(k[j].Interface())
+	}
+}
+
+func (k mapKeysByValue) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+
+func formatDiffValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case proto.Message:
+		return fmt.Sprintf("{%s}", proto.CompactTextString(v))
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package changeset
+
+import (
+	"testing"
+
+	"github.com/m3db/m3cluster/generated/proto/changesettest"
+	"github.com/m3db/m3cluster/generated/proto/placementpb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff_NoChanges(t *testing.T) {
+	diff := NewDiff(&changesettest.Config{Text: "foo"}, &changesettest.Config{Text: "foo"})
+	require.True(t, diff.Empty())
+	require.Equal(t, "", diff.String())
+}
+
+func TestDiff_RepeatedFields(t *testing.T) {
+	diff := NewDiff(
+		&changesettest.Changes{Lines: []string{"foo", "bar", "baz"}},
+		&changesettest.Changes{Lines: []string{"foo", "zed"}},
+	)
+	require.Equal(t, Diff{
+		{Path: "lines[1]", Type: FieldChanged, Old: "bar", New: "zed"},
+		{Path: "lines[2]", Type: FieldRemoved, Old: "baz"},
+	}, diff)
+	require.Equal(t, "~ lines[1]: \"bar\" -> \"zed\"\n- lines[2]: \"baz\"", diff.String())
+}
+
+func TestDiff_NestedMessages(t *testing.T) {
+	oldPlacement := &placementpb.Placement{
+		ReplicaFactor: 1,
+		NumShards:     2,
+		Instances: map[string]*placementpb.Instance{
+			"i1": {
+				Id:     "i1",
+				Weight: 1,
+				Shards: []*placementpb.Shard{
+					{Id: 0, State: placementpb.ShardState_AVAILABLE},
+					{Id: 1, State: placementpb.ShardState_AVAILABLE},
+				},
+			},
+		},
+	}
+	newPlacement := &placementpb.Placement{
+		ReplicaFactor: 1,
+		NumShards:     2,
+		Instances: map[string]*placementpb.Instance{
+			"i1": {
+				Id:     "i1",
+				Weight: 1,
+				Shards: []*placementpb.Shard{
+					{Id: 0, State: placementpb.ShardState_AVAILABLE},
+					{Id: 1, State: placementpb.ShardState_LEAVING},
+				},
+			},
+			"i2": {
+				Id:     "i2",
+				Weight: 1,
+			},
+		},
+	}
+
+	diff := NewDiff(oldPlacement, newPlacement)
+	require.Equal(t, 2, len(diff))
+	require.Equal(t, FieldDiff{
+		Path: "instances[i1].shards[1].state",
+		Type: FieldChanged,
+		Old:  placementpb.ShardState_AVAILABLE,
+		New:  placementpb.ShardState_LEAVING,
+	}, diff[0])
+	require.Equal(t, "instances[i2]", diff[1].Path)
+	require.Equal(t, FieldAdded, diff[1].Type)
+	require.Nil(t, diff[1].Old)
+	require.Equal(t, newPlacement.Instances["i2"], diff[1].New)
+
+	require.Equal(t, "~ instances[i1].shards[1].state: AVAILABLE -> LEAVING", diff[0].String())
+}
+
+func TestDiff_MapKeysOrderedByValue(t *testing.T) {
+	diff := NewDiff(&placementpb.Placement{}, &placementpb.Placement{
+		ShardSplits: map[uint32]*placementpb.ShardSplit{
+			10: {ChildShards: []uint32{10, 20}},
+			2:  {ChildShards: []uint32{2, 12}},
+			1:  {ChildShards: []uint32{1, 11}},
+		},
+	})
+	require.Equal(t, 3, len(diff))
+	require.Equal(t, "shard_splits[1]", diff[0].Path)
+	require.Equal(t, "shard_splits[2]", diff[1].Path)
+	require.Equal(t, "shard_splits[10]", diff[2].Path)
+}
+
+func TestDiff_NilMessages(t *testing.T) {
+	config := &changesettest.Config{Text: "foo"}
+	require.True(t, NewDiff(nil, nil).Empty())
+	require.True(t, NewDiff((*changesettest.Config)(nil), nil).Empty())
+	require.Equal(t, Diff{{Path: "", Type: FieldAdded, New: config}}, NewDiff(nil, config))
+	require.Equal(t, Diff{{Path: "", Type: FieldAdded, New: config}}, NewDiff((*changesettest.Config)(nil), config))
+	require.Equal(t, Diff{{Path: "", Type: FieldRemoved, Old: config}}, NewDiff(config, nil))
+}
@@ -23,13 +23,19 @@ package changeset
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/m3db/m3cluster/generated/proto/changesetpb"
 	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3x/clock"
 	"github.com/m3db/m3x/log"
 )
 
+const (
+	defaultHistoryLimit = 100
+)
+
 var (
 	// ErrAlreadyCommitted is returned when attempting to commit an already
 	// committed ChangeSet
@@ -47,6 +53,57 @@ var (
 	// a version that doesn't exist
 	ErrUnknownVersion = errors.New("unknown version")
 
+	// ErrNotStale is returned when attempting to rebase a ChangeSet that is
+	// already built on the latest version of the configuration
+	ErrNotStale = errors.New("change set is not stale")
+
+	// ErrRebaseInProgress is returned when attempting to rebase a ChangeSet
+	// that is concurrently being changed or rebased
+	ErrRebaseInProgress = errors.New("rebase in progress")
+
+	// ErrChangeSetNotClosed is returned when attempting to reopen or roll
+	// forward a ChangeSet that is not closed
+	ErrChangeSetNotClosed = errors.New("change set not closed")
+
+	// ErrConcurrentChange is returned when a ChangeSet is modified while
+	// attempting to discard or reopen it
+	ErrConcurrentChange = errors.New("change set concurrently modified")
+
+	// ErrCommitNotWritten is returned when attempting to roll forward a
+	// ChangeSet whose configuration has moved on to a version that was not
+	// produced by committing the ChangeSet
+	ErrCommitNotWritten = errors.New("configuration changed without the change set commit")
+
+	// ErrSelfApproval is returned when the author of a ChangeSet attempts to
+	// approve or reject it
+	ErrSelfApproval = errors.New("change set author cannot review own changes")
+
+	// ErrAuthorChanged is returned when attempting to change the author of a
+	// ChangeSet once it has been set
+	ErrAuthorChanged = errors.New("change set author cannot be changed")
+
+	// ErrAuthorNotSet is returned when attempting to approve or reject a
+	// ChangeSet whose author has not been set
+	ErrAuthorNotSet = errors.New("change set author must be set before review")
+
+	// ErrNotApproved is returned when attempting to commit a ChangeSet that
+	// does not have the required number of approvals
+	ErrNotApproved = errors.New("change set does not have the required approvals")
+
+	// ErrRejected is returned when attempting to commit a ChangeSet that has
+	// been rejected by an approver
+	ErrRejected = errors.New("change set rejected")
+
+	// ErrRevertToLatestVersion is returned when attempting to revert the
+	// configuration to its latest version
+	ErrRevertToLatestVersion = errors.New("cannot revert to the latest version")
+
+	// ErrPendingChanges is returned when attempting to revert the configuration
+	// while there are pending changes for the latest version
+	ErrPendingChanges = errors.New("pending changes for the latest version")
+
+	errApproverNotSet = errors.New("approver must be specified")
+
 	errOptsNotSet       = errors.New("opts must not be nil")
 	errKVNotSet         = errors.New("KV must be specified")
 	errConfigKeyNotSet  = errors.New("configKey must be specified")
@@ -80,6 +137,21 @@ type ManagerOptions interface {
 	SetChangesType(changes proto.Message) ManagerOptions
 	ChangesType() proto.Message
 
+	// RequiredApprovals is the number of distinct approvers, other than the
+	// author, that must approve a ChangeSet before it can be committed
+	SetRequiredApprovals(n int) ManagerOptions
+	RequiredApprovals() int
+
+	// NowFn is the function used to timestamp approvals
+	SetNowFn(fn clock.NowFn) ManagerOptions
+	NowFn() clock.NowFn
+
+	// HistoryLimit is the number of most recent versions of the configuration
+	// whose ChangeSets are listed by ChangeSets, StaleChangeSets and History.
+	// Defaults to 100 if not set
+	SetHistoryLimit(n int) ManagerOptions
+	HistoryLimit() int
+
 	// Validate validates the options
 	Validate() error
 }
@@ -94,6 +166,56 @@ type ChangeFn func(config, changes proto.Message) error
 // configuration
 type ApplyFn func(config, changes proto.Message) error
 
+// A ConflictFn checks whether a set of changes built on the base version of a
+// configuration can be moved onto the latest version of the configuration,
+// returning an error if the changes conflict with the latest configuration
+type ConflictFn func(baseConfig, latestConfig, changes proto.Message) error
+
+// A ChangeSet describes the set of changes built on a version of the
+// configuration
+type ChangeSet struct {
+	// ForVersion is the version of the configuration the changes are built on
+	ForVersion int
+
+	// State is the state of the ChangeSet
+	State changesetpb.ChangeSetState
+
+	// Changes are the changes in the ChangeSet
+	Changes proto.Message
+
+	// Author is the author of the changes
+	Author string
+
+	// Description describes the purpose of the changes
+	Description string
+
+	// Approvals are the reviews of the changes
+	Approvals []*changesetpb.Approval
+
+	// CommittedVersion is the version of the configuration produced by
+	// committing the ChangeSet, if it has been committed
+	CommittedVersion int
+
+	// CommitTime is the time the ChangeSet was committed, if it has been
+	// committed
+	CommitTime time.Time
+}
+
+// A RevertFn adds the changes that transform the latest configuration back
+// into a previous configuration
+type RevertFn func(previousConfig, latestConfig, changes proto.Message) error
+
+// A StaleChangeSet is an open ChangeSet built on a version of the
+// configuration that is no longer the latest, and so can no longer be
+// committed unless it is rebased
+type StaleChangeSet struct {
+	// ForVersion is the version of the configuration the changes are built on
+	ForVersion int
+
+	// Changes are the pending changes
+	Changes proto.Message
+}
+
 // A Manager manages sets of changes in a version friendly manager.  Changes to
 // a given version of a configuration object are stored under
 // <key>/_changes/<version>.  Multiple changes can be added, then committed all
@@ -115,6 +237,76 @@ type Manager interface {
 	// batch, are not applied more than once, and that new changes are not
 	// started while a commit is underway
 	Commit(version int, apply ApplyFn) error
+
+	// Preview applies the ChangeSet built on the specified version of the
+	// configuration to a copy of that configuration, returning the field-level
+	// differences between the current and resulting configurations. Nothing is
+	// written, and the ChangeSet may still be open
+	Preview(version int, apply ApplyFn) (Diff, error)
+
+	// Rebase moves the pending changes built on the specified version of the
+	// configuration onto the latest configuration, ahead of any changes already
+	// pending for the latest configuration. The conflict function is consulted
+	// before anything is moved, and aborts the rebase if it returns an error;
+	// a nil conflict function allows any rebase. The rebased ChangeSet is
+	// marked as REBASED
+	Rebase(version int, conflict ConflictFn) error
+
+	// StaleChangeSets returns the open ChangeSets built on versions of the
+	// configuration older than the latest, in increasing version order. Only
+	// the versions within the history limit are considered
+	StaleChangeSets() ([]StaleChangeSet, error)
+
+	// ChangeSets returns the ChangeSets built on the versions of the
+	// configuration within the history limit, in increasing version order
+	ChangeSets() ([]ChangeSet, error)
+
+	// Discard drops the pending changes of the open ChangeSet built on the
+	// specified version of the configuration, leaving the ChangeSet open and
+	// empty
+	Discard(version int) error
+
+	// Reopen reopens the ChangeSet built on the specified version of the
+	// configuration that was left CLOSED by a failed commit, so changes can
+	// be made to it again. A ChangeSet can only be reopened if the
+	// configuration is still at the version the ChangeSet is built on
+	Reopen(version int) error
+
+	// RollForward completes the failed commit of the CLOSED ChangeSet built on
+	// the specified version of the configuration. If the configuration has
+	// already moved past that version, the ChangeSet is only marked as
+	// COMMITTED if the next version of the configuration is the result of
+	// applying the changes, otherwise ErrCommitNotWritten is returned
+	RollForward(version int, apply ApplyFn) error
+
+	// Describe records the author and description of the open ChangeSet built
+	// on the specified version of the configuration. The author can only be
+	// set once, and approvals are dropped whenever the description changes
+	Describe(version int, author, description string) error
+
+	// Approve records the approval of the open ChangeSet built on the
+	// specified version of the configuration, replacing any earlier review by
+	// the same approver. The author must be set before the ChangeSet can be
+	// reviewed. Approvals are dropped whenever the changes change
+	Approve(version int, approver, comment string) error
+
+	// Reject records the rejection of the open ChangeSet built on the
+	// specified version of the configuration, replacing any earlier review by
+	// the same approver. A rejected ChangeSet cannot be committed
+	Reject(version int, approver, comment string) error
+
+	// History returns the committed ChangeSets, each linked to the version of
+	// the configuration it produced, in increasing version order. Only the
+	// versions within the history limit are considered
+	History() ([]ChangeSet, error)
+
+	// Revert creates a new ChangeSet against the latest configuration that
+	// restores the specified previous version of the configuration, recording
+	// the author and description of the revert. The description defaults to
+	// naming the restored version if empty. The ChangeSet is committed like
+	// any other ChangeSet, and cannot be created while other changes are
+	// pending for the latest configuration
+	Revert(version int, author, description string, revert RevertFn) error
 }
 
 // NewManager creates a new change list Manager
@@ -132,21 +324,37 @@ func NewManager(opts ManagerOptions) (Manager, error) {
 		logger = log.NullLogger
 	}
 
+	nowFn := opts.NowFn()
+	if nowFn == nil {
+		nowFn = time.Now
+	}
+
+	historyLimit := opts.HistoryLimit()
+	if historyLimit <= 0 {
+		historyLimit = defaultHistoryLimit
+	}
+
 	return manager{
-		key:         opts.ConfigKey(),
-		kv:          opts.KV(),
-		configType:  proto.Clone(opts.ConfigType()),
-		changesType: proto.Clone(opts.ChangesType()),
-		log:         logger,
+		key:               opts.ConfigKey(),
+		kv:                opts.KV(),
+		configType:        proto.Clone(opts.ConfigType()),
+		changesType:       proto.Clone(opts.ChangesType()),
+		requiredApprovals: opts.RequiredApprovals(),
+		nowFn:             nowFn,
+		historyLimit:      historyLimit,
+		log:               logger,
 	}, nil
 }
 
 type manager struct {
-	key         string
-	kv          kv.Store
-	configType  proto.Message
-	changesType proto.Message
-	log         log.Logger
+	key               string
+	kv                kv.Store
+	configType        proto.Message
+	changesType       proto.Message
+	requiredApprovals int
+	nowFn             clock.NowFn
+	historyLimit      int
+	log               log.Logger
 }
 
 func (m manager) Change(change ChangeFn) error {
@@ -192,8 +400,10 @@ func (m manager) Change(change ChangeFn) error {
 			return err
 		}
 
-		// ...and update the stored changes
+		// ...and update the stored changes, dropping any approvals of the
+		// previous changes
 		changeset.Changes = changeBytes
+		changeset.Approvals = nil
 		if _, err := m.kv.CheckAndSet(changeSetKey, csVersion, changeset); err != nil {
 			if err == kv.ErrVersionMismatch {
 				// Someone else updated the changes first - try again
@@ -284,16 +494,25 @@ func (m manager) Commit(version int, apply ApplyFn) error {
 	}
 
 	// If the change set is not already CLOSED, mark it as such to prevent new
-	// changes from being recorded while the commit is underway
+	// changes from being recorded while the commit is underway.  Approvals are
+	// checked against the same version of the change set that is closed
+	changeSetVersion := changeSetVal.Version()
 	if changeset.State != changesetpb.ChangeSetState_CLOSED {
+		if err := m.checkApprovals(&changeset); err != nil {
+			return err
+		}
+
 		changeset.State = changesetpb.ChangeSetState_CLOSED
-		if _, err := m.kv.CheckAndSet(changeSetKey, changeSetVal.Version(), &changeset); err != nil {
+		closedVersion, err := m.kv.CheckAndSet(changeSetKey, changeSetVersion, &changeset)
+		if err != nil {
 			if err == kv.ErrVersionMismatch {
 				return ErrCommitInProgress
 			}
 
 			return err
 		}
+
+		changeSetVersion = closedVersion
 	}
 
 	// Transform the current configuration according to the change list
@@ -308,7 +527,8 @@ func (m manager) Commit(version int, apply ApplyFn) error {
 
 	// Save the updated config.  This updates the version number for the config, so
 	// attempting to commit the current version again will fail
-	if _, err := m.kv.CheckAndSet(m.key, configVal.Version(), config); err != nil {
+	committedVersion, err := m.kv.CheckAndSet(m.key, configVal.Version(), config)
+	if err != nil {
 		if err == kv.ErrVersionMismatch {
 			return ErrAlreadyCommitted
 		}
@@ -316,9 +536,657 @@ func (m manager) Commit(version int, apply ApplyFn) error {
 		return err
 	}
 
+	// Link the change set to the configuration version it produced.  The commit
+	// itself has succeeded at this point, so failures are only logged
+	changeset.State = changesetpb.ChangeSetState_COMMITTED
+	changeset.CommittedVersion = int32(committedVersion)
+	changeset.CommitTimeNanos = m.nowFn().UnixNano()
+	if _, err := m.kv.CheckAndSet(changeSetKey, changeSetVersion, &changeset); err != nil {
+		m.log.Errorf("could not record commit of change set %s as config version %d: %v",
+			changeSetKey, committedVersion, err)
+	}
+
 	return nil
 }
 
+func (m manager) Preview(version int, apply ApplyFn) (Diff, error) {
+	configVal, err := m.kv.Get(m.key)
+	if err != nil {
+		return nil, err
+	}
+
+	if configVal.Version() < version {
+		return nil, ErrUnknownVersion
+	}
+
+	if configVal.Version() > version {
+		return nil, ErrAlreadyCommitted
+	}
+
+	config := proto.Clone(m.configType)
+	if err := configVal.Unmarshal(config); err != nil {
+		return nil, err
+	}
+
+	changeSetVal, err := m.kv.Get(fmtChangeSetKey(m.key, version))
+	if err != nil {
+		return nil, err
+	}
+
+	var changeset changesetpb.ChangeSet
+	if err := changeSetVal.Unmarshal(&changeset); err != nil {
+		return nil, err
+	}
+
+	changes := proto.Clone(m.changesType)
+	if err := proto.Unmarshal(changeset.Changes, changes); err != nil {
+		return nil, err
+	}
+
+	// Apply against a clone so the apply function cannot alter the baseline
+	newConfig := proto.Clone(config)
+	if err := apply(newConfig, changes); err != nil {
+		return nil, err
+	}
+
+	return NewDiff(config, newConfig), nil
+}
+
+func (m manager) Rebase(version int, conflict ConflictFn) error {
+	// Get the latest configuration
+	configVal, err := m.kv.Get(m.key)
+	if err != nil {
+		return err
+	}
+
+	// Confirm the version does exist and is no longer the latest
+	if configVal.Version() < version {
+		return ErrUnknownVersion
+	}
+
+	if configVal.Version() == version {
+		return ErrNotStale
+	}
+
+	latestConfig := proto.Clone(m.configType)
+	if err := configVal.Unmarshal(latestConfig); err != nil {
+		return err
+	}
+
+	// Retrieve the configuration the changes were built on
+	baseConfig, err := m.getConfigAtVersion(version)
+	if err != nil {
+		return err
+	}
+
+	// Get the stale change set, which must still be open
+	changeSetKey := fmtChangeSetKey(m.key, version)
+	changeSetVal, err := m.kv.Get(changeSetKey)
+	if err != nil {
+		return err
+	}
+
+	var changeset changesetpb.ChangeSet
+	if err := changeSetVal.Unmarshal(&changeset); err != nil {
+		return err
+	}
+
+	if changeset.State != changesetpb.ChangeSetState_OPEN {
+		return ErrChangeSetClosed
+	}
+
+	changes := proto.Clone(m.changesType)
+	if err := proto.Unmarshal(changeset.Changes, changes); err != nil {
+		return err
+	}
+
+	if conflict != nil {
+		if err := conflict(baseConfig, latestConfig, changes); err != nil {
+			return err
+		}
+	}
+
+	// Mark the stale change set as rebased before moving the changes, so they
+	// cannot be changed or rebased again while the rebase is underway
+	changeset.State = changesetpb.ChangeSetState_REBASED
+	rebasedVersion, err := m.kv.CheckAndSet(changeSetKey, changeSetVal.Version(), &changeset)
+	if err != nil {
+		if err == kv.ErrVersionMismatch {
+			return ErrRebaseInProgress
+		}
+
+		return err
+	}
+
+	if err := m.prependChanges(configVal.Version(), changes); err != nil {
+		// Reopen the stale change set so the changes are not lost
+		changeset.State = changesetpb.ChangeSetState_OPEN
+		if _, reopenErr := m.kv.CheckAndSet(changeSetKey, rebasedVersion, &changeset); reopenErr != nil {
+			m.log.Errorf("could not reopen change set %s after failed rebase: %v", changeSetKey, reopenErr)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (m manager) StaleChangeSets() ([]StaleChangeSet, error) {
+	configVersion, changesets, err := m.changeSets()
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []StaleChangeSet
+	for _, changeset := range changesets {
+		if changeset.ForVersion >= configVersion ||
+			changeset.State != changesetpb.ChangeSetState_OPEN {
+			continue
+		}
+
+		stale = append(stale, StaleChangeSet{
+			ForVersion: changeset.ForVersion,
+			Changes:    changeset.Changes,
+		})
+	}
+
+	return stale, nil
+}
+
+func (m manager) ChangeSets() ([]ChangeSet, error) {
+	_, changesets, err := m.changeSets()
+	return changesets, err
+}
+
+func (m manager) Discard(version int) error {
+	changeSetKey := fmtChangeSetKey(m.key, version)
+	changeSetVal, err := m.kv.Get(changeSetKey)
+	if err != nil {
+		return err
+	}
+
+	var changeset changesetpb.ChangeSet
+	if err := changeSetVal.Unmarshal(&changeset); err != nil {
+		return err
+	}
+
+	if changeset.State != changesetpb.ChangeSetState_OPEN {
+		return ErrChangeSetClosed
+	}
+
+	changeset.Changes = nil
+	changeset.Approvals = nil
+	if _, err := m.kv.CheckAndSet(changeSetKey, changeSetVal.Version(), &changeset); err != nil {
+		if err == kv.ErrVersionMismatch {
+			return ErrConcurrentChange
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (m manager) Reopen(version int) error {
+	configVal, err := m.kv.Get(m.key)
+	if err != nil {
+		return err
+	}
+
+	if configVal.Version() < version {
+		return ErrUnknownVersion
+	}
+
+	// The commit may have succeeded if the configuration has moved on
+	if configVal.Version() > version {
+		return ErrAlreadyCommitted
+	}
+
+	changeSetKey := fmtChangeSetKey(m.key, version)
+	changeSetVal, err := m.kv.Get(changeSetKey)
+	if err != nil {
+		return err
+	}
+
+	var changeset changesetpb.ChangeSet
+	if err := changeSetVal.Unmarshal(&changeset); err != nil {
+		return err
+	}
+
+	if changeset.State != changesetpb.ChangeSetState_CLOSED {
+		return ErrChangeSetNotClosed
+	}
+
+	changeset.State = changesetpb.ChangeSetState_OPEN
+	if _, err := m.kv.CheckAndSet(changeSetKey, changeSetVal.Version(), &changeset); err != nil {
+		if err == kv.ErrVersionMismatch {
+			return ErrConcurrentChange
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (m manager) RollForward(version int, apply ApplyFn) error {
+	changeSetKey := fmtChangeSetKey(m.key, version)
+	changeSetVal, err := m.kv.Get(changeSetKey)
+	if err != nil {
+		return err
+	}
+
+	var changeset changesetpb.ChangeSet
+	if err := changeSetVal.Unmarshal(&changeset); err != nil {
+		return err
+	}
+
+	if changeset.State != changesetpb.ChangeSetState_CLOSED {
+		return ErrChangeSetNotClosed
+	}
+
+	configVal, err := m.kv.Get(m.key)
+	if err != nil {
+		return err
+	}
+
+	// The failed commit may have written the configuration before recording
+	// the commit in the change set, in which case only the change set is left
+	// to update.  The next version is only attributed to the change set if it
+	// matches the changes, since someone else may have written it instead
+	if configVal.Version() > version {
+		if err := m.checkCommitWritten(version, changeset.Changes, apply); err != nil {
+			return err
+		}
+
+		changeset.State = changesetpb.ChangeSetState_COMMITTED
+		changeset.CommittedVersion = int32(version + 1)
+		changeset.CommitTimeNanos = m.nowFn().UnixNano()
+		if _, err := m.kv.CheckAndSet(changeSetKey, changeSetVal.Version(), &changeset); err != nil {
+			if err == kv.ErrVersionMismatch {
+				return ErrConcurrentChange
+			}
+
+			return err
+		}
+
+		return nil
+	}
+
+	// Commit picks up where the failed commit left off, since the change set
+	// is already closed
+	return m.Commit(version, apply)
+}
+
+// checkCommitWritten checks that the version of the configuration following
+// the specified version is the result of applying the changes to it
+func (m manager) checkCommitWritten(version int, changeBytes []byte, apply ApplyFn) error {
+	config, err := m.getConfigAtVersion(version)
+	if err != nil {
+		return err
+	}
+
+	committedConfig, err := m.getConfigAtVersion(version + 1)
+	if err != nil {
+		return err
+	}
+
+	changes := proto.Clone(m.changesType)
+	if err := proto.Unmarshal(changeBytes, changes); err != nil {
+		return err
+	}
+
+	if err := apply(config, changes); err != nil {
+		return err
+	}
+
+	if !proto.Equal(config, committedConfig) {
+		return ErrCommitNotWritten
+	}
+
+	return nil
+}
+
+func (m manager) Describe(version int, author, description string) error {
+	return m.updateOpenChangeSet(version, func(changeset *changesetpb.ChangeSet) error {
+		// The author cannot be rewritten, otherwise the author could approve
+		// their own changes by naming someone else as the author
+		if changeset.Author != "" && changeset.Author != author {
+			return ErrAuthorChanged
+		}
+
+		if changeset.Author != author || changeset.Description != description {
+			changeset.Approvals = nil
+		}
+
+		changeset.Author = author
+		changeset.Description = description
+		return nil
+	})
+}
+
+func (m manager) Approve(version int, approver, comment string) error {
+	return m.review(version, approver, comment, changesetpb.ApprovalDecision_APPROVED)
+}
+
+func (m manager) Reject(version int, approver, comment string) error {
+	return m.review(version, approver, comment, changesetpb.ApprovalDecision_REJECTED)
+}
+
+func (m manager) review(
+	version int,
+	approver, comment string,
+	decision changesetpb.ApprovalDecision,
+) error {
+	if approver == "" {
+		return errApproverNotSet
+	}
+
+	return m.updateOpenChangeSet(version, func(changeset *changesetpb.ChangeSet) error {
+		if changeset.Author == "" {
+			return ErrAuthorNotSet
+		}
+
+		if approver == changeset.Author {
+			return ErrSelfApproval
+		}
+
+		approval := &changesetpb.Approval{
+			Approver:       approver,
+			Decision:       decision,
+			Comment:        comment,
+			TimestampNanos: m.nowFn().UnixNano(),
+		}
+
+		for i, existing := range changeset.Approvals {
+			if existing.Approver == approver {
+				changeset.Approvals[i] = approval
+				return nil
+			}
+		}
+
+		changeset.Approvals = append(changeset.Approvals, approval)
+		return nil
+	})
+}
+
+// updateOpenChangeSet applies the update to the open change set built on the
+// specified configuration version, retrying if the change set is modified
+// concurrently
+func (m manager) updateOpenChangeSet(version int, update func(*changesetpb.ChangeSet) error) error {
+	changeSetKey := fmtChangeSetKey(m.key, version)
+	for {
+		changeSetVal, err := m.kv.Get(changeSetKey)
+		if err != nil {
+			return err
+		}
+
+		var changeset changesetpb.ChangeSet
+		if err := changeSetVal.Unmarshal(&changeset); err != nil {
+			return err
+		}
+
+		if changeset.State != changesetpb.ChangeSetState_OPEN {
+			return ErrChangeSetClosed
+		}
+
+		if err := update(&changeset); err != nil {
+			return err
+		}
+
+		if _, err := m.kv.CheckAndSet(changeSetKey, changeSetVal.Version(), &changeset); err != nil {
+			if err == kv.ErrVersionMismatch {
+				// Someone else updated the change set first - try again
+				continue
+			}
+
+			return err
+		}
+
+		return nil
+	}
+}
+
+// checkApprovals checks whether the change set satisfies the approval policy
+func (m manager) checkApprovals(changeset *changesetpb.ChangeSet) error {
+	approvers := make(map[string]struct{}, len(changeset.Approvals))
+	for _, approval := range changeset.Approvals {
+		if approval.Approver == changeset.Author {
+			continue
+		}
+
+		switch approval.Decision {
+		case changesetpb.ApprovalDecision_REJECTED:
+			return ErrRejected
+		case changesetpb.ApprovalDecision_APPROVED:
+			approvers[approval.Approver] = struct{}{}
+		}
+	}
+
+	if len(approvers) < m.requiredApprovals {
+		return ErrNotApproved
+	}
+
+	return nil
+}
+
+func (m manager) History() ([]ChangeSet, error) {
+	_, changesets, err := m.changeSets()
+	if err != nil {
+		return nil, err
+	}
+
+	var history []ChangeSet
+	for _, changeset := range changesets {
+		if changeset.State == changesetpb.ChangeSetState_COMMITTED {
+			history = append(history, changeset)
+		}
+	}
+
+	return history, nil
+}
+
+func (m manager) Revert(version int, author, description string, revert RevertFn) error {
+	configVal, err := m.kv.Get(m.key)
+	if err != nil {
+		return err
+	}
+
+	if configVal.Version() < version {
+		return ErrUnknownVersion
+	}
+
+	if configVal.Version() == version {
+		return ErrRevertToLatestVersion
+	}
+
+	previousConfig, err := m.getConfigAtVersion(version)
+	if err != nil {
+		return err
+	}
+
+	if description == "" {
+		description = fmt.Sprintf("revert to version %d", version)
+	}
+
+	for {
+		latestConfig := proto.Clone(m.configType)
+		configVersion, err := m.getOrCreate(m.key, latestConfig)
+		if err != nil {
+			return err
+		}
+
+		changeset := &changesetpb.ChangeSet{
+			ForVersion: int32(configVersion),
+			State:      changesetpb.ChangeSetState_OPEN,
+		}
+
+		changeSetKey := fmtChangeSetKey(m.key, configVersion)
+		csVersion, err := m.getOrCreate(changeSetKey, changeset)
+		if err != nil {
+			return err
+		}
+
+		if changeset.State != changesetpb.ChangeSetState_OPEN {
+			return ErrChangeSetClosed
+		}
+
+		if len(changeset.Changes) != 0 {
+			return ErrPendingChanges
+		}
+
+		if changeset.Author != "" && changeset.Author != author {
+			return ErrAuthorChanged
+		}
+
+		changes := proto.Clone(m.changesType)
+		if err := revert(previousConfig, latestConfig, changes); err != nil {
+			return err
+		}
+
+		changeBytes, err := proto.Marshal(changes)
+		if err != nil {
+			return err
+		}
+
+		changeset.Changes = changeBytes
+		changeset.Author = author
+		changeset.Description = description
+		changeset.Approvals = nil
+		if _, err := m.kv.CheckAndSet(changeSetKey, csVersion, changeset); err != nil {
+			if err == kv.ErrVersionMismatch {
+				// Someone else updated the changes first - try again
+				continue
+			}
+
+			return err
+		}
+
+		return nil
+	}
+}
+
+// changeSets returns the latest configuration version, and the change sets
+// built on the versions of the configuration within the history limit.  The
+// change sets are looked up one version at a time, so the scan is bounded to
+// keep the number of round trips constant for long-lived configurations
+func (m manager) changeSets() (int, []ChangeSet, error) {
+	configVal, err := m.kv.Get(m.key)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	from := configVal.Version() - m.historyLimit + 1
+	if from < 1 {
+		from = 1
+	}
+
+	var changesets []ChangeSet
+	for version := from; version <= configVal.Version(); version++ {
+		changeSetVal, err := m.kv.Get(fmtChangeSetKey(m.key, version))
+		if err == kv.ErrNotFound {
+			continue
+		}
+
+		if err != nil {
+			return 0, nil, err
+		}
+
+		var changeset changesetpb.ChangeSet
+		if err := changeSetVal.Unmarshal(&changeset); err != nil {
+			return 0, nil, err
+		}
+
+		changes := proto.Clone(m.changesType)
+		if err := proto.Unmarshal(changeset.Changes, changes); err != nil {
+			return 0, nil, err
+		}
+
+		var commitTime time.Time
+		if changeset.CommitTimeNanos != 0 {
+			commitTime = time.Unix(0, changeset.CommitTimeNanos)
+		}
+
+		changesets = append(changesets, ChangeSet{
+			ForVersion:       version,
+			State:            changeset.State,
+			Changes:          changes,
+			Author:           changeset.Author,
+			Description:      changeset.Description,
+			Approvals:        changeset.Approvals,
+			CommittedVersion: int(changeset.CommittedVersion),
+			CommitTime:       commitTime,
+		})
+	}
+
+	return configVal.Version(), changesets, nil
+}
+
+// prependChanges adds the changes to the change set of the specified
+// configuration version, ahead of the changes already pending there
+func (m manager) prependChanges(configVersion int, changes proto.Message) error {
+	for {
+		changeset := &changesetpb.ChangeSet{
+			ForVersion: int32(configVersion),
+			State:      changesetpb.ChangeSetState_OPEN,
+		}
+
+		changeSetKey := fmtChangeSetKey(m.key, configVersion)
+		csVersion, err := m.getOrCreate(changeSetKey, changeset)
+		if err != nil {
+			return err
+		}
+
+		if changeset.State != changesetpb.ChangeSetState_OPEN {
+			return ErrChangeSetClosed
+		}
+
+		pending := proto.Clone(m.changesType)
+		if err := proto.Unmarshal(changeset.Changes, pending); err != nil {
+			return err
+		}
+
+		merged := proto.Clone(changes)
+		proto.Merge(merged, pending)
+		changeBytes, err := proto.Marshal(merged)
+		if err != nil {
+			return err
+		}
+
+		changeset.Changes = changeBytes
+		changeset.Approvals = nil
+		if _, err := m.kv.CheckAndSet(changeSetKey, csVersion, changeset); err != nil {
+			if err == kv.ErrVersionMismatch {
+				// Someone else updated the changes first - try again
+				continue
+			}
+
+			return err
+		}
+
+		return nil
+	}
+}
+
+// getConfigAtVersion retrieves a past version of the configuration
+func (m manager) getConfigAtVersion(version int) (proto.Message, error) {
+	vals, err := m.kv.History(m.key, version, version+1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vals) == 0 {
+		return nil, ErrUnknownVersion
+	}
+
+	config := proto.Clone(m.configType)
+	if err := vals[0].Unmarshal(config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
 func (m manager) getOrCreate(k string, v proto.Message) (int, error) {
 	for {
 		val, err := m.kv.Get(k)
@@ -356,11 +1224,14 @@ func fmtChangeSetKey(configKey string, configVers int) string {
 }
 
 type managerOptions struct {
-	kv          kv.Store
-	logger      log.Logger
-	configKey   string
-	configType  proto.Message
-	changesType proto.Message
+	kv                kv.Store
+	logger            log.Logger
+	configKey         string
+	configType        proto.Message
+	changesType       proto.Message
+	requiredApprovals int
+	nowFn             clock.NowFn
+	historyLimit      int
 }
 
 func (opts managerOptions) KV() kv.Store               { return opts.kv }
@@ -368,6 +1239,9 @@ func (opts managerOptions) Logger() log.Logger         { return opts.logger }
 func (opts managerOptions) ConfigKey() string          { return opts.configKey }
 func (opts managerOptions) ConfigType() proto.Message  { return opts.configType }
 func (opts managerOptions) ChangesType() proto.Message { return opts.changesType }
+func (opts managerOptions) RequiredApprovals() int     { return opts.requiredApprovals }
+func (opts managerOptions) NowFn() clock.NowFn         { return opts.nowFn }
+func (opts managerOptions) HistoryLimit() int          { return opts.historyLimit }
 
 func (opts managerOptions) SetKV(kv kv.Store) ManagerOptions {
 	opts.kv = kv
@@ -389,6 +1263,18 @@ func (opts managerOptions) SetChangesType(ct proto.Message) ManagerOptions {
 	opts.changesType = ct
 	return opts
 }
+func (opts managerOptions) SetRequiredApprovals(n int) ManagerOptions {
+	opts.requiredApprovals = n
+	return opts
+}
+func (opts managerOptions) SetNowFn(fn clock.NowFn) ManagerOptions {
+	opts.nowFn = fn
+	return opts
+}
+func (opts managerOptions) SetHistoryLimit(n int) ManagerOptions {
+	opts.historyLimit = n
+	return opts
+}
 
 func (opts managerOptions) Validate() error {
 	if opts.ConfigKey() == "" {
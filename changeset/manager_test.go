@@ -24,6 +24,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/golang/protobuf/proto"
@@ -282,6 +283,7 @@ func TestManagerCommit_Success(t *testing.T) {
 
 	var (
 		changeSet1 = new(changeSetMatcher)
+		changeSet2 = new(changeSetMatcher)
 		config1    = new(configMatcher)
 
 		committedVersion = 22
@@ -309,6 +311,10 @@ func TestManagerCommit_Success(t *testing.T) {
 		// Update the transformed confi
 		s.kv.EXPECT().CheckAndSet(s.configKey, committedVersion, config1).
 			Return(committedVersion+1, nil),
+
+		// Record the commit
+		s.kv.EXPECT().CheckAndSet(changeSetKey, changeSetVersion+1, changeSet2).
+			Return(changeSetVersion+2, nil),
 	)
 
 	err := s.mgr.Commit(committedVersion, commit)
@@ -316,6 +322,8 @@ func TestManagerCommit_Success(t *testing.T) {
 
 	require.Equal(t, changesetpb.ChangeSetState_CLOSED, changeSet1.changeset().State)
 	require.Equal(t, "shoop\nwoop\nhoop\nfoo\nbar", config1.config().Text)
+	require.Equal(t, changesetpb.ChangeSetState_COMMITTED, changeSet2.changeset().State)
+	require.Equal(t, int32(committedVersion+1), changeSet2.changeset().CommittedVersion)
 }
 
 func TestManagerCommit_ConfigNotFound(t *testing.T) {
@@ -434,6 +442,10 @@ func TestManagerCommit_ChangeSetClosing(t *testing.T) {
 		// Update the transformed config
 		s.kv.EXPECT().CheckAndSet(s.configKey, committedVersion, config1).
 			Return(committedVersion+1, nil),
+
+		// Record the commit, failures are ignored
+		s.kv.EXPECT().CheckAndSet(changeSetKey, changeSetVersion, gomock.Any()).
+			Return(0, errBadThingsHappened),
 	)
 
 	err := s.mgr.Commit(committedVersion, commit)
@@ -741,6 +753,492 @@ func TestManager_GetPendingChangesChangeUnmarshalError(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestManager_RebaseSuccess(t *testing.T) {
+	store, mgr := newMemTestManager(t)
+
+	// Build changes on version 1, then move the config on through another path
+	require.NoError(t, mgr.Change(addLines("foo")))
+	_, err := store.Set("config", &changesettest.Config{Text: "zed"})
+	require.NoError(t, err)
+	require.NoError(t, mgr.Change(addLines("bar")))
+
+	stale, err := mgr.StaleChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(stale))
+	require.Equal(t, 1, stale[0].ForVersion)
+	require.Equal(t, []string{"foo"}, stale[0].Changes.(*changesettest.Changes).Lines)
+
+	var conflictArgs []proto.Message
+	require.NoError(t, mgr.Rebase(1, func(base, latest, changes proto.Message) error {
+		conflictArgs = []proto.Message{base, latest, changes}
+		return nil
+	}))
+	require.Equal(t, "", conflictArgs[0].(*changesettest.Config).Text)
+	require.Equal(t, "zed", conflictArgs[1].(*changesettest.Config).Text)
+	require.Equal(t, []string{"foo"}, conflictArgs[2].(*changesettest.Changes).Lines)
+
+	vers, _, changes, err := mgr.GetPendingChanges()
+	require.NoError(t, err)
+	require.Equal(t, 2, vers)
+	require.Equal(t, []string{"foo", "bar"}, changes.(*changesettest.Changes).Lines)
+
+	val, err := store.Get(fmtChangeSetKey("config", 1))
+	require.NoError(t, err)
+	var changeset changesetpb.ChangeSet
+	require.NoError(t, val.Unmarshal(&changeset))
+	require.Equal(t, changesetpb.ChangeSetState_REBASED, changeset.State)
+
+	stale, err = mgr.StaleChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, 0, len(stale))
+
+	require.Equal(t, ErrChangeSetClosed, mgr.Rebase(1, noConflict))
+	require.NoError(t, mgr.Commit(2, commit))
+}
+
+func TestManager_RebaseConflict(t *testing.T) {
+	store, mgr := newMemTestManager(t)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	_, err := store.Set("config", &changesettest.Config{Text: "zed"})
+	require.NoError(t, err)
+
+	require.Equal(t, errBadThingsHappened, mgr.Rebase(1, func(_, _, _ proto.Message) error {
+		return errBadThingsHappened
+	}))
+
+	// The stale change set is left untouched
+	stale, err := mgr.StaleChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(stale))
+}
+
+func TestManager_RebaseClosedLatestChangeSet(t *testing.T) {
+	store, mgr := newMemTestManager(t)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	_, err := store.Set("config", &changesettest.Config{Text: "zed"})
+	require.NoError(t, err)
+	_, err = store.Set(fmtChangeSetKey("config", 2), &changesetpb.ChangeSet{
+		ForVersion: 2,
+		State:      changesetpb.ChangeSetState_CLOSED,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, ErrChangeSetClosed, mgr.Rebase(1, noConflict))
+
+	// The stale change set is reopened so the changes are not lost
+	stale, err := mgr.StaleChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(stale))
+	require.Equal(t, []string{"foo"}, stale[0].Changes.(*changesettest.Changes).Lines)
+}
+
+func TestManager_RebaseInvalidVersion(t *testing.T) {
+	store, mgr := newMemTestManager(t)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.Equal(t, ErrNotStale, mgr.Rebase(1, noConflict))
+	require.Equal(t, ErrUnknownVersion, mgr.Rebase(2, noConflict))
+
+	_, err := store.Set("config", &changesettest.Config{Text: "zed"})
+	require.NoError(t, err)
+	require.NoError(t, mgr.Rebase(1, noConflict))
+}
+
+func TestManager_RebaseNilConflictFn(t *testing.T) {
+	store, mgr := newMemTestManager(t)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	_, err := store.Set("config", &changesettest.Config{Text: "zed"})
+	require.NoError(t, err)
+	require.NoError(t, mgr.Rebase(1, nil))
+
+	_, _, changes, err := mgr.GetPendingChanges()
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, changes.(*changesettest.Changes).Lines)
+}
+
+func TestManager_ChangeSetsHistoryLimit(t *testing.T) {
+	store := mem.NewStore()
+	mgr, err := NewManager(newMemTestManagerOptions(store).SetHistoryLimit(2))
+	require.NoError(t, err)
+
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, mgr.Change(addLines("foo")))
+		require.NoError(t, mgr.Commit(i, commit))
+	}
+
+	changesets, err := mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(changesets))
+	require.Equal(t, 3, changesets[0].ForVersion)
+
+	history, err := mgr.History()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(history))
+	require.Equal(t, 4, history[0].CommittedVersion)
+}
+
+func TestManager_ChangeSets(t *testing.T) {
+	store, mgr := newMemTestManager(t)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Commit(1, commit))
+	require.NoError(t, mgr.Change(addLines("bar")))
+	_, err := store.Set("config", &changesettest.Config{Text: "zed"})
+	require.NoError(t, err)
+
+	changesets, err := mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(changesets))
+	require.Equal(t, 1, changesets[0].ForVersion)
+	require.Equal(t, changesetpb.ChangeSetState_COMMITTED, changesets[0].State)
+	require.Equal(t, []string{"foo"}, changesets[0].Changes.(*changesettest.Changes).Lines)
+	require.Equal(t, 2, changesets[1].ForVersion)
+	require.Equal(t, changesetpb.ChangeSetState_OPEN, changesets[1].State)
+	require.Equal(t, []string{"bar"}, changesets[1].Changes.(*changesettest.Changes).Lines)
+}
+
+func TestManager_Discard(t *testing.T) {
+	_, mgr := newMemTestManager(t)
+
+	require.NoError(t, mgr.Change(addLines("foo", "bar")))
+	require.NoError(t, mgr.Discard(1))
+
+	_, _, changes, err := mgr.GetPendingChanges()
+	require.NoError(t, err)
+	require.Equal(t, 0, len(changes.(*changesettest.Changes).Lines))
+
+	// The change set is still open for new changes
+	require.NoError(t, mgr.Change(addLines("baz")))
+	_, _, changes, err = mgr.GetPendingChanges()
+	require.NoError(t, err)
+	require.Equal(t, []string{"baz"}, changes.(*changesettest.Changes).Lines)
+
+	require.NoError(t, mgr.Commit(1, commit))
+	require.Equal(t, ErrChangeSetClosed, mgr.Discard(1))
+	require.Equal(t, kv.ErrNotFound, mgr.Discard(2))
+}
+
+func TestManager_ReopenAndRollForward(t *testing.T) {
+	store, mgr := newMemTestManager(t)
+
+	// Simulate a commit that fails after closing the change set
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.Equal(t, errBadThingsHappened, mgr.Commit(1, func(_, _ proto.Message) error {
+		return errBadThingsHappened
+	}))
+	require.Equal(t, ErrChangeSetClosed, mgr.Change(addLines("bar")))
+
+	require.NoError(t, mgr.Reopen(1))
+	require.Equal(t, ErrChangeSetNotClosed, mgr.Reopen(1))
+	require.Equal(t, ErrChangeSetNotClosed, mgr.RollForward(1, commit))
+	require.NoError(t, mgr.Change(addLines("bar")))
+
+	// Fail again, and then roll forward
+	require.Equal(t, errBadThingsHappened, mgr.Commit(1, func(_, _ proto.Message) error {
+		return errBadThingsHappened
+	}))
+	require.NoError(t, mgr.RollForward(1, commit))
+
+	val, err := store.Get("config")
+	require.NoError(t, err)
+	var config changesettest.Config
+	require.NoError(t, val.Unmarshal(&config))
+	require.Equal(t, "foo\nbar", config.Text)
+
+	require.Equal(t, ErrAlreadyCommitted, mgr.Reopen(1))
+	require.Equal(t, ErrUnknownVersion, mgr.Reopen(3))
+}
+
+func TestManager_RollForwardAfterConfigWritten(t *testing.T) {
+	now := time.Unix(1234, 0)
+	store := mem.NewStore()
+	mgr, err := NewManager(newMemTestManagerOptions(store).
+		SetNowFn(func() time.Time { return now }))
+	require.NoError(t, err)
+
+	// Simulate a commit that writes the config but fails before recording the
+	// commit in the change set
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.Equal(t, errBadThingsHappened, mgr.Commit(1, func(_, _ proto.Message) error {
+		return errBadThingsHappened
+	}))
+	_, err = store.Set("config", &changesettest.Config{Text: "foo"})
+	require.NoError(t, err)
+
+	require.Equal(t, ErrAlreadyCommitted, mgr.Reopen(1))
+	require.NoError(t, mgr.RollForward(1, commit))
+	require.Equal(t, ErrChangeSetNotClosed, mgr.RollForward(1, commit))
+
+	history, err := mgr.History()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(history))
+	require.Equal(t, 1, history[0].ForVersion)
+	require.Equal(t, 2, history[0].CommittedVersion)
+	require.Equal(t, now, history[0].CommitTime)
+
+	// The config is left as written by the failed commit
+	val, err := store.Get("config")
+	require.NoError(t, err)
+	require.Equal(t, 2, val.Version())
+}
+
+func TestManager_RollForwardAfterConfigChangedElsewhere(t *testing.T) {
+	store := mem.NewStore()
+	mgr, err := NewManager(newMemTestManagerOptions(store))
+	require.NoError(t, err)
+
+	// Simulate a commit that fails before writing the config, which is then
+	// written by someone else
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.Equal(t, errBadThingsHappened, mgr.Commit(1, func(_, _ proto.Message) error {
+		return errBadThingsHappened
+	}))
+	_, err = store.Set("config", &changesettest.Config{Text: "bar"})
+	require.NoError(t, err)
+
+	// The changes are not recorded as committed since they were never applied
+	require.Equal(t, ErrCommitNotWritten, mgr.RollForward(1, commit))
+
+	changesets, err := mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, changesetpb.ChangeSetState_CLOSED, changesets[0].State)
+
+	history, err := mgr.History()
+	require.NoError(t, err)
+	require.Equal(t, 0, len(history))
+}
+
+func TestManager_ApprovalWorkflow(t *testing.T) {
+	now := time.Unix(1234, 0)
+	store := mem.NewStore()
+	mgr, err := NewManager(newMemTestManagerOptions(store).
+		SetRequiredApprovals(2).
+		SetNowFn(func() time.Time { return now }))
+	require.NoError(t, err)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Describe(1, "alice", "add foo"))
+	require.Equal(t, ErrNotApproved, mgr.Commit(1, commit))
+
+	// Authors cannot approve their own changes
+	require.Equal(t, ErrSelfApproval, mgr.Approve(1, "alice", ""))
+	require.Equal(t, errApproverNotSet, mgr.Approve(1, "", ""))
+
+	// Approvals must come from distinct approvers
+	require.NoError(t, mgr.Approve(1, "bob", "lgtm"))
+	require.NoError(t, mgr.Approve(1, "bob", "still lgtm"))
+	require.Equal(t, ErrNotApproved, mgr.Commit(1, commit))
+
+	// A rejection blocks the commit until the approver changes their mind
+	require.NoError(t, mgr.Reject(1, "carol", "not yet"))
+	require.Equal(t, ErrRejected, mgr.Commit(1, commit))
+
+	changesets, err := mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(changesets))
+	require.Equal(t, "alice", changesets[0].Author)
+	require.Equal(t, "add foo", changesets[0].Description)
+	require.Equal(t, []*changesetpb.Approval{
+		{
+			Approver:       "bob",
+			Decision:       changesetpb.ApprovalDecision_APPROVED,
+			Comment:        "still lgtm",
+			TimestampNanos: now.UnixNano(),
+		},
+		{
+			Approver:       "carol",
+			Decision:       changesetpb.ApprovalDecision_REJECTED,
+			Comment:        "not yet",
+			TimestampNanos: now.UnixNano(),
+		},
+	}, changesets[0].Approvals)
+
+	require.NoError(t, mgr.Approve(1, "carol", "ok"))
+	require.NoError(t, mgr.Commit(1, commit))
+
+	// The committed change set can no longer be reviewed
+	require.Equal(t, ErrChangeSetClosed, mgr.Approve(1, "dave", ""))
+}
+
+func TestManager_ChangeDropsApprovals(t *testing.T) {
+	store := mem.NewStore()
+	mgr, err := NewManager(newMemTestManagerOptions(store).SetRequiredApprovals(1))
+	require.NoError(t, err)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Describe(1, "alice", "add foo"))
+	require.NoError(t, mgr.Approve(1, "bob", ""))
+	require.NoError(t, mgr.Change(addLines("bar")))
+	require.Equal(t, ErrNotApproved, mgr.Commit(1, commit))
+
+	require.NoError(t, mgr.Approve(1, "bob", ""))
+	require.NoError(t, mgr.Commit(1, commit))
+}
+
+func TestManager_SelfApprovalBypassRejected(t *testing.T) {
+	store := mem.NewStore()
+	mgr, err := NewManager(newMemTestManagerOptions(store).SetRequiredApprovals(1))
+	require.NoError(t, err)
+
+	// Changes cannot be reviewed before the author is set
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.Equal(t, ErrAuthorNotSet, mgr.Approve(1, "alice", ""))
+	require.Equal(t, ErrAuthorNotSet, mgr.Reject(1, "alice", ""))
+
+	// The author cannot be rewritten to approve their own changes
+	require.NoError(t, mgr.Describe(1, "alice", "add foo"))
+	require.Equal(t, ErrAuthorChanged, mgr.Describe(1, "mallory", "add foo"))
+	require.Equal(t, ErrSelfApproval, mgr.Approve(1, "alice", ""))
+	require.Equal(t, ErrNotApproved, mgr.Commit(1, commit))
+
+	// Changing the description drops the approvals
+	require.NoError(t, mgr.Approve(1, "bob", ""))
+	require.NoError(t, mgr.Describe(1, "alice", "add foo and more"))
+	require.Equal(t, ErrNotApproved, mgr.Commit(1, commit))
+
+	// Describing with the same author and description keeps the approvals
+	require.NoError(t, mgr.Approve(1, "bob", ""))
+	require.NoError(t, mgr.Describe(1, "alice", "add foo and more"))
+	require.NoError(t, mgr.Commit(1, commit))
+}
+
+func TestManager_History(t *testing.T) {
+	var (
+		store = mem.NewStore()
+		now   = time.Unix(1234, 0)
+	)
+	mgr, err := NewManager(newMemTestManagerOptions(store).
+		SetNowFn(func() time.Time { return now }))
+	require.NoError(t, err)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Describe(1, "alice", "add foo"))
+	require.NoError(t, mgr.Commit(1, commit))
+
+	// Changes committed through another path are not part of the history
+	_, err = store.Set("config", &changesettest.Config{Text: "zed"})
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, mgr.Change(addLines("bar")))
+	require.NoError(t, mgr.Commit(3, commit))
+
+	// Pending changes are not part of the history either
+	require.NoError(t, mgr.Change(addLines("baz")))
+
+	history, err := mgr.History()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(history))
+
+	require.Equal(t, 1, history[0].ForVersion)
+	require.Equal(t, 2, history[0].CommittedVersion)
+	require.Equal(t, "alice", history[0].Author)
+	require.Equal(t, "add foo", history[0].Description)
+	require.Equal(t, time.Unix(1234, 0), history[0].CommitTime)
+	require.Equal(t, []string{"foo"}, history[0].Changes.(*changesettest.Changes).Lines)
+
+	require.Equal(t, 3, history[1].ForVersion)
+	require.Equal(t, 4, history[1].CommittedVersion)
+	require.Equal(t, now, history[1].CommitTime)
+	require.Equal(t, []string{"bar"}, history[1].Changes.(*changesettest.Changes).Lines)
+}
+
+func TestManager_Revert(t *testing.T) {
+	store, mgr := newMemTestManager(t)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Commit(1, commit))
+	require.NoError(t, mgr.Change(addLines("bar")))
+	require.NoError(t, mgr.Commit(2, commit))
+
+	// Reverting is not possible while other changes are pending
+	require.NoError(t, mgr.Change(addLines("baz")))
+	require.Equal(t, ErrPendingChanges, mgr.Revert(2, "alice", "", revertText))
+	require.NoError(t, mgr.Discard(3))
+
+	require.Equal(t, ErrRevertToLatestVersion, mgr.Revert(3, "alice", "", revertText))
+	require.Equal(t, ErrUnknownVersion, mgr.Revert(4, "alice", "", revertText))
+	require.NoError(t, mgr.Revert(2, "alice", "", revertText))
+
+	changesets, err := mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, "alice", changesets[2].Author)
+	require.Equal(t, "revert to version 2", changesets[2].Description)
+
+	require.NoError(t, mgr.Commit(3, func(cfgProto, changesProto proto.Message) error {
+		cfgProto.(*changesettest.Config).Text = changesProto.(*changesettest.Changes).Lines[0]
+		return nil
+	}))
+
+	val, err := store.Get("config")
+	require.NoError(t, err)
+	var config changesettest.Config
+	require.NoError(t, val.Unmarshal(&config))
+	require.Equal(t, "foo", config.Text)
+}
+
+func TestManager_RevertWithApprovals(t *testing.T) {
+	store := mem.NewStore()
+	mgr, err := NewManager(newMemTestManagerOptions(store).SetRequiredApprovals(1))
+	require.NoError(t, err)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Describe(1, "alice", "add foo"))
+	require.NoError(t, mgr.Approve(1, "bob", ""))
+	require.NoError(t, mgr.Commit(1, commit))
+
+	// The revert can be reviewed without describing it again
+	require.NoError(t, mgr.Revert(1, "alice", "undo foo", revertText))
+	require.Equal(t, ErrSelfApproval, mgr.Approve(2, "alice", ""))
+	require.NoError(t, mgr.Approve(2, "bob", ""))
+	require.NoError(t, mgr.Commit(2, commit))
+
+	history, err := mgr.History()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(history))
+	require.Equal(t, "alice", history[1].Author)
+	require.Equal(t, "undo foo", history[1].Description)
+	require.Equal(t, 1, len(history[1].Approvals))
+}
+
+func TestManager_Preview(t *testing.T) {
+	store, mgr := newMemTestManager(t)
+
+	require.NoError(t, mgr.Change(addLines("foo", "bar")))
+	diff, err := mgr.Preview(1, func(cfgProto, changesProto proto.Message) error {
+		config := cfgProto.(*changesettest.Config)
+		config.Text = strings.Join(changesProto.(*changesettest.Changes).Lines, ",")
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, Diff{
+		{Path: "text", Type: FieldChanged, Old: "", New: "foo,bar"},
+	}, diff)
+
+	// Nothing should have been written
+	val, err := store.Get("config")
+	require.NoError(t, err)
+	require.Equal(t, 1, val.Version())
+	vers, _, changes, err := mgr.GetPendingChanges()
+	require.NoError(t, err)
+	require.Equal(t, 1, vers)
+	require.Equal(t, []string{"foo", "bar"}, changes.(*changesettest.Changes).Lines)
+
+	_, err = mgr.Preview(1, func(_, _ proto.Message) error {
+		return errBadThingsHappened
+	})
+	require.Equal(t, errBadThingsHappened, err)
+
+	_, err = mgr.Preview(2, commit)
+	require.Equal(t, ErrUnknownVersion, err)
+	require.NoError(t, mgr.Commit(1, commit))
+	_, err = mgr.Preview(1, commit)
+	require.Equal(t, ErrAlreadyCommitted, err)
+}
+
 func TestManagerOptions_Validate(t *testing.T) {
 	tests := []struct {
 		err  error
@@ -812,6 +1310,31 @@ func commit(cfgProto, changesProto proto.Message) error {
 	return nil
 }
 
+func noConflict(_, _, _ proto.Message) error {
+	return nil
+}
+
+func revertText(previousProto, _, changesProto proto.Message) error {
+	changes := changesProto.(*changesettest.Changes)
+	changes.Lines = []string{previousProto.(*changesettest.Config).Text}
+	return nil
+}
+
+func newMemTestManager(t *testing.T) (kv.Store, Manager) {
+	store := mem.NewStore()
+	mgr, err := NewManager(newMemTestManagerOptions(store))
+	require.NoError(t, err)
+	return store, mgr
+}
+
+func newMemTestManagerOptions(store kv.Store) ManagerOptions {
+	return NewManagerOptions().
+		SetKV(store).
+		SetConfigType(&changesettest.Config{}).
+		SetChangesType(&changesettest.Changes{}).
+		SetConfigKey("config")
+}
+
 type testSuite struct {
 	t         *testing.T
 	kv        *kv.MockStore
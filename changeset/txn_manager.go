@@ -0,0 +1,622 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package changeset
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/m3db/m3cluster/generated/proto/changesetpb"
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3x/clock"
+)
+
+var (
+	// ErrConfigChanged is returned when one of the configuration keys a
+	// ChangeSet is built on has changed since the ChangeSet was started
+	ErrConfigChanged = errors.New("configuration changed since change set was started")
+
+	errChangeSetKeyNotSet = errors.New("changeSetKey must be specified")
+	errConfigTypesNotSet  = errors.New("configTypes must be specified")
+)
+
+// TxnManagerOptions are options used in creating a new TxnManager
+type TxnManagerOptions interface {
+	// KV is the transactional KVStore holding the configuration
+	SetKV(kv kv.TxnStore) TxnManagerOptions
+	KV() kv.TxnStore
+
+	// ChangeSetKey is the key holding the pending ChangeSet
+	SetChangeSetKey(key string) TxnManagerOptions
+	ChangeSetKey() string
+
+	// ConfigTypes maps each key holding part of the configuration to a
+	// proto.Message defining the structure of the object under that key.
+	// Clones of these protos will be used to unmarshal configuration instances
+	SetConfigTypes(configTypes map[string]proto.Message) TxnManagerOptions
+	ConfigTypes() map[string]proto.Message
+
+	// ChangesType is a proto.Message defining the structure of the changes
+	// object.  Clones of this proto will be used to unmarshal change list
+	// instances
+	SetChangesType(changes proto.Message) TxnManagerOptions
+	ChangesType() proto.Message
+
+	// NowFn is the function used to timestamp commits
+	SetNowFn(fn clock.NowFn) TxnManagerOptions
+	NowFn() clock.NowFn
+
+	// Validate validates the options
+	Validate() error
+}
+
+// NewTxnManagerOptions creates an empty TxnManagerOptions
+func NewTxnManagerOptions() TxnManagerOptions { return txnManagerOptions{} }
+
+// A TxnChangeFn adds a change to an existing set of changes, given the
+// configuration under each key
+type TxnChangeFn func(configs map[string]proto.Message, changes proto.Message) error
+
+// A TxnApplyFn applies a set of changes to the configuration under each key,
+// updating the configurations in place
+type TxnApplyFn func(configs map[string]proto.Message, changes proto.Message) error
+
+// A TxnConflictFn checks whether a set of changes built on the base versions
+// of the configurations can be moved onto the latest configurations,
+// returning an error if the changes conflict with the latest configurations
+type TxnConflictFn func(baseConfigs, latestConfigs map[string]proto.Message, changes proto.Message) error
+
+// A TxnManager manages sets of changes spanning several configuration keys.
+// The pending ChangeSet is stored under a single key, along with the version
+// of each configuration key it is built on.  Committing transforms all the
+// configurations according to the changes, then writes them back in a single
+// transaction that only succeeds if none of the keys have changed since
+// the ChangeSet was started
+type TxnManager interface {
+	// Change adds a change to the pending ChangeSet, starting a new ChangeSet
+	// against the latest configurations if none is pending
+	Change(change TxnChangeFn) error
+
+	// GetPendingChanges gets the versions of the configurations the pending
+	// ChangeSet is built on, the configurations and the pending changes
+	GetPendingChanges() (map[string]int, map[string]proto.Message, proto.Message, error)
+
+	// Commit commits the pending ChangeSet, writing every configuration that
+	// the changes modify and marking the ChangeSet as COMMITTED atomically
+	Commit(apply TxnApplyFn) error
+
+	// Discard drops the pending changes, whether the ChangeSet is open or was
+	// left CLOSED by a failed commit, leaving an empty open ChangeSet built on
+	// the latest configurations
+	Discard() error
+
+	// Reopen reopens the ChangeSet that was left CLOSED by a failed commit, so
+	// changes can be made to it again
+	Reopen() error
+
+	// Rebase moves the pending changes, whether the ChangeSet is open or was
+	// left CLOSED by a failed commit, onto the latest configurations once any
+	// of them has changed since the ChangeSet was started. The conflict
+	// function is consulted before anything is moved, and aborts the rebase
+	// if it returns an error; a nil conflict function allows any rebase. The
+	// rebased ChangeSet is left open
+	Rebase(conflict TxnConflictFn) error
+}
+
+// NewTxnManager creates a new TxnManager
+func NewTxnManager(opts TxnManagerOptions) (TxnManager, error) {
+	if opts == nil {
+		return nil, errOptsNotSet
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	nowFn := opts.NowFn()
+	if nowFn == nil {
+		nowFn = time.Now
+	}
+
+	configTypes := make(map[string]proto.Message, len(opts.ConfigTypes()))
+	keys := make([]string, 0, len(opts.ConfigTypes()))
+	for key, configType := range opts.ConfigTypes() {
+		configTypes[key] = proto.Clone(configType)
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return txnManager{
+		changeSetKey: opts.ChangeSetKey(),
+		kv:           opts.KV(),
+		keys:         keys,
+		configTypes:  configTypes,
+		changesType:  proto.Clone(opts.ChangesType()),
+		nowFn:        nowFn,
+	}, nil
+}
+
+type txnManager struct {
+	changeSetKey string
+	kv           kv.TxnStore
+	keys         []string
+	configTypes  map[string]proto.Message
+	changesType  proto.Message
+	nowFn        clock.NowFn
+}
+
+func (m txnManager) Change(change TxnChangeFn) error {
+	for {
+		versions, configs, err := m.getConfigs()
+		if err != nil {
+			return err
+		}
+
+		// Retrieve the pending change set, starting a new one against the
+		// latest configurations if there is none or the last one was committed
+		var changeset changesetpb.ChangeSet
+		csVersion := 0
+		csVal, err := m.kv.Get(m.changeSetKey)
+		if err != nil && err != kv.ErrNotFound {
+			return err
+		}
+
+		if err == nil {
+			if err := csVal.Unmarshal(&changeset); err != nil {
+				return err
+			}
+			csVersion = csVal.Version()
+		}
+
+		switch changeset.State {
+		case changesetpb.ChangeSetState_UNKNOWN, changesetpb.ChangeSetState_COMMITTED:
+			changeset = changesetpb.ChangeSet{
+				State:          changesetpb.ChangeSetState_OPEN,
+				ConfigVersions: versions,
+			}
+		case changesetpb.ChangeSetState_OPEN:
+			if len(changeset.Changes) == 0 {
+				// Nothing to lose, so move an empty change set onto the
+				// latest configurations
+				changeset.ConfigVersions = versions
+			} else if !sameVersions(changeset.ConfigVersions, versions) {
+				return ErrConfigChanged
+			}
+		default:
+			return ErrChangeSetClosed
+		}
+
+		// Apply the new changes...
+		changes := proto.Clone(m.changesType)
+		if err := proto.Unmarshal(changeset.Changes, changes); err != nil {
+			return err
+		}
+
+		if err := change(configs, changes); err != nil {
+			return err
+		}
+
+		changeBytes, err := proto.Marshal(changes)
+		if err != nil {
+			return err
+		}
+
+		// ...and update the stored changes
+		changeset.Changes = changeBytes
+		if csVersion == 0 {
+			_, err = m.kv.SetIfNotExists(m.changeSetKey, &changeset)
+		} else {
+			_, err = m.kv.CheckAndSet(m.changeSetKey, csVersion, &changeset)
+		}
+
+		if err != nil {
+			if err == kv.ErrVersionMismatch || err == kv.ErrAlreadyExists {
+				// Someone else updated the changes first - try again
+				continue
+			}
+
+			return err
+		}
+
+		return nil
+	}
+}
+
+func (m txnManager) GetPendingChanges() (map[string]int, map[string]proto.Message, proto.Message, error) {
+	versions, configs, err := m.getConfigs()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	csVal, err := m.kv.Get(m.changeSetKey)
+	if err != nil {
+		if err == kv.ErrNotFound {
+			// It's ok, just means no pending changes
+			return toIntVersions(versions), configs, nil, nil
+		}
+
+		return nil, nil, nil, err
+	}
+
+	var changeset changesetpb.ChangeSet
+	if err := csVal.Unmarshal(&changeset); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if changeset.State == changesetpb.ChangeSetState_COMMITTED {
+		return toIntVersions(versions), configs, nil, nil
+	}
+
+	changes := proto.Clone(m.changesType)
+	if err := proto.Unmarshal(changeset.Changes, changes); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return toIntVersions(changeset.ConfigVersions), configs, changes, nil
+}
+
+func (m txnManager) Commit(apply TxnApplyFn) error {
+	csVal, err := m.kv.Get(m.changeSetKey)
+	if err != nil {
+		return err
+	}
+
+	var changeset changesetpb.ChangeSet
+	if err := csVal.Unmarshal(&changeset); err != nil {
+		return err
+	}
+
+	switch changeset.State {
+	case changesetpb.ChangeSetState_OPEN, changesetpb.ChangeSetState_CLOSED:
+	case changesetpb.ChangeSetState_COMMITTED:
+		return ErrAlreadyCommitted
+	default:
+		return ErrChangeSetClosed
+	}
+
+	// Make sure none of the configurations have moved on since the changes
+	// were started
+	versions, configs, err := m.getConfigs()
+	if err != nil {
+		return err
+	}
+
+	if !sameVersions(changeset.ConfigVersions, versions) {
+		return ErrConfigChanged
+	}
+
+	// If the change set is not already CLOSED, mark it as such to prevent new
+	// changes from being recorded while the commit is underway
+	csVersion := csVal.Version()
+	if changeset.State == changesetpb.ChangeSetState_OPEN {
+		changeset.State = changesetpb.ChangeSetState_CLOSED
+		closedVersion, err := m.kv.CheckAndSet(m.changeSetKey, csVersion, &changeset)
+		if err != nil {
+			if err == kv.ErrVersionMismatch {
+				return ErrCommitInProgress
+			}
+
+			return err
+		}
+
+		csVersion = closedVersion
+	}
+
+	// Transform the current configurations according to the change list,
+	// keeping the originals to find out which keys actually changed
+	changes := proto.Clone(m.changesType)
+	if err := proto.Unmarshal(changeset.Changes, changes); err != nil {
+		return err
+	}
+
+	original := make(map[string]proto.Message, len(configs))
+	for key, config := range configs {
+		original[key] = proto.Clone(config)
+	}
+
+	if err := apply(configs, changes); err != nil {
+		return err
+	}
+
+	// Write the modified configurations and the committed change set in a
+	// single transaction, conditional on every key involved being unchanged
+	changeset.State = changesetpb.ChangeSetState_COMMITTED
+	changeset.CommitTimeNanos = m.nowFn().UnixNano()
+
+	conditions := make([]kv.Condition, 0, len(m.keys)+1)
+	ops := make([]kv.Op, 0, len(m.keys)+1)
+	for _, key := range m.keys {
+		conditions = append(conditions, versionCondition(key, int(versions[key])))
+		if !proto.Equal(original[key], configs[key]) {
+			ops = append(ops, kv.NewSetOp(key, configs[key]))
+		}
+	}
+	conditions = append(conditions, versionCondition(m.changeSetKey, csVersion))
+	ops = append(ops, kv.NewSetOp(m.changeSetKey, &changeset))
+
+	if _, err := m.kv.Commit(conditions, ops); err != nil {
+		if err == kv.ErrConditionCheckFailed {
+			return ErrConfigChanged
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (m txnManager) Discard() error {
+	csVal, changeset, err := m.getPendingChangeSet()
+	if err != nil {
+		return err
+	}
+
+	// NB: a commit still underway fails once the change set is rewritten,
+	// since the commit is conditional on the version of the change set
+	versions, _, err := m.getConfigs()
+	if err != nil {
+		return err
+	}
+
+	changeset = &changesetpb.ChangeSet{
+		State:          changesetpb.ChangeSetState_OPEN,
+		ConfigVersions: versions,
+	}
+	if _, err := m.kv.CheckAndSet(m.changeSetKey, csVal.Version(), changeset); err != nil {
+		if err == kv.ErrVersionMismatch {
+			return ErrConcurrentChange
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (m txnManager) Reopen() error {
+	csVal, changeset, err := m.getPendingChangeSet()
+	if err != nil {
+		return err
+	}
+
+	if changeset.State != changesetpb.ChangeSetState_CLOSED {
+		return ErrChangeSetNotClosed
+	}
+
+	changeset.State = changesetpb.ChangeSetState_OPEN
+	if _, err := m.kv.CheckAndSet(m.changeSetKey, csVal.Version(), changeset); err != nil {
+		if err == kv.ErrVersionMismatch {
+			return ErrConcurrentChange
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (m txnManager) Rebase(conflict TxnConflictFn) error {
+	csVal, changeset, err := m.getPendingChangeSet()
+	if err != nil {
+		return err
+	}
+
+	versions, latestConfigs, err := m.getConfigs()
+	if err != nil {
+		return err
+	}
+
+	if sameVersions(changeset.ConfigVersions, versions) {
+		return ErrNotStale
+	}
+
+	changes := proto.Clone(m.changesType)
+	if err := proto.Unmarshal(changeset.Changes, changes); err != nil {
+		return err
+	}
+
+	if conflict != nil {
+		baseConfigs, err := m.getConfigsAtVersions(changeset.ConfigVersions)
+		if err != nil {
+			return err
+		}
+
+		if err := conflict(baseConfigs, latestConfigs, changes); err != nil {
+			return err
+		}
+	}
+
+	changeset.State = changesetpb.ChangeSetState_OPEN
+	changeset.ConfigVersions = versions
+	if _, err := m.kv.CheckAndSet(m.changeSetKey, csVal.Version(), changeset); err != nil {
+		if err == kv.ErrVersionMismatch {
+			return ErrRebaseInProgress
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// getPendingChangeSet retrieves the change set, which must either be open or
+// have been left CLOSED by a failed commit
+func (m txnManager) getPendingChangeSet() (kv.Value, *changesetpb.ChangeSet, error) {
+	csVal, err := m.kv.Get(m.changeSetKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var changeset changesetpb.ChangeSet
+	if err := csVal.Unmarshal(&changeset); err != nil {
+		return nil, nil, err
+	}
+
+	switch changeset.State {
+	case changesetpb.ChangeSetState_OPEN, changesetpb.ChangeSetState_CLOSED:
+	case changesetpb.ChangeSetState_COMMITTED:
+		return nil, nil, ErrAlreadyCommitted
+	default:
+		return nil, nil, ErrChangeSetClosed
+	}
+
+	return csVal, &changeset, nil
+}
+
+// getConfigsAtVersions retrieves the specified past version of the
+// configuration under each key.  Keys at version 0 have an empty configuration
+func (m txnManager) getConfigsAtVersions(versions map[string]int32) (map[string]proto.Message, error) {
+	configs := make(map[string]proto.Message, len(m.keys))
+	for _, key := range m.keys {
+		config := proto.Clone(m.configTypes[key])
+		if version := int(versions[key]); version > 0 {
+			vals, err := m.kv.History(key, version, version+1)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(vals) == 0 {
+				return nil, ErrUnknownVersion
+			}
+
+			if err := vals[0].Unmarshal(config); err != nil {
+				return nil, err
+			}
+		}
+
+		configs[key] = config
+	}
+
+	return configs, nil
+}
+
+// getConfigs retrieves the latest version of the configuration under each
+// key.  Keys that do not exist yet have version 0 and an empty configuration
+func (m txnManager) getConfigs() (map[string]int32, map[string]proto.Message, error) {
+	versions := make(map[string]int32, len(m.keys))
+	configs := make(map[string]proto.Message, len(m.keys))
+	for _, key := range m.keys {
+		config := proto.Clone(m.configTypes[key])
+		val, err := m.kv.Get(key)
+		if err != nil && err != kv.ErrNotFound {
+			return nil, nil, err
+		}
+
+		versions[key] = 0
+		if err == nil {
+			if err := val.Unmarshal(config); err != nil {
+				return nil, nil, err
+			}
+			versions[key] = int32(val.Version())
+		}
+
+		configs[key] = config
+	}
+
+	return versions, configs, nil
+}
+
+func versionCondition(key string, version int) kv.Condition {
+	return kv.NewCondition().
+		SetCompareType(kv.CompareEqual).
+		SetTargetType(kv.TargetVersion).
+		SetKey(key).
+		SetValue(version)
+}
+
+func sameVersions(a, b map[string]int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for key, version := range a {
+		if other, ok := b[key]; !ok || other != version {
+			return false
+		}
+	}
+
+	return true
+}
+
+func toIntVersions(versions map[string]int32) map[string]int {
+	res := make(map[string]int, len(versions))
+	for key, version := range versions {
+		res[key] = int(version)
+	}
+	return res
+}
+
+type txnManagerOptions struct {
+	kv           kv.TxnStore
+	changeSetKey string
+	configTypes  map[string]proto.Message
+	changesType  proto.Message
+	nowFn        clock.NowFn
+}
+
+func (opts txnManagerOptions) KV() kv.TxnStore                       { return opts.kv }
+func (opts txnManagerOptions) ChangeSetKey() string                  { return opts.changeSetKey }
+func (opts txnManagerOptions) ConfigTypes() map[string]proto.Message { return opts.configTypes }
+func (opts txnManagerOptions) ChangesType() proto.Message            { return opts.changesType }
+func (opts txnManagerOptions) NowFn() clock.NowFn                    { return opts.nowFn }
+
+func (opts txnManagerOptions) SetKV(kv kv.TxnStore) TxnManagerOptions {
+	opts.kv = kv
+	return opts
+}
+func (opts txnManagerOptions) SetChangeSetKey(k string) TxnManagerOptions {
+	opts.changeSetKey = k
+	return opts
+}
+func (opts txnManagerOptions) SetConfigTypes(configTypes map[string]proto.Message) TxnManagerOptions {
+	opts.configTypes = configTypes
+	return opts
+}
+func (opts txnManagerOptions) SetChangesType(ct proto.Message) TxnManagerOptions {
+	opts.changesType = ct
+	return opts
+}
+func (opts txnManagerOptions) SetNowFn(fn clock.NowFn) TxnManagerOptions {
+	opts.nowFn = fn
+	return opts
+}
+
+func (opts txnManagerOptions) Validate() error {
+	if opts.ChangeSetKey() == "" {
+		return errChangeSetKeyNotSet
+	}
+
+	if opts.KV() == nil {
+		return errKVNotSet
+	}
+
+	if len(opts.ConfigTypes()) == 0 {
+		return errConfigTypesNotSet
+	}
+
+	if opts.ChangesType() == nil {
+		return errChangeTypeNotSet
+	}
+
+	return nil
+}
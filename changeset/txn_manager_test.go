@@ -0,0 +1,298 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package changeset
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/m3db/m3cluster/generated/proto/changesetpb"
+	"github.com/m3db/m3cluster/generated/proto/changesettest"
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxnManager_CommitSuccess(t *testing.T) {
+	store, mgr := newTxnTestManager(t)
+
+	_, err := store.Set("limits", &changesettest.Config{Text: "10"})
+	require.NoError(t, err)
+
+	require.NoError(t, mgr.Change(setText("routing", "foo")))
+	require.NoError(t, mgr.Change(setText("limits", "20")))
+
+	versions, configs, changes, err := mgr.GetPendingChanges()
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"routing": 0, "limits": 1, "unused": 0}, versions)
+	require.Equal(t, "10", configs["limits"].(*changesettest.Config).Text)
+	require.Equal(t, []string{"routing=foo", "limits=20"}, changes.(*changesettest.Changes).Lines)
+
+	require.NoError(t, mgr.Commit(applyTexts))
+
+	val, err := store.Get("routing")
+	require.NoError(t, err)
+	require.Equal(t, 1, val.Version())
+	requireText(t, val, "foo")
+
+	val, err = store.Get("limits")
+	require.NoError(t, err)
+	require.Equal(t, 2, val.Version())
+	requireText(t, val, "20")
+
+	// Keys not modified by the changes are not written
+	_, err = store.Get("unused")
+	require.Equal(t, kv.ErrNotFound, err)
+
+	val, err = store.Get("routes_and_limits")
+	require.NoError(t, err)
+	var changeset changesetpb.ChangeSet
+	require.NoError(t, val.Unmarshal(&changeset))
+	require.Equal(t, changesetpb.ChangeSetState_COMMITTED, changeset.State)
+
+	require.Equal(t, ErrAlreadyCommitted, mgr.Commit(applyTexts))
+
+	// New changes start a new change set against the committed configurations
+	require.NoError(t, mgr.Change(setText("routing", "bar")))
+	versions, _, changes, err = mgr.GetPendingChanges()
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"routing": 1, "limits": 2, "unused": 0}, versions)
+	require.Equal(t, []string{"routing=bar"}, changes.(*changesettest.Changes).Lines)
+}
+
+func TestTxnManager_ConfigChanged(t *testing.T) {
+	store, mgr := newTxnTestManager(t)
+
+	require.NoError(t, mgr.Change(setText("routing", "foo")))
+	_, err := store.Set("limits", &changesettest.Config{Text: "10"})
+	require.NoError(t, err)
+
+	require.Equal(t, ErrConfigChanged, mgr.Change(setText("limits", "20")))
+	require.Equal(t, ErrConfigChanged, mgr.Commit(applyTexts))
+
+	_, err = store.Get("routing")
+	require.Equal(t, kv.ErrNotFound, err)
+}
+
+func TestTxnManager_ConfigChangedDuringCommit(t *testing.T) {
+	store, mgr := newTxnTestManager(t)
+
+	require.NoError(t, mgr.Change(setText("routing", "foo")))
+	require.Equal(t, ErrConfigChanged, mgr.Commit(func(configs map[string]proto.Message, changes proto.Message) error {
+		// Simulate a concurrent update landing after the changes are applied
+		if _, err := store.Set("limits", &changesettest.Config{Text: "10"}); err != nil {
+			return err
+		}
+		return applyTexts(configs, changes)
+	}))
+
+	_, err := store.Get("routing")
+	require.Equal(t, kv.ErrNotFound, err)
+}
+
+func TestTxnManager_ChangeOnClosedChangeSet(t *testing.T) {
+	_, mgr := newTxnTestManager(t)
+
+	require.NoError(t, mgr.Change(setText("routing", "foo")))
+	require.Equal(t, errBadThingsHappened, mgr.Commit(func(_ map[string]proto.Message, _ proto.Message) error {
+		return errBadThingsHappened
+	}))
+	require.Equal(t, ErrChangeSetClosed, mgr.Change(setText("routing", "bar")))
+
+	// The commit can be completed later
+	require.NoError(t, mgr.Commit(applyTexts))
+}
+
+func TestTxnManager_RebaseAfterConfigChanged(t *testing.T) {
+	store, mgr := newTxnTestManager(t)
+
+	require.NoError(t, mgr.Change(setText("routing", "foo")))
+	_, err := store.Set("limits", &changesettest.Config{Text: "10"})
+	require.NoError(t, err)
+	require.Equal(t, ErrConfigChanged, mgr.Change(setText("routing", "bar")))
+
+	// A conflict aborts the rebase
+	errConflict := errors.New("conflict")
+	require.Equal(t, errConflict, mgr.Rebase(func(base, latest map[string]proto.Message, _ proto.Message) error {
+		require.Equal(t, "", base["limits"].(*changesettest.Config).Text)
+		require.Equal(t, "10", latest["limits"].(*changesettest.Config).Text)
+		return errConflict
+	}))
+	require.Equal(t, ErrConfigChanged, mgr.Commit(applyTexts))
+
+	require.NoError(t, mgr.Rebase(nil))
+	require.Equal(t, ErrNotStale, mgr.Rebase(nil))
+
+	// The manager works again on the latest configurations
+	require.NoError(t, mgr.Change(setText("routing", "bar")))
+	require.NoError(t, mgr.Commit(applyTexts))
+
+	val, err := store.Get("routing")
+	require.NoError(t, err)
+	requireText(t, val, "bar")
+	val, err = store.Get("limits")
+	require.NoError(t, err)
+	requireText(t, val, "10")
+}
+
+func TestTxnManager_DiscardAfterConfigChanged(t *testing.T) {
+	store, mgr := newTxnTestManager(t)
+
+	require.NoError(t, mgr.Change(setText("routing", "foo")))
+	_, err := store.Set("limits", &changesettest.Config{Text: "10"})
+	require.NoError(t, err)
+	require.Equal(t, ErrConfigChanged, mgr.Commit(applyTexts))
+
+	require.NoError(t, mgr.Discard())
+	versions, _, changes, err := mgr.GetPendingChanges()
+	require.NoError(t, err)
+	require.Equal(t, 1, versions["limits"])
+	require.Empty(t, changes.(*changesettest.Changes).Lines)
+
+	require.NoError(t, mgr.Change(setText("limits", "20")))
+	require.NoError(t, mgr.Commit(applyTexts))
+
+	_, err = store.Get("routing")
+	require.Equal(t, kv.ErrNotFound, err)
+	val, err := store.Get("limits")
+	require.NoError(t, err)
+	requireText(t, val, "20")
+
+	require.Equal(t, ErrAlreadyCommitted, mgr.Discard())
+}
+
+func TestTxnManager_RecoverFailedCommit(t *testing.T) {
+	store, mgr := newTxnTestManager(t)
+	failCommit := func(_ map[string]proto.Message, _ proto.Message) error {
+		return errBadThingsHappened
+	}
+
+	// Reopening allows further changes to be made before committing again
+	require.NoError(t, mgr.Change(setText("routing", "foo")))
+	require.Equal(t, errBadThingsHappened, mgr.Commit(failCommit))
+	require.Equal(t, ErrChangeSetClosed, mgr.Change(setText("routing", "bar")))
+	require.NoError(t, mgr.Reopen())
+	require.Equal(t, ErrChangeSetNotClosed, mgr.Reopen())
+	require.NoError(t, mgr.Change(setText("routing", "bar")))
+	require.NoError(t, mgr.Commit(applyTexts))
+
+	val, err := store.Get("routing")
+	require.NoError(t, err)
+	requireText(t, val, "bar")
+
+	// A failed commit whose configurations have since changed can be rebased
+	require.NoError(t, mgr.Change(setText("routing", "baz")))
+	require.Equal(t, errBadThingsHappened, mgr.Commit(failCommit))
+	_, err = store.Set("limits", &changesettest.Config{Text: "10"})
+	require.NoError(t, err)
+	require.Equal(t, ErrConfigChanged, mgr.Commit(applyTexts))
+	require.NoError(t, mgr.Rebase(nil))
+	require.NoError(t, mgr.Commit(applyTexts))
+
+	val, err = store.Get("routing")
+	require.NoError(t, err)
+	requireText(t, val, "baz")
+
+	// Or discarded
+	require.NoError(t, mgr.Change(setText("routing", "qux")))
+	require.Equal(t, errBadThingsHappened, mgr.Commit(failCommit))
+	require.NoError(t, mgr.Discard())
+	require.NoError(t, mgr.Change(setText("limits", "20")))
+	require.NoError(t, mgr.Commit(applyTexts))
+
+	val, err = store.Get("routing")
+	require.NoError(t, err)
+	requireText(t, val, "baz")
+	val, err = store.Get("limits")
+	require.NoError(t, err)
+	requireText(t, val, "20")
+}
+
+func TestTxnManagerOptions_Validate(t *testing.T) {
+	configTypes := map[string]proto.Message{"foo": &changesettest.Config{}}
+	tests := []struct {
+		err  error
+		opts TxnManagerOptions
+	}{
+		{errChangeSetKeyNotSet, NewTxnManagerOptions().
+			SetConfigTypes(configTypes).
+			SetChangesType(&changesettest.Changes{}).
+			SetKV(mem.NewStore())},
+
+		{errKVNotSet, NewTxnManagerOptions().
+			SetChangeSetKey("bar").
+			SetConfigTypes(configTypes).
+			SetChangesType(&changesettest.Changes{})},
+
+		{errConfigTypesNotSet, NewTxnManagerOptions().
+			SetChangeSetKey("bar").
+			SetChangesType(&changesettest.Changes{}).
+			SetKV(mem.NewStore())},
+
+		{errChangeTypeNotSet, NewTxnManagerOptions().
+			SetChangeSetKey("bar").
+			SetConfigTypes(configTypes).
+			SetKV(mem.NewStore())},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.err, test.opts.Validate())
+	}
+}
+
+func newTxnTestManager(t *testing.T) (kv.TxnStore, TxnManager) {
+	store := mem.NewStore()
+	mgr, err := NewTxnManager(NewTxnManagerOptions().
+		SetKV(store).
+		SetChangeSetKey("routes_and_limits").
+		SetConfigTypes(map[string]proto.Message{
+			"routing": &changesettest.Config{},
+			"limits":  &changesettest.Config{},
+			"unused":  &changesettest.Config{},
+		}).
+		SetChangesType(&changesettest.Changes{}))
+	require.NoError(t, err)
+	return store, mgr
+}
+
+func setText(key, text string) TxnChangeFn {
+	return func(_ map[string]proto.Message, changesProto proto.Message) error {
+		changes := changesProto.(*changesettest.Changes)
+		changes.Lines = append(changes.Lines, key+"="+text)
+		return nil
+	}
+}
+
+func applyTexts(configs map[string]proto.Message, changesProto proto.Message) error {
+	for _, line := range changesProto.(*changesettest.Changes).Lines {
+		parts := strings.SplitN(line, "=", 2)
+		configs[parts[0]].(*changesettest.Config).Text = parts[1]
+	}
+	return nil
+}
+
+func requireText(t *testing.T, val kv.Value, text string) {
+	var config changesettest.Config
+	require.NoError(t, val.Unmarshal(&config))
+	require.Equal(t, text, config.Text)
+}
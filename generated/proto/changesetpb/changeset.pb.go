@@ -30,6 +30,7 @@ It is generated from these files:
 
 It has these top-level messages:
 	ChangeSet
+	Approval
 */
 package changesetpb
 
@@ -52,20 +53,26 @@ const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 type ChangeSetState int32
 
 const (
-	ChangeSetState_UNKNOWN ChangeSetState = 0
-	ChangeSetState_OPEN    ChangeSetState = 1
-	ChangeSetState_CLOSED  ChangeSetState = 2
+	ChangeSetState_UNKNOWN   ChangeSetState = 0
+	ChangeSetState_OPEN      ChangeSetState = 1
+	ChangeSetState_CLOSED    ChangeSetState = 2
+	ChangeSetState_REBASED   ChangeSetState = 3
+	ChangeSetState_COMMITTED ChangeSetState = 4
 )
 
 var ChangeSetState_name = map[int32]string{
 	0: "UNKNOWN",
 	1: "OPEN",
 	2: "CLOSED",
+	3: "REBASED",
+	4: "COMMITTED",
 }
 var ChangeSetState_value = map[string]int32{
-	"UNKNOWN": 0,
-	"OPEN":    1,
-	"CLOSED":  2,
+	"UNKNOWN":   0,
+	"OPEN":      1,
+	"CLOSED":    2,
+	"REBASED":   3,
+	"COMMITTED": 4,
 }
 
 func (x ChangeSetState) String() string {
@@ -73,6 +80,31 @@ func (x ChangeSetState) String() string {
 }
 func (ChangeSetState) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
+// ApprovalDecision is the decision of an approver on a changeset
+type ApprovalDecision int32
+
+const (
+	ApprovalDecision_UNDECIDED ApprovalDecision = 0
+	ApprovalDecision_APPROVED  ApprovalDecision = 1
+	ApprovalDecision_REJECTED  ApprovalDecision = 2
+)
+
+var ApprovalDecision_name = map[int32]string{
+	0: "UNDECIDED",
+	1: "APPROVED",
+	2: "REJECTED",
+}
+var ApprovalDecision_value = map[string]int32{
+	"UNDECIDED": 0,
+	"APPROVED":  1,
+	"REJECTED":  2,
+}
+
+func (x ApprovalDecision) String() string {
+	return proto.EnumName(ApprovalDecision_name, int32(x))
+}
+func (ApprovalDecision) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
 // A ChangeSet is a set of changes that are applied together.  The exact
 // format of the changes is up to the application; the ChangeSet simply
 // tracks the state of application
@@ -83,6 +115,20 @@ type ChangeSet struct {
 	State ChangeSetState `protobuf:"varint,2,opt,name=state,enum=changesetpb.ChangeSetState" json:"state,omitempty"`
 	// changes are the marshalled form of the changes
 	Changes []byte `protobuf:"bytes,3,opt,name=changes,proto3" json:"changes,omitempty"`
+	// author is the author of the changes
+	Author string `protobuf:"bytes,4,opt,name=author" json:"author,omitempty"`
+	// description describes the purpose of the changes
+	Description string `protobuf:"bytes,5,opt,name=description" json:"description,omitempty"`
+	// approvals are the reviews of the changes, at most one per approver
+	Approvals []*Approval `protobuf:"bytes,6,rep,name=approvals" json:"approvals,omitempty"`
+	// committed_version is the version of configuration produced by
+	// committing this ChangeSet
+	CommittedVersion int32 `protobuf:"varint,7,opt,name=committed_version,json=committedVersion" json:"committed_version,omitempty"`
+	// commit_time_nanos is the time this ChangeSet was committed
+	CommitTimeNanos int64 `protobuf:"varint,8,opt,name=commit_time_nanos,json=commitTimeNanos" json:"commit_time_nanos,omitempty"`
+	// config_versions are the versions of each configuration key on which a
+	// ChangeSet spanning several keys is built
+	ConfigVersions map[string]int32 `protobuf:"bytes,9,rep,name=config_versions,json=configVersions" json:"config_versions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
 }
 
 func (m *ChangeSet) Reset()                    { *m = ChangeSet{} }
@@ -90,24 +136,76 @@ func (m *ChangeSet) String() string            { return proto.CompactTextString(
 func (*ChangeSet) ProtoMessage()               {}
 func (*ChangeSet) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
+func (m *ChangeSet) GetApprovals() []*Approval {
+	if m != nil {
+		return m.Approvals
+	}
+	return nil
+}
+
+func (m *ChangeSet) GetConfigVersions() map[string]int32 {
+	if m != nil {
+		return m.ConfigVersions
+	}
+	return nil
+}
+
+// An Approval records the review of a ChangeSet by an approver
+type Approval struct {
+	// approver is the identity of the approver
+	Approver string `protobuf:"bytes,1,opt,name=approver" json:"approver,omitempty"`
+	// decision is the decision of the approver
+	Decision ApprovalDecision `protobuf:"varint,2,opt,name=decision,enum=changesetpb.ApprovalDecision" json:"decision,omitempty"`
+	// comment is an optional comment from the approver
+	Comment string `protobuf:"bytes,3,opt,name=comment" json:"comment,omitempty"`
+	// timestamp_nanos is the time of the review
+	TimestampNanos int64 `protobuf:"varint,4,opt,name=timestamp_nanos,json=timestampNanos" json:"timestamp_nanos,omitempty"`
+}
+
+func (m *Approval) Reset()                    { *m = Approval{} }
+func (m *Approval) String() string            { return proto.CompactTextString(m) }
+func (*Approval) ProtoMessage()               {}
+func (*Approval) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
 func init() {
 	proto.RegisterType((*ChangeSet)(nil), "changesetpb.ChangeSet")
+	proto.RegisterType((*Approval)(nil), "changesetpb.Approval")
 	proto.RegisterEnum("changesetpb.ChangeSetState", ChangeSetState_name, ChangeSetState_value)
+	proto.RegisterEnum("changesetpb.ApprovalDecision", ApprovalDecision_name, ApprovalDecision_value)
 }
 
 func init() { proto.RegisterFile("changeset.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 176 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0x4f, 0xce, 0x48, 0xcc,
-	0x4b, 0x4f, 0x2d, 0x4e, 0x2d, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x86, 0x0b, 0x14,
-	0x24, 0x29, 0x55, 0x72, 0x71, 0x3a, 0x83, 0xb9, 0xc1, 0xa9, 0x25, 0x42, 0xf2, 0x5c, 0xdc, 0x69,
-	0xf9, 0x45, 0xf1, 0x65, 0xa9, 0x45, 0xc5, 0x99, 0xf9, 0x79, 0x12, 0x8c, 0x0a, 0x8c, 0x1a, 0xac,
-	0x41, 0x5c, 0x69, 0xf9, 0x45, 0x61, 0x10, 0x11, 0x21, 0x43, 0x2e, 0xd6, 0xe2, 0x92, 0xc4, 0x92,
-	0x54, 0x09, 0x26, 0x05, 0x46, 0x0d, 0x3e, 0x23, 0x69, 0x3d, 0x24, 0xa3, 0xf4, 0xe0, 0xe6, 0x04,
-	0x83, 0x94, 0x04, 0x41, 0x54, 0x0a, 0x49, 0x70, 0xb1, 0x43, 0x15, 0x49, 0x30, 0x2b, 0x30, 0x6a,
-	0xf0, 0x04, 0xc1, 0xb8, 0x5a, 0xc6, 0x5c, 0x7c, 0xa8, 0x5a, 0x84, 0xb8, 0xb9, 0xd8, 0x43, 0xfd,
-	0xbc, 0xfd, 0xfc, 0xc3, 0xfd, 0x04, 0x18, 0x84, 0x38, 0xb8, 0x58, 0xfc, 0x03, 0x5c, 0xfd, 0x04,
-	0x18, 0x85, 0xb8, 0xb8, 0xd8, 0x9c, 0x7d, 0xfc, 0x83, 0x5d, 0x5d, 0x04, 0x98, 0x92, 0xd8, 0xc0,
-	0x7e, 0x30, 0x06, 0x04, 0x00, 0x00, 0xff, 0xff, 0x89, 0xba, 0xc3, 0x6a, 0xd6, 0x00, 0x00, 0x00,
+	// 478 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x6c, 0x93, 0xcd, 0x6e, 0x9b, 0x40,
+	0x14, 0x85, 0x33, 0xc6, 0xd8, 0x70, 0x49, 0x6d, 0x3a, 0xfd, 0x11, 0x4a, 0x55, 0x15, 0x65, 0x53,
+	0xe4, 0x4a, 0x96, 0x9a, 0x6c, 0xda, 0x4a, 0x5d, 0xb8, 0x30, 0x8b, 0xb4, 0x0d, 0x58, 0x63, 0x27,
+	0x5d, 0x5a, 0x04, 0x8f, 0x13, 0xd4, 0xc0, 0x20, 0x98, 0x58, 0xca, 0xfb, 0xf4, 0xa5, 0xfa, 0x36,
+	0xd5, 0x0c, 0x3f, 0xb5, 0x23, 0xef, 0x38, 0xe7, 0x7e, 0x33, 0xdc, 0x7b, 0x2e, 0xc0, 0x38, 0xb9,
+	0x8b, 0xf3, 0x5b, 0x56, 0x31, 0x31, 0x2d, 0x4a, 0x2e, 0x38, 0xb6, 0x3a, 0xa3, 0xb8, 0x39, 0xfd,
+	0xab, 0x81, 0xe9, 0x2b, 0xbd, 0x60, 0x02, 0xbf, 0x03, 0x6b, 0xc3, 0xcb, 0xd5, 0x96, 0x95, 0x55,
+	0xca, 0x73, 0x07, 0xb9, 0xc8, 0xd3, 0x29, 0x6c, 0x78, 0x79, 0x5d, 0x3b, 0xf8, 0x23, 0xe8, 0x95,
+	0x88, 0x05, 0x73, 0x7a, 0x2e, 0xf2, 0x46, 0x67, 0x6f, 0xa6, 0x3b, 0x77, 0x4d, 0xbb, 0x7b, 0x16,
+	0x12, 0xa1, 0x35, 0x89, 0x1d, 0x18, 0x36, 0x90, 0xa3, 0xb9, 0xc8, 0x3b, 0xa6, 0xad, 0xc4, 0xaf,
+	0x61, 0x10, 0x3f, 0x88, 0x3b, 0x5e, 0x3a, 0x7d, 0x17, 0x79, 0x26, 0x6d, 0x14, 0x76, 0xc1, 0x5a,
+	0xb3, 0x2a, 0x29, 0xd3, 0x42, 0xc8, 0x2e, 0x74, 0x55, 0xdc, 0xb5, 0xf0, 0x39, 0x98, 0x71, 0x51,
+	0x94, 0x7c, 0x1b, 0xdf, 0x57, 0xce, 0xc0, 0xd5, 0x3c, 0xeb, 0xec, 0xd5, 0x5e, 0x2b, 0xb3, 0xa6,
+	0x4a, 0xff, 0x73, 0xf8, 0x03, 0x3c, 0x4f, 0x78, 0x96, 0xa5, 0x42, 0xb0, 0x75, 0x37, 0xe2, 0x50,
+	0x8d, 0x68, 0x77, 0x85, 0x76, 0xd0, 0x49, 0x0b, 0xaf, 0x44, 0x9a, 0xb1, 0x55, 0x1e, 0xe7, 0xbc,
+	0x72, 0x0c, 0x17, 0x79, 0x1a, 0x1d, 0xd7, 0x85, 0x65, 0x9a, 0xb1, 0x50, 0xda, 0x78, 0x01, 0xe3,
+	0x84, 0xe7, 0x9b, 0xf4, 0xb6, 0xbd, 0xb5, 0x72, 0x4c, 0xd5, 0xd3, 0xe4, 0x70, 0x3c, 0x53, 0x5f,
+	0xd1, 0xcd, 0xab, 0x2a, 0x92, 0x8b, 0xf2, 0x91, 0x8e, 0x92, 0x3d, 0xf3, 0x64, 0x06, 0x2f, 0x0e,
+	0x60, 0xd8, 0x06, 0xed, 0x37, 0x7b, 0x54, 0x9b, 0x31, 0xa9, 0x7c, 0xc4, 0x2f, 0x41, 0xdf, 0xc6,
+	0xf7, 0x0f, 0xf5, 0x4a, 0x74, 0x5a, 0x8b, 0x2f, 0xbd, 0x4f, 0xe8, 0xf4, 0x0f, 0x02, 0xa3, 0x0d,
+	0x02, 0x9f, 0x80, 0x51, 0x47, 0xc1, 0xca, 0xe6, 0x74, 0xa7, 0xf1, 0x67, 0x30, 0xd6, 0x2c, 0x49,
+	0x55, 0x20, 0xf5, 0x62, 0xdf, 0x1e, 0x4c, 0x33, 0x68, 0x20, 0xda, 0xe1, 0x6a, 0xbb, 0x3c, 0xcb,
+	0x58, 0x2e, 0xd4, 0x76, 0x4d, 0xda, 0x4a, 0xfc, 0x1e, 0xc6, 0x32, 0xba, 0x4a, 0xc4, 0x59, 0xd1,
+	0xe4, 0xd7, 0x57, 0xf9, 0x8d, 0x3a, 0x5b, 0xc5, 0x37, 0x89, 0x60, 0xb4, 0xff, 0xe5, 0x60, 0x0b,
+	0x86, 0x57, 0xe1, 0x8f, 0x30, 0xfa, 0x15, 0xda, 0x47, 0xd8, 0x80, 0x7e, 0x34, 0x27, 0xa1, 0x8d,
+	0x30, 0xc0, 0xc0, 0xff, 0x19, 0x2d, 0x48, 0x60, 0xf7, 0x24, 0x42, 0xc9, 0xb7, 0x99, 0x14, 0x1a,
+	0x7e, 0x06, 0xa6, 0x1f, 0x5d, 0x5e, 0x5e, 0x2c, 0x97, 0x24, 0xb0, 0xfb, 0x93, 0xaf, 0x60, 0x3f,
+	0xed, 0x58, 0x22, 0x57, 0x61, 0x40, 0xfc, 0x8b, 0x80, 0x04, 0xf6, 0x11, 0x3e, 0x06, 0x63, 0x36,
+	0x9f, 0xd3, 0xe8, 0x9a, 0x04, 0x36, 0x92, 0x8a, 0x92, 0xef, 0xc4, 0x97, 0xc7, 0x7b, 0x37, 0x03,
+	0xf5, 0x9b, 0x9c, 0xff, 0x1b, 0x00, 0x7f, 0xab, 0x6d, 0x0b, 0x39, 0x03, 0x00, 0x00,
 }
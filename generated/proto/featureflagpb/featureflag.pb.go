@@ -0,0 +1,136 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Code generated by protoc-gen-go.
+// source: featureflag.proto
+// DO NOT EDIT!
+
+/*
+Package featureflagpb is a generated protocol buffer package.
+
+It is generated from these files:
+	featureflag.proto
+
+It has these top-level messages:
+	FeatureFlag
+	Rule
+	Match
+*/
+package featureflagpb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// A FeatureFlag describes how a feature is rolled out. When the flag is
+// disabled the feature is off for every entity. Otherwise the first rule
+// whose matches all apply to an entity decides the rollout percentage for
+// that entity, falling back to the flag-level percentage if no rule applies.
+type FeatureFlag struct {
+	// enabled is the master switch of the flag.
+	Enabled bool `protobuf:"varint,1,opt,name=enabled" json:"enabled,omitempty"`
+	// percentage is the default rollout percentage in the range [0, 100].
+	Percentage float64 `protobuf:"fixed64,2,opt,name=percentage" json:"percentage,omitempty"`
+	// rules are the targeting rules evaluated in order.
+	Rules []*Rule `protobuf:"bytes,3,rep,name=rules" json:"rules,omitempty"`
+	// salt is mixed into the entity hash so different flags bucket the same
+	// entity independently. The flag key is used if the salt is empty.
+	Salt string `protobuf:"bytes,4,opt,name=salt" json:"salt,omitempty"`
+}
+
+func (m *FeatureFlag) Reset()                    { *m = FeatureFlag{} }
+func (m *FeatureFlag) String() string            { return proto.CompactTextString(m) }
+func (*FeatureFlag) ProtoMessage()               {}
+func (*FeatureFlag) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *FeatureFlag) GetRules() []*Rule {
+	if m != nil {
+		return m.Rules
+	}
+	return nil
+}
+
+// A Rule targets entities whose attributes satisfy all of its matches.
+type Rule struct {
+	Matches []*Match `protobuf:"bytes,1,rep,name=matches" json:"matches,omitempty"`
+	// percentage is the rollout percentage in the range [0, 100] for the
+	// entities targeted by the rule.
+	Percentage float64 `protobuf:"fixed64,2,opt,name=percentage" json:"percentage,omitempty"`
+}
+
+func (m *Rule) Reset()                    { *m = Rule{} }
+func (m *Rule) String() string            { return proto.CompactTextString(m) }
+func (*Rule) ProtoMessage()               {}
+func (*Rule) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *Rule) GetMatches() []*Match {
+	if m != nil {
+		return m.Matches
+	}
+	return nil
+}
+
+// A Match is satisfied if the value of the attribute is one of the values.
+type Match struct {
+	Attribute string   `protobuf:"bytes,1,opt,name=attribute" json:"attribute,omitempty"`
+	Values    []string `protobuf:"bytes,2,rep,name=values" json:"values,omitempty"`
+}
+
+func (m *Match) Reset()                    { *m = Match{} }
+func (m *Match) String() string            { return proto.CompactTextString(m) }
+func (*Match) ProtoMessage()               {}
+func (*Match) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func init() {
+	proto.RegisterType((*FeatureFlag)(nil), "featureflagpb.FeatureFlag")
+	proto.RegisterType((*Rule)(nil), "featureflagpb.Rule")
+	proto.RegisterType((*Match)(nil), "featureflagpb.Match")
+}
+
+func init() { proto.RegisterFile("featureflag.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 220 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x84, 0x90, 0x3f, 0x4f, 0xc3, 0x30,
+	0x10, 0xc5, 0xe5, 0x26, 0x6d, 0xc9, 0x55, 0x0c, 0x1c, 0x08, 0x79, 0x40, 0xc8, 0xca, 0x64, 0x96,
+	0x0c, 0x30, 0xb3, 0x76, 0x63, 0xb9, 0x81, 0xfd, 0x52, 0xae, 0x01, 0xc9, 0xb4, 0x91, 0x7d, 0xe6,
+	0x23, 0xf0, 0xb9, 0x51, 0x5d, 0x2a, 0x02, 0x0b, 0x9b, 0xdf, 0xcf, 0xef, 0xde, 0xfd, 0x81, 0x8b,
+	0xad, 0xb0, 0xe6, 0x28, 0xdb, 0xc0, 0x43, 0x37, 0xc6, 0xbd, 0xee, 0xf1, 0x7c, 0x82, 0xc6, 0xbe,
+	0xfd, 0x34, 0xb0, 0x5a, 0x1f, 0xc9, 0x3a, 0xf0, 0x80, 0x16, 0x96, 0xb2, 0xe3, 0x3e, 0xc8, 0x8b,
+	0x35, 0xce, 0xf8, 0x33, 0x3a, 0x49, 0xbc, 0x05, 0x18, 0x25, 0x6e, 0x64, 0xa7, 0x3c, 0x88, 0x9d,
+	0x39, 0xe3, 0x0d, 0x4d, 0x08, 0xde, 0xc1, 0x3c, 0xe6, 0x20, 0xc9, 0x56, 0xae, 0xf2, 0xab, 0xfb,
+	0xcb, 0xee, 0x57, 0xa3, 0x8e, 0x72, 0x10, 0x3a, 0x3a, 0x10, 0xa1, 0x4e, 0x1c, 0xd4, 0xd6, 0xce,
+	0xf8, 0x86, 0xca, 0xbb, 0x7d, 0x86, 0xfa, 0x60, 0xc1, 0x0e, 0x96, 0xef, 0xac, 0x9b, 0x57, 0x49,
+	0xd6, 0x94, 0xa0, 0xab, 0x3f, 0x41, 0x4f, 0x87, 0x5f, 0x3a, 0x99, 0xfe, 0x1b, 0xab, 0x7d, 0x84,
+	0x79, 0xa9, 0xc0, 0x1b, 0x68, 0x58, 0x35, 0xbe, 0xf5, 0x59, 0xa5, 0xec, 0xd6, 0xd0, 0x0f, 0xc0,
+	0x6b, 0x58, 0x7c, 0x70, 0xc8, 0x92, 0xec, 0xcc, 0x55, 0xbe, 0xa1, 0x6f, 0xd5, 0x2f, 0xca, 0xd5,
+	0x1e, 0xbe, 0x06, 0x00, 0xfd, 0x3b, 0x93, 0xa3, 0x4a, 0x01, 0x00, 0x00,
+}
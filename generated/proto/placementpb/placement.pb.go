@@ -33,6 +33,7 @@ It has these top-level messages:
 	Instance
 	Shard
 	PlacementSnapshots
+	ShardSplit
 */
 package placementpb
 
@@ -85,6 +86,12 @@ type Placement struct {
 	// shard placement.
 	CutoverTime int64 `protobuf:"varint,5,opt,name=cutover_time,json=cutoverTime" json:"cutover_time,omitempty"`
 	IsMirrored  bool  `protobuf:"varint,6,opt,name=is_mirrored,json=isMirrored" json:"is_mirrored,omitempty"`
+	// failure_domain_levels are the ordered failure domain levels, from the outermost
+	// to the innermost, that the replicas of each shard are spread across.
+	FailureDomainLevels []string `protobuf:"bytes,7,rep,name=failure_domain_levels,json=failureDomainLevels" json:"failure_domain_levels,omitempty"`
+	// shard_splits maps the parent shards split by increasing the number of shards
+	// to their child shards.
+	ShardSplits map[uint32]*ShardSplit `protobuf:"bytes,8,rep,name=shard_splits,json=shardSplits" json:"shard_splits,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 }
 
 func (m *Placement) Reset()                    { *m = Placement{} }
@@ -99,6 +106,13 @@ func (m *Placement) GetInstances() map[string]*Instance {
 	return nil
 }
 
+func (m *Placement) GetShardSplits() map[uint32]*ShardSplit {
+	if m != nil {
+		return m.ShardSplits
+	}
+	return nil
+}
+
 type Instance struct {
 	Id         string   `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
 	Rack       string   `protobuf:"bytes,2,opt,name=rack" json:"rack,omitempty"`
@@ -109,6 +123,9 @@ type Instance struct {
 	ShardSetId uint32   `protobuf:"varint,7,opt,name=shard_set_id,json=shardSetId" json:"shard_set_id,omitempty"`
 	Hostname   string   `protobuf:"bytes,8,opt,name=hostname" json:"hostname,omitempty"`
 	Port       uint32   `protobuf:"varint,9,opt,name=port" json:"port,omitempty"`
+	// failure_domains maps failure domain levels (e.g. region, zone, rack, host) to
+	// the domain the instance belongs to at that level.
+	FailureDomains map[string]string `protobuf:"bytes,10,rep,name=failure_domains,json=failureDomains" json:"failure_domains,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 }
 
 func (m *Instance) Reset()                    { *m = Instance{} }
@@ -123,6 +140,13 @@ func (m *Instance) GetShards() []*Shard {
 	return nil
 }
 
+func (m *Instance) GetFailureDomains() map[string]string {
+	if m != nil {
+		return m.FailureDomains
+	}
+	return nil
+}
+
 type Shard struct {
 	Id       uint32     `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
 	State    ShardState `protobuf:"varint,2,opt,name=state,enum=placementpb.ShardState" json:"state,omitempty"`
@@ -157,50 +181,68 @@ func (m *PlacementSnapshots) GetSnapshots() []*Placement {
 	return nil
 }
 
+type ShardSplit struct {
+	ChildShards []uint32 `protobuf:"varint,1,rep,packed,name=child_shards,json=childShards" json:"child_shards,omitempty"`
+}
+
+func (m *ShardSplit) Reset()                    { *m = ShardSplit{} }
+func (m *ShardSplit) String() string            { return proto.CompactTextString(m) }
+func (*ShardSplit) ProtoMessage()               {}
+func (*ShardSplit) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
 func init() {
 	proto.RegisterType((*Placement)(nil), "placementpb.Placement")
 	proto.RegisterType((*Instance)(nil), "placementpb.Instance")
 	proto.RegisterType((*Shard)(nil), "placementpb.Shard")
 	proto.RegisterType((*PlacementSnapshots)(nil), "placementpb.PlacementSnapshots")
+	proto.RegisterType((*ShardSplit)(nil), "placementpb.ShardSplit")
 	proto.RegisterEnum("placementpb.ShardState", ShardState_name, ShardState_value)
 }
 
 func init() { proto.RegisterFile("placement.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 538 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x6c, 0x53, 0xdd, 0x6e, 0xd3, 0x4c,
-	0x10, 0xfd, 0xec, 0x34, 0x69, 0x76, 0x5c, 0xe7, 0x8b, 0x56, 0xa2, 0x58, 0x45, 0x08, 0x13, 0x54,
-	0x29, 0x2a, 0x22, 0x17, 0x85, 0x0b, 0xd4, 0xbb, 0x80, 0x02, 0x32, 0x0a, 0x11, 0x5a, 0x57, 0xbd,
-	0xe0, 0xc6, 0x72, 0xed, 0x0d, 0x59, 0x35, 0xde, 0xb5, 0x76, 0x37, 0x45, 0xe5, 0x95, 0x78, 0x17,
-	0x9e, 0x86, 0x07, 0x40, 0x1e, 0xdb, 0xf9, 0x11, 0xdc, 0xcd, 0x9c, 0x39, 0x3b, 0x33, 0xe7, 0x78,
-	0x0c, 0xff, 0x97, 0xeb, 0x34, 0xe3, 0x05, 0x97, 0x76, 0x52, 0x6a, 0x65, 0x15, 0xf5, 0xb6, 0x40,
-	0x79, 0x3b, 0xfa, 0xe5, 0x02, 0xf9, 0xd2, 0xe6, 0xf4, 0x3d, 0x10, 0x21, 0x8d, 0x4d, 0x65, 0xc6,
-	0x4d, 0xe0, 0x84, 0x9d, 0xb1, 0x77, 0x79, 0x3e, 0xd9, 0xa3, 0x4f, 0xb6, 0xd4, 0x49, 0xd4, 0xf2,
-	0x66, 0xd2, 0xea, 0x07, 0xb6, 0x7b, 0x47, 0xcf, 0x61, 0xa0, 0x79, 0xb9, 0x16, 0x59, 0x9a, 0x2c,
-	0xd3, 0xcc, 0x2a, 0x1d, 0xb8, 0xa1, 0x33, 0xf6, 0x99, 0xdf, 0xa0, 0x1f, 0x10, 0xa4, 0x4f, 0x01,
-	0xe4, 0xa6, 0x48, 0xcc, 0x2a, 0xd5, 0xb9, 0x09, 0x3a, 0x48, 0x21, 0x72, 0x53, 0xc4, 0x08, 0x54,
-	0x65, 0x61, 0xea, 0x2a, 0xcf, 0x83, 0xa3, 0xd0, 0x19, 0xf7, 0x19, 0x11, 0x26, 0xae, 0x01, 0xfa,
-	0x1c, 0x4e, 0xb2, 0x8d, 0x55, 0xf7, 0x5c, 0x27, 0x56, 0x14, 0x3c, 0xe8, 0x86, 0xce, 0xb8, 0xc3,
-	0xbc, 0x06, 0xbb, 0x16, 0x05, 0xa7, 0xcf, 0xc0, 0x13, 0x26, 0x29, 0x84, 0xd6, 0x4a, 0xf3, 0x3c,
-	0xe8, 0x61, 0x0b, 0x10, 0xe6, 0x73, 0x83, 0x9c, 0xc5, 0x30, 0x38, 0x54, 0x41, 0x87, 0xd0, 0xb9,
-	0xe3, 0x0f, 0x81, 0x13, 0x3a, 0x63, 0xc2, 0xaa, 0x90, 0xbe, 0x84, 0xee, 0x7d, 0xba, 0xde, 0x70,
-	0xd4, 0xe0, 0x5d, 0x3e, 0x3a, 0x70, 0xa3, 0x7d, 0xcd, 0x6a, 0xce, 0x95, 0xfb, 0xd6, 0x19, 0xfd,
-	0x76, 0xa0, 0xdf, 0xe2, 0x74, 0x00, 0xae, 0xc8, 0x9b, 0x76, 0xae, 0xc8, 0x29, 0x85, 0x23, 0x9d,
-	0x66, 0x77, 0xd8, 0x8c, 0x30, 0x8c, 0x2b, 0xec, 0x87, 0x92, 0x1c, 0x1d, 0x20, 0x0c, 0x63, 0x7a,
-	0x0a, 0xbd, 0xef, 0x5c, 0x7c, 0x5b, 0x59, 0x14, 0xee, 0xb3, 0x26, 0xa3, 0x67, 0xd0, 0xe7, 0x32,
-	0x2f, 0x95, 0x90, 0x16, 0x15, 0x13, 0xb6, 0xcd, 0xe9, 0x05, 0xf4, 0x1a, 0x2f, 0x7b, 0xf8, 0xe1,
-	0xe8, 0xc1, 0xaa, 0xe8, 0x1b, 0x6b, 0x18, 0x34, 0x84, 0x13, 0x8c, 0x12, 0xc3, 0x6d, 0x22, 0xf2,
-	0xe0, 0x18, 0xa7, 0x00, 0x62, 0x31, 0xb7, 0x51, 0x5e, 0x4d, 0x5a, 0x29, 0x63, 0x65, 0x5a, 0xf0,
-	0xa0, 0x5f, 0x4f, 0x6a, 0xf3, 0x6a, 0xe3, 0x52, 0x69, 0x1b, 0x10, 0x7c, 0x85, 0xf1, 0xe8, 0xa7,
-	0x03, 0x5d, 0x9c, 0xb1, 0xa7, 0xd9, 0x47, 0xcd, 0xaf, 0xa0, 0x6b, 0x6c, 0x6a, 0x6b, 0x07, 0x07,
-	0x97, 0x8f, 0xff, 0x5e, 0x2b, 0xae, 0xca, 0xac, 0x66, 0xd1, 0x27, 0x40, 0x8c, 0xda, 0xe8, 0x8c,
-	0x57, 0x7b, 0xd5, 0x9e, 0xf4, 0x6b, 0x20, 0xca, 0xe9, 0x0b, 0xf0, 0xdb, 0xaf, 0x2e, 0x53, 0xa9,
-	0x0c, 0xda, 0xd3, 0x61, 0xed, 0x29, 0x2c, 0x2a, 0xac, 0x3d, 0x8d, 0xe5, 0xb2, 0xe1, 0xec, 0x9d,
-	0xc6, 0x72, 0x89, 0x94, 0xd1, 0x27, 0xa0, 0xdb, 0x4b, 0x8e, 0x65, 0x5a, 0x9a, 0x95, 0xb2, 0x86,
-	0xbe, 0x01, 0x62, 0xda, 0xa4, 0xb9, 0xfe, 0xd3, 0x7f, 0x5f, 0x3f, 0xdb, 0x11, 0x2f, 0xae, 0x00,
-	0x76, 0x2a, 0xe8, 0x10, 0x4e, 0xa2, 0x45, 0x74, 0x1d, 0x4d, 0xe7, 0xd1, 0xd7, 0x68, 0xf1, 0x71,
-	0xf8, 0x1f, 0xf5, 0x81, 0x4c, 0x6f, 0xa6, 0xd1, 0x7c, 0xfa, 0x6e, 0x3e, 0x1b, 0x3a, 0xd4, 0x83,
-	0xe3, 0xf9, 0x6c, 0x7a, 0x53, 0xd5, 0xdc, 0xdb, 0x1e, 0xfe, 0x91, 0xaf, 0xff, 0x04, 0x00, 0x00,
-	0xff, 0xff, 0x20, 0x36, 0x61, 0x31, 0xa4, 0x03, 0x00, 0x00,
+	// 667 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x74, 0x54, 0xdd, 0x6e, 0xd3, 0x4c,
+	0x10, 0xfd, 0x9c, 0x34, 0x69, 0x76, 0x5c, 0xa7, 0xd1, 0xf6, 0x6b, 0xb1, 0x82, 0x10, 0x26, 0xa8,
+	0x22, 0x14, 0x35, 0x48, 0x85, 0x0b, 0xd4, 0xbb, 0x00, 0x2d, 0x72, 0x15, 0x2a, 0xb4, 0xa9, 0x8a,
+	0xc4, 0x8d, 0xe5, 0xda, 0x1b, 0xb2, 0xaa, 0xbd, 0xb6, 0xbc, 0x9b, 0xa2, 0xf2, 0x0a, 0x3c, 0x08,
+	0x17, 0xbc, 0x24, 0xf2, 0xd8, 0x4e, 0x1c, 0x08, 0x77, 0x3b, 0x67, 0xce, 0xfc, 0x78, 0xce, 0x8c,
+	0x61, 0x37, 0x8d, 0xfc, 0x80, 0xc7, 0x5c, 0xea, 0x51, 0x9a, 0x25, 0x3a, 0xa1, 0xe6, 0x12, 0x48,
+	0x6f, 0x06, 0x3f, 0xb7, 0x80, 0x7c, 0xaa, 0x6c, 0xfa, 0x0e, 0x88, 0x90, 0x4a, 0xfb, 0x32, 0xe0,
+	0xca, 0x36, 0x9c, 0xe6, 0xd0, 0x3c, 0x39, 0x1c, 0xd5, 0xe8, 0xa3, 0x25, 0x75, 0xe4, 0x56, 0xbc,
+	0x33, 0xa9, 0xb3, 0x7b, 0xb6, 0x8a, 0xa3, 0x87, 0xd0, 0xcd, 0x78, 0x1a, 0x89, 0xc0, 0xf7, 0x66,
+	0x7e, 0xa0, 0x93, 0xcc, 0x6e, 0x38, 0xc6, 0xd0, 0x62, 0x56, 0x89, 0x9e, 0x23, 0x48, 0x1f, 0x01,
+	0xc8, 0x45, 0xec, 0xa9, 0xb9, 0x9f, 0x85, 0xca, 0x6e, 0x22, 0x85, 0xc8, 0x45, 0x3c, 0x45, 0x20,
+	0x77, 0x0b, 0x55, 0x78, 0x79, 0x68, 0x6f, 0x39, 0xc6, 0xb0, 0xc3, 0x88, 0x50, 0xd3, 0x02, 0xa0,
+	0x4f, 0x60, 0x27, 0x58, 0xe8, 0xe4, 0x8e, 0x67, 0x9e, 0x16, 0x31, 0xb7, 0x5b, 0x8e, 0x31, 0x6c,
+	0x32, 0xb3, 0xc4, 0xae, 0x44, 0xcc, 0xe9, 0x63, 0x30, 0x85, 0xf2, 0x62, 0x91, 0x65, 0x49, 0xc6,
+	0x43, 0xbb, 0x8d, 0x29, 0x40, 0xa8, 0x8f, 0x25, 0x42, 0x4f, 0x60, 0x7f, 0xe6, 0x8b, 0x68, 0x91,
+	0x71, 0x2f, 0x4c, 0x62, 0x5f, 0x48, 0x2f, 0xe2, 0x77, 0x3c, 0x52, 0xf6, 0xb6, 0xd3, 0x1c, 0x12,
+	0xb6, 0x57, 0x3a, 0xdf, 0xa3, 0x6f, 0x82, 0x2e, 0x7a, 0x01, 0x3b, 0xd8, 0x93, 0xa7, 0xd2, 0x48,
+	0x68, 0x65, 0x77, 0x70, 0x48, 0xcf, 0xfe, 0x31, 0x24, 0xec, 0x76, 0x8a, 0xcc, 0x62, 0x4c, 0xa6,
+	0x5a, 0x21, 0xfd, 0x29, 0x74, 0xd7, 0xa7, 0x48, 0x7b, 0xd0, 0xbc, 0xe5, 0xf7, 0xb6, 0xe1, 0x18,
+	0x43, 0xc2, 0xf2, 0x27, 0x7d, 0x01, 0xad, 0x3b, 0x3f, 0x5a, 0x70, 0x9c, 0xa1, 0x79, 0xb2, 0xbf,
+	0x56, 0xa8, 0x8a, 0x66, 0x05, 0xe7, 0xb4, 0xf1, 0xc6, 0xe8, 0x7f, 0x86, 0xde, 0x9f, 0x55, 0xeb,
+	0x69, 0xad, 0x22, 0xed, 0xf1, 0x7a, 0xda, 0x07, 0x6b, 0x69, 0x57, 0xf1, 0xb5, 0xc4, 0x83, 0x1f,
+	0x4d, 0xe8, 0x54, 0x05, 0x69, 0x17, 0x1a, 0x22, 0x2c, 0xfb, 0x6c, 0x88, 0x90, 0x52, 0xd8, 0xca,
+	0xfc, 0xe0, 0x16, 0xd3, 0x11, 0x86, 0xef, 0x1c, 0xfb, 0x9e, 0x48, 0x8e, 0xd2, 0x12, 0x86, 0x6f,
+	0x7a, 0x00, 0xed, 0x6f, 0x5c, 0x7c, 0x9d, 0x6b, 0x54, 0xd4, 0x62, 0xa5, 0x45, 0xfb, 0xd0, 0xe1,
+	0x32, 0x4c, 0x13, 0x21, 0x35, 0x4a, 0x49, 0xd8, 0xd2, 0xa6, 0x47, 0xd0, 0x2e, 0x97, 0xa4, 0x8d,
+	0xc3, 0xa6, 0x7f, 0x37, 0xcb, 0x4a, 0x06, 0x75, 0x96, 0xf2, 0x70, 0xed, 0x89, 0xd0, 0xde, 0xc6,
+	0x2a, 0x50, 0x4c, 0x9d, 0x6b, 0x37, 0xcc, 0x2b, 0xcd, 0x13, 0xa5, 0xa5, 0x1f, 0x73, 0xbb, 0x53,
+	0x54, 0xaa, 0xec, 0xbc, 0xe3, 0x34, 0xc9, 0xb4, 0x4d, 0x30, 0x0a, 0xdf, 0x94, 0xc1, 0xee, 0xfa,
+	0x92, 0x28, 0x1b, 0xb0, 0x8d, 0xe7, 0x1b, 0xa5, 0x18, 0x9d, 0xd7, 0x97, 0xa6, 0x54, 0xbd, 0xbb,
+	0xb6, 0x49, 0xaa, 0x3f, 0x86, 0xbd, 0x0d, 0xb4, 0x0d, 0xea, 0xff, 0x5f, 0x97, 0x89, 0xd4, 0xd5,
+	0xf8, 0x65, 0x40, 0x0b, 0x3f, 0xbd, 0x26, 0x85, 0x85, 0x52, 0x1c, 0x43, 0x4b, 0x69, 0x5f, 0x17,
+	0x31, 0xdd, 0x8d, 0xd2, 0xe6, 0x6e, 0x56, 0xb0, 0xe8, 0x43, 0x20, 0x2a, 0x59, 0x64, 0x01, 0xcf,
+	0xc7, 0x55, 0x48, 0xd5, 0x29, 0x00, 0x37, 0xa4, 0x4f, 0xc1, 0xaa, 0xae, 0x4c, 0xfa, 0x32, 0x51,
+	0xa8, 0x5a, 0x93, 0x55, 0xa7, 0x77, 0x99, 0x63, 0xd5, 0x29, 0xce, 0x66, 0x25, 0xa7, 0x76, 0x8a,
+	0xb3, 0x19, 0x52, 0x06, 0x17, 0x40, 0x97, 0x47, 0x31, 0x95, 0x7e, 0xaa, 0xe6, 0x89, 0x56, 0xf4,
+	0x35, 0x10, 0x55, 0x19, 0xe5, 0xdf, 0xe6, 0x60, 0xf3, 0x21, 0xb1, 0x15, 0x71, 0xf0, 0x12, 0x60,
+	0xb5, 0xa0, 0x58, 0x7c, 0x2e, 0xa2, 0xb0, 0xfa, 0x8f, 0xe4, 0x69, 0x2c, 0x66, 0x22, 0x86, 0x34,
+	0x75, 0x74, 0x5a, 0x05, 0xe0, 0xf7, 0xf6, 0x60, 0xc7, 0xbd, 0x74, 0xaf, 0xdc, 0xf1, 0xc4, 0xfd,
+	0xe2, 0x5e, 0x7e, 0xe8, 0xfd, 0x47, 0x2d, 0x20, 0xe3, 0xeb, 0xb1, 0x3b, 0x19, 0xbf, 0x9d, 0x9c,
+	0xf5, 0x0c, 0x6a, 0xc2, 0xf6, 0xe4, 0x6c, 0x7c, 0x9d, 0xfb, 0x1a, 0x37, 0x6d, 0xfc, 0x65, 0xbe,
+	0xfa, 0x3d, 0x00, 0x1f, 0x82, 0xd1, 0x58, 0x45, 0x05, 0x00, 0x00,
 }
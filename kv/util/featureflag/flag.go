@@ -0,0 +1,237 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package featureflag
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/m3db/m3cluster/generated/proto/featureflagpb"
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/kv/util/runtime"
+)
+
+// Well-known entity attributes that targeting rules may match on.
+const (
+	ZoneAttribute        = "zone"
+	EnvironmentAttribute = "environment"
+	InstanceIDAttribute  = "instance_id"
+)
+
+const (
+	// numBuckets is the number of buckets entities are hashed into, which
+	// gives percentage rollouts a granularity of 0.01%.
+	numBuckets = 10000
+
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+var (
+	errNilValue = errors.New("nil feature flag value")
+)
+
+// Entity is the subject a feature flag is evaluated against.
+type Entity struct {
+	// ID identifies the entity and determines its bucket in percentage rollouts.
+	ID string
+
+	// Attributes are matched against the targeting rules of the flag.
+	Attributes map[string]string
+}
+
+// Flag is a feature flag whose rollout is stored in kv and updated live
+// through a watch.
+type Flag interface {
+	runtime.Value
+
+	// Enabled returns whether the feature is enabled for the entity.
+	Enabled(entity Entity) bool
+}
+
+type flag struct {
+	runtime.Value
+
+	key          string
+	defaultValue bool
+	evaluator    atomic.Value
+}
+
+// NewFlag creates a new feature flag for the given key.
+func NewFlag(key string, opts Options) Flag {
+	f := &flag{
+		key:          key,
+		defaultValue: opts.DefaultValue(),
+	}
+	valueOpts := runtime.NewOptions().
+		SetInstrumentOptions(opts.InstrumentOptions()).
+		SetInitWatchTimeout(opts.InitWatchTimeout()).
+		SetKVStore(opts.KVStore()).
+		SetUnmarshalFn(f.toEvaluator).
+		SetProcessFn(f.process)
+	f.Value = runtime.NewValue(key, valueOpts)
+	return f
+}
+
+func (f *flag) Enabled(entity Entity) bool {
+	e, ok := f.evaluator.Load().(*evaluator)
+	if !ok {
+		return f.defaultValue
+	}
+	return e.enabled(entity)
+}
+
+func (f *flag) toEvaluator(value kv.Value) (interface{}, error) {
+	if value == nil {
+		return nil, errNilValue
+	}
+	var pb featureflagpb.FeatureFlag
+	if err := value.Unmarshal(&pb); err != nil {
+		return nil, err
+	}
+	salt := pb.Salt
+	if salt == "" {
+		salt = f.key
+	}
+	return newEvaluator(&pb, salt)
+}
+
+func (f *flag) process(value interface{}) error {
+	f.evaluator.Store(value.(*evaluator))
+	return nil
+}
+
+// evaluator is an immutable, compiled form of a feature flag so evaluation
+// does not need to take any locks.
+type evaluator struct {
+	on        bool
+	salt      string
+	threshold uint64
+	rules     []rule
+}
+
+func newEvaluator(pb *featureflagpb.FeatureFlag, salt string) (*evaluator, error) {
+	threshold, err := toThreshold(pb.Percentage)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]rule, 0, len(pb.Rules))
+	for i, r := range pb.Rules {
+		compiled, err := newRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %d: %v", i, err)
+		}
+		rules = append(rules, compiled)
+	}
+	return &evaluator{
+		on:        pb.Enabled,
+		salt:      salt,
+		threshold: threshold,
+		rules:     rules,
+	}, nil
+}
+
+func (e *evaluator) enabled(entity Entity) bool {
+	if !e.on {
+		return false
+	}
+	threshold := e.threshold
+	for _, r := range e.rules {
+		if r.matches(entity.Attributes) {
+			threshold = r.threshold
+			break
+		}
+	}
+	if threshold == 0 {
+		return false
+	}
+	if threshold >= numBuckets {
+		return true
+	}
+	return bucket(e.salt, entity.ID) < threshold
+}
+
+type rule struct {
+	conditions []match
+	threshold  uint64
+}
+
+func newRule(pb *featureflagpb.Rule) (rule, error) {
+	threshold, err := toThreshold(pb.Percentage)
+	if err != nil {
+		return rule{}, err
+	}
+	matches := make([]match, 0, len(pb.Matches))
+	for _, m := range pb.Matches {
+		if m.Attribute == "" {
+			return rule{}, errors.New("empty match attribute")
+		}
+		values := make(map[string]struct{}, len(m.Values))
+		for _, v := range m.Values {
+			values[v] = struct{}{}
+		}
+		matches = append(matches, match{attribute: m.Attribute, values: values})
+	}
+	return rule{conditions: matches, threshold: threshold}, nil
+}
+
+func (r rule) matches(attributes map[string]string) bool {
+	for _, m := range r.conditions {
+		v, ok := attributes[m.attribute]
+		if !ok {
+			return false
+		}
+		if _, ok := m.values[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+type match struct {
+	attribute string
+	values    map[string]struct{}
+}
+
+func toThreshold(percentage float64) (uint64, error) {
+	if percentage < 0 || percentage > 100 {
+		return 0, fmt.Errorf("percentage %v is not in range [0, 100]", percentage)
+	}
+	return uint64(percentage*numBuckets/100 + 0.5), nil
+}
+
+// bucket hashes the entity id with fnv-1a into one of numBuckets buckets.
+// The hash is computed inline to avoid allocating on the hot path.
+func bucket(salt, id string) uint64 {
+	h := uint64(fnvOffset64)
+	for i := 0; i < len(salt); i++ {
+		h ^= uint64(salt[i])
+		h *= fnvPrime64
+	}
+	h ^= ':'
+	h *= fnvPrime64
+	for i := 0; i < len(id); i++ {
+		h ^= uint64(id[i])
+		h *= fnvPrime64
+	}
+	return h % numBuckets
+}
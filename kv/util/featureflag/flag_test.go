@@ -0,0 +1,251 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package featureflag
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3cluster/generated/proto/featureflagpb"
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/kv/mem"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testFlagKey     = "testFlag"
+	testWaitTimeout = 5 * time.Second
+)
+
+func TestFlagDefaultValueBeforeWatch(t *testing.T) {
+	store := mem.NewStore()
+	f := NewFlag(testFlagKey, testFlagOptions(store).SetDefaultValue(true))
+	require.True(t, f.Enabled(Entity{ID: "foo"}))
+}
+
+func TestFlagDisabled(t *testing.T) {
+	store := mem.NewStore()
+	_, err := store.Set(testFlagKey, &featureflagpb.FeatureFlag{
+		Enabled:    false,
+		Percentage: 100,
+	})
+	require.NoError(t, err)
+
+	f := NewFlag(testFlagKey, testFlagOptions(store).SetDefaultValue(true))
+	require.NoError(t, f.Watch())
+	defer f.Unwatch()
+
+	require.False(t, f.Enabled(Entity{ID: "foo"}))
+}
+
+func TestFlagPercentageRollout(t *testing.T) {
+	store := mem.NewStore()
+	_, err := store.Set(testFlagKey, &featureflagpb.FeatureFlag{
+		Enabled:    true,
+		Percentage: 30,
+	})
+	require.NoError(t, err)
+
+	f := NewFlag(testFlagKey, testFlagOptions(store))
+	require.NoError(t, f.Watch())
+	defer f.Unwatch()
+
+	var (
+		numEntities = 10000
+		numEnabled  int
+	)
+	for i := 0; i < numEntities; i++ {
+		entity := Entity{ID: fmt.Sprintf("entity%d", i)}
+		enabled := f.Enabled(entity)
+		if enabled {
+			numEnabled++
+		}
+		// Evaluation is stable for the same entity.
+		require.Equal(t, enabled, f.Enabled(entity))
+	}
+	require.InDelta(t, 0.3, float64(numEnabled)/float64(numEntities), 0.02)
+}
+
+func TestFlagTargetingRules(t *testing.T) {
+	store := mem.NewStore()
+	_, err := store.Set(testFlagKey, &featureflagpb.FeatureFlag{
+		Enabled:    true,
+		Percentage: 0,
+		Rules: []*featureflagpb.Rule{
+			{
+				Matches: []*featureflagpb.Match{
+					{Attribute: InstanceIDAttribute, Values: []string{"i1"}},
+				},
+				Percentage: 0,
+			},
+			{
+				Matches: []*featureflagpb.Match{
+					{Attribute: ZoneAttribute, Values: []string{"z1", "z2"}},
+					{Attribute: EnvironmentAttribute, Values: []string{"staging"}},
+				},
+				Percentage: 100,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	f := NewFlag(testFlagKey, testFlagOptions(store))
+	require.NoError(t, f.Watch())
+	defer f.Unwatch()
+
+	inputs := []struct {
+		attributes map[string]string
+		expected   bool
+	}{
+		{
+			attributes: map[string]string{ZoneAttribute: "z1", EnvironmentAttribute: "staging"},
+			expected:   true,
+		},
+		{
+			attributes: map[string]string{ZoneAttribute: "z2", EnvironmentAttribute: "staging"},
+			expected:   true,
+		},
+		{
+			attributes: map[string]string{ZoneAttribute: "z3", EnvironmentAttribute: "staging"},
+			expected:   false,
+		},
+		{
+			attributes: map[string]string{ZoneAttribute: "z1"},
+			expected:   false,
+		},
+		{
+			attributes: map[string]string{
+				ZoneAttribute:        "z1",
+				EnvironmentAttribute: "staging",
+				InstanceIDAttribute:  "i1",
+			},
+			expected: false,
+		},
+	}
+	for _, input := range inputs {
+		entity := Entity{ID: "foo", Attributes: input.attributes}
+		require.Equal(t, input.expected, f.Enabled(entity))
+	}
+}
+
+func TestFlagLiveUpdate(t *testing.T) {
+	store := mem.NewStore()
+	_, err := store.Set(testFlagKey, &featureflagpb.FeatureFlag{Enabled: true})
+	require.NoError(t, err)
+
+	f := NewFlag(testFlagKey, testFlagOptions(store))
+	require.NoError(t, f.Watch())
+	defer f.Unwatch()
+
+	entity := Entity{ID: "foo"}
+	require.False(t, f.Enabled(entity))
+
+	_, err = store.Set(testFlagKey, &featureflagpb.FeatureFlag{Enabled: true, Percentage: 100})
+	require.NoError(t, err)
+	waitUntil(t, func() bool { return f.Enabled(entity) })
+}
+
+func TestFlagInvalidUpdateNotApplied(t *testing.T) {
+	store := mem.NewStore()
+	_, err := store.Set(testFlagKey, &featureflagpb.FeatureFlag{Enabled: true, Percentage: 100})
+	require.NoError(t, err)
+
+	f := NewFlag(testFlagKey, testFlagOptions(store))
+	require.NoError(t, f.Watch())
+	defer f.Unwatch()
+
+	entity := Entity{ID: "foo"}
+	require.True(t, f.Enabled(entity))
+
+	// Rewatching waits for the latest value, so the invalid updates are
+	// deterministically received through the watch.
+	for _, invalid := range testInvalidFlags() {
+		f.Unwatch()
+		_, err := store.Set(testFlagKey, invalid)
+		require.NoError(t, err)
+		require.Error(t, f.Watch())
+		require.True(t, f.Enabled(entity))
+	}
+}
+
+func TestFlagInvalidUpdateRejectedWhileWatching(t *testing.T) {
+	store := mem.NewStore()
+	_, err := store.Set(testFlagKey, &featureflagpb.FeatureFlag{Enabled: true, Percentage: 100})
+	require.NoError(t, err)
+
+	f := NewFlag(testFlagKey, testFlagOptions(store))
+	require.NoError(t, f.Watch())
+	defer f.Unwatch()
+
+	entity := Entity{ID: "foo"}
+	require.True(t, f.Enabled(entity))
+
+	for _, invalid := range testInvalidFlags() {
+		version, err := store.Set(testFlagKey, invalid)
+		require.NoError(t, err)
+		waitUntil(t, func() bool { return f.UpdateStatus().RejectedVersion == version })
+		require.Equal(t, 1, f.UpdateStatus().LastGoodVersion)
+		require.True(t, f.Enabled(entity))
+	}
+}
+
+func TestBucketSalted(t *testing.T) {
+	var numDifferent int
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("entity%d", i)
+		require.True(t, bucket("foo", id) < numBuckets)
+		require.Equal(t, bucket("foo", id), bucket("foo", id))
+		if bucket("foo", id) != bucket("bar", id) {
+			numDifferent++
+		}
+	}
+	require.True(t, numDifferent > 90)
+}
+
+func testInvalidFlags() []*featureflagpb.FeatureFlag {
+	return []*featureflagpb.FeatureFlag{
+		{Enabled: false, Percentage: 101},
+		{
+			Enabled: false,
+			Rules: []*featureflagpb.Rule{
+				{Matches: []*featureflagpb.Match{{}}},
+			},
+		},
+	}
+}
+
+func testFlagOptions(store kv.Store) Options {
+	return NewOptions().
+		SetInitWatchTimeout(100 * time.Millisecond).
+		SetKVStore(store)
+}
+
+func waitUntil(t *testing.T, fn func() bool) {
+	deadline := time.Now().Add(testWaitTimeout)
+	for !fn() {
+		if time.Now().After(deadline) {
+			require.Fail(t, "timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package runtime
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3x/log"
+)
+
+// CompositeValue is a value derived from several keys that can be updated
+// during runtime. The process function is called with a Snapshot of all the
+// keys whenever any of them changes.
+type CompositeValue interface {
+	// Keys are the keys associated with the value.
+	Keys() []string
+
+	// Watch starts watching for value updates.
+	Watch() error
+
+	// Unwatch stops watching for value updates.
+	Unwatch()
+}
+
+// Snapshot is a consistent view of the values of several keys. If no unmarshal
+// function is configured, the values are the kv values of the keys.
+type Snapshot struct {
+	values   map[string]interface{}
+	versions map[string]int
+}
+
+// Value returns the value of the key.
+func (s Snapshot) Value(key string) interface{} { return s.values[key] }
+
+// Version returns the version of the key.
+func (s Snapshot) Version(key string) int { return s.versions[key] }
+
+type compositeValue struct {
+	sync.RWMutex
+
+	keys             []string
+	store            kv.Store
+	opts             Options
+	log              log.Logger
+	unmarshalFn      UnmarshalFn
+	processFn        ProcessFn
+	debounceInterval time.Duration
+	afterFn          afterFn
+
+	status   valueStatus
+	watch    *compositeWatch
+	versions map[string]int
+}
+
+// afterFn returns a channel receiving the time after the duration elapses.
+type afterFn func(d time.Duration) <-chan time.Time
+
+// compositeWatch holds the watches on the keys of a composite value for
+// as long as the value is being watched.
+type compositeWatch struct {
+	watches  []kv.ValueWatch
+	notifyCh chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewCompositeValue creates a new composite value.
+func NewCompositeValue(
+	keys []string,
+	opts Options,
+) CompositeValue {
+	return &compositeValue{
+		keys:             keys,
+		opts:             opts,
+		store:            opts.KVStore(),
+		log:              opts.InstrumentOptions().Logger(),
+		unmarshalFn:      opts.UnmarshalFn(),
+		processFn:        opts.ProcessFn(),
+		debounceInterval: opts.DebounceInterval(),
+		afterFn:          time.After,
+	}
+}
+
+func (v *compositeValue) Keys() []string { return v.keys }
+
+func (v *compositeValue) Watch() error {
+	v.Lock()
+	defer v.Unlock()
+
+	if v.status == valueWatching {
+		return nil
+	}
+	watches := make([]kv.ValueWatch, 0, len(v.keys))
+	for _, key := range v.keys {
+		watch, err := v.store.Watch(key)
+		if err != nil {
+			for _, w := range watches {
+				w.Close()
+			}
+			return CreateWatchError{innerError: err}
+		}
+		watches = append(watches, watch)
+	}
+	cw := &compositeWatch{
+		watches:  watches,
+		notifyCh: make(chan struct{}, 1),
+		doneCh:   make(chan struct{}),
+	}
+	v.status = valueWatching
+	v.watch = cw
+
+	err := v.waitForAllKeys(watches)
+	if err == nil {
+		err = v.updateWithLock(watches)
+	}
+
+	// NB: similar to Value, we keep watching even if the initialization
+	// failed so the value is updated once all keys become available.
+	for _, watch := range watches {
+		go cw.forwardUpdates(watch)
+	}
+	go v.processUpdates(cw)
+	if err != nil {
+		return InitValueError{innerError: err}
+	}
+	return nil
+}
+
+func (v *compositeValue) Unwatch() {
+	v.Lock()
+	defer v.Unlock()
+
+	if v.status == valueNotWatching {
+		return
+	}
+	close(v.watch.doneCh)
+	for _, watch := range v.watch.watches {
+		watch.Close()
+	}
+	v.status = valueNotWatching
+	v.watch = nil
+}
+
+// waitForAllKeys waits until all the keys have a value or the initial watch
+// timeout expires.
+func (v *compositeValue) waitForAllKeys(watches []kv.ValueWatch) error {
+	timeout := time.After(v.opts.InitWatchTimeout())
+	for _, watch := range watches {
+		for watch.Get() == nil {
+			select {
+			case <-watch.C():
+			case <-timeout:
+				return errInitWatchTimeout
+			}
+		}
+	}
+	return nil
+}
+
+func (w *compositeWatch) forwardUpdates(watch kv.ValueWatch) {
+	for range watch.C() {
+		select {
+		case w.notifyCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (v *compositeValue) processUpdates(cw *compositeWatch) {
+	for {
+		select {
+		case <-cw.doneCh:
+			return
+		case <-cw.notifyCh:
+		}
+
+		if v.debounceInterval > 0 {
+			select {
+			case <-cw.doneCh:
+				return
+			case <-v.afterFn(v.debounceInterval):
+			}
+			// Updates received during the debounce interval are covered by
+			// the snapshot taken below.
+			select {
+			case <-cw.notifyCh:
+			default:
+			}
+		}
+
+		v.Lock()
+		if v.status != valueWatching || v.watch != cw {
+			v.Unlock()
+			return
+		}
+		if err := v.updateWithLock(cw.watches); err != nil {
+			v.log.Errorf("error updating composite value: %v", err)
+		}
+		v.Unlock()
+	}
+}
+
+func (v *compositeValue) updateWithLock(watches []kv.ValueWatch) error {
+	var (
+		updates = make([]kv.Value, len(v.keys))
+		changed bool
+	)
+	for i, key := range v.keys {
+		update := watches[i].Get()
+		if update == nil {
+			return fmt.Errorf("%v for key %s", errNilValue, key)
+		}
+		if update.Version() != v.versions[key] {
+			changed = true
+		}
+		updates[i] = update
+	}
+	if !changed {
+		return nil
+	}
+
+	snapshot := Snapshot{
+		values:   make(map[string]interface{}, len(v.keys)),
+		versions: make(map[string]int, len(v.keys)),
+	}
+	for i, key := range v.keys {
+		var value interface{} = updates[i]
+		if v.unmarshalFn != nil {
+			unmarshalled, err := v.unmarshalFn(updates[i])
+			if err != nil {
+				return fmt.Errorf("error unmarshalling value for key %s version %d: %v",
+					key, updates[i].Version(), err)
+			}
+			value = unmarshalled
+		}
+		snapshot.values[key] = value
+		snapshot.versions[key] = updates[i].Version()
+	}
+	if err := v.processFn(snapshot); err != nil {
+		return err
+	}
+	v.versions = snapshot.versions
+	return nil
+}
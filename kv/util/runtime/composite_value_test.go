@@ -0,0 +1,203 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package runtime
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3cluster/generated/proto/commonpb"
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/kv/mem"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testLimitKey  = "testLimit"
+	testToggleKey = "testToggle"
+)
+
+func TestCompositeValueWatchCreateWatchError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := kv.NewMockStore(ctrl)
+	errWatch := errors.New("error creating watch")
+	watch := kv.NewMockValueWatch(ctrl)
+	watch.EXPECT().Close()
+	store.EXPECT().Watch(testLimitKey).Return(watch, nil)
+	store.EXPECT().Watch(testToggleKey).Return(nil, errWatch)
+
+	cv := NewCompositeValue(
+		[]string{testLimitKey, testToggleKey},
+		testValueOptions(store),
+	).(*compositeValue)
+	require.Equal(t, CreateWatchError{innerError: errWatch}, cv.Watch())
+	require.Equal(t, valueNotWatching, cv.status)
+}
+
+func TestCompositeValueWatchTimeoutMissingKey(t *testing.T) {
+	store := mem.NewStore()
+	_, err := store.Set(testLimitKey, &commonpb.Int64Proto{Value: 10})
+	require.NoError(t, err)
+
+	var snapshots []Snapshot
+	cv := testCompositeValue(store, &sync.Mutex{}, &snapshots)
+	require.Equal(t, InitValueError{innerError: errInitWatchTimeout}, cv.Watch())
+	defer cv.Unwatch()
+
+	// The value is processed once the missing key becomes available.
+	_, err = store.Set(testToggleKey, &commonpb.BoolProto{Value: true})
+	require.NoError(t, err)
+	waitUntil(t, func() bool {
+		cv.RLock()
+		defer cv.RUnlock()
+		return len(cv.versions) == 2
+	})
+}
+
+func TestCompositeValueProcessesConsistentSnapshot(t *testing.T) {
+	store := mem.NewStore()
+	_, err := store.Set(testLimitKey, &commonpb.Int64Proto{Value: 10})
+	require.NoError(t, err)
+	_, err = store.Set(testToggleKey, &commonpb.BoolProto{Value: false})
+	require.NoError(t, err)
+
+	var (
+		lock      sync.Mutex
+		snapshots []Snapshot
+	)
+	cv := testCompositeValue(store, &lock, &snapshots)
+	var (
+		debounceStarted = make(chan struct{}, 1)
+		debounceEnded   = make(chan time.Time, 1)
+	)
+	cv.afterFn = func(time.Duration) <-chan time.Time {
+		select {
+		case debounceStarted <- struct{}{}:
+		default:
+		}
+		return debounceEnded
+	}
+	require.NoError(t, cv.Watch())
+	defer cv.Unwatch()
+
+	lock.Lock()
+	require.Equal(t, 1, len(snapshots))
+	require.Equal(t, int64(10), testLimit(t, snapshots[0]))
+	require.False(t, testToggle(t, snapshots[0]))
+	lock.Unlock()
+
+	// Updates to both keys within the debounce interval are processed together.
+	_, err = store.Set(testLimitKey, &commonpb.Int64Proto{Value: 20})
+	require.NoError(t, err)
+	select {
+	case <-debounceStarted:
+	case <-time.After(testWaitTimeout):
+		require.Fail(t, "timed out waiting for the debounce interval to start")
+	}
+	_, err = store.Set(testToggleKey, &commonpb.BoolProto{Value: true})
+	require.NoError(t, err)
+	debounceEnded <- time.Now()
+
+	waitUntil(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(snapshots) == 2
+	})
+	lock.Lock()
+	defer lock.Unlock()
+	latest := snapshots[1]
+	require.Equal(t, int64(20), testLimit(t, latest))
+	require.True(t, testToggle(t, latest))
+	require.Equal(t, 2, latest.Version(testLimitKey))
+	require.Equal(t, 2, latest.Version(testToggleKey))
+}
+
+func TestCompositeValueUpdateUnchanged(t *testing.T) {
+	store := mem.NewStore()
+	_, err := store.Set(testLimitKey, &commonpb.Int64Proto{Value: 10})
+	require.NoError(t, err)
+	_, err = store.Set(testToggleKey, &commonpb.BoolProto{Value: false})
+	require.NoError(t, err)
+
+	var snapshots []Snapshot
+	cv := testCompositeValue(store, &sync.Mutex{}, &snapshots)
+	limitWatch, err := store.Watch(testLimitKey)
+	require.NoError(t, err)
+	toggleWatch, err := store.Watch(testToggleKey)
+	require.NoError(t, err)
+
+	watches := []kv.ValueWatch{limitWatch, toggleWatch}
+	require.NoError(t, cv.updateWithLock(watches))
+	require.NoError(t, cv.updateWithLock(watches))
+	require.Equal(t, 1, len(snapshots))
+}
+
+func TestCompositeValueProcessError(t *testing.T) {
+	store := mem.NewStore()
+	_, err := store.Set(testLimitKey, &commonpb.Int64Proto{Value: 10})
+	require.NoError(t, err)
+	_, err = store.Set(testToggleKey, &commonpb.BoolProto{Value: false})
+	require.NoError(t, err)
+
+	errProcess := errors.New("error processing")
+	opts := testValueOptions(store).
+		SetProcessFn(func(interface{}) error { return errProcess })
+	cv := NewCompositeValue([]string{testLimitKey, testToggleKey}, opts).(*compositeValue)
+	require.Equal(t, InitValueError{innerError: errProcess}, cv.Watch())
+	require.Nil(t, cv.versions)
+
+	cv.Unwatch()
+	require.Equal(t, valueNotWatching, cv.status)
+}
+
+func testCompositeValue(
+	store kv.Store,
+	lock sync.Locker,
+	snapshots *[]Snapshot,
+) *compositeValue {
+	opts := testValueOptions(store).
+		SetDebounceInterval(50 * time.Millisecond).
+		SetProcessFn(func(v interface{}) error {
+			lock.Lock()
+			*snapshots = append(*snapshots, v.(Snapshot))
+			lock.Unlock()
+			return nil
+		})
+	return NewCompositeValue([]string{testLimitKey, testToggleKey}, opts).(*compositeValue)
+}
+
+func testLimit(t *testing.T, s Snapshot) int64 {
+	var limit commonpb.Int64Proto
+	require.NoError(t, s.Value(testLimitKey).(kv.Value).Unmarshal(&limit))
+	return limit.Value
+}
+
+func testToggle(t *testing.T, s Snapshot) bool {
+	var toggle commonpb.BoolProto
+	require.NoError(t, s.Value(testToggleKey).(kv.Value).Unmarshal(&toggle))
+	return toggle.Value
+}
@@ -29,6 +29,7 @@ import (
 
 const (
 	defaultInitWatchTimeout = 10 * time.Second
+	defaultDebounceInterval = 100 * time.Millisecond
 )
 
 // Options provide a set of value options.
@@ -62,6 +63,31 @@ type Options interface {
 
 	// ProcessFn returns the process function.
 	ProcessFn() ProcessFn
+
+	// SetRejectFn sets the function called when an update is rejected. The
+	// function is called while the value lock is held and must not call back
+	// into the value.
+	SetRejectFn(value RejectFn) Options
+
+	// RejectFn returns the function called when an update is rejected.
+	RejectFn() RejectFn
+
+	// SetNewMessageFn sets the function creating the proto message stored
+	// under the key, which is required for rollbacks.
+	SetNewMessageFn(value NewMessageFn) Options
+
+	// NewMessageFn returns the function creating the proto message stored
+	// under the key.
+	NewMessageFn() NewMessageFn
+
+	// SetDebounceInterval sets the interval a composite value waits after an
+	// update before processing, so updates to several keys made close together
+	// are processed as a single snapshot.
+	SetDebounceInterval(value time.Duration) Options
+
+	// DebounceInterval returns the interval a composite value waits after an
+	// update before processing.
+	DebounceInterval() time.Duration
 }
 
 type options struct {
@@ -70,6 +96,9 @@ type options struct {
 	kvStore          kv.Store
 	unmarshalFn      UnmarshalFn
 	processFn        ProcessFn
+	rejectFn         RejectFn
+	newMessageFn     NewMessageFn
+	debounceInterval time.Duration
 }
 
 // NewOptions creates a new set of options.
@@ -77,6 +106,7 @@ func NewOptions() Options {
 	return &options{
 		instrumentOpts:   instrument.NewOptions(),
 		initWatchTimeout: defaultInitWatchTimeout,
+		debounceInterval: defaultDebounceInterval,
 	}
 }
 
@@ -129,3 +159,33 @@ func (o *options) SetProcessFn(value ProcessFn) Options {
 func (o *options) ProcessFn() ProcessFn {
 	return o.processFn
 }
+
+func (o *options) SetRejectFn(value RejectFn) Options {
+	opts := *o
+	opts.rejectFn = value
+	return &opts
+}
+
+func (o *options) RejectFn() RejectFn {
+	return o.rejectFn
+}
+
+func (o *options) SetNewMessageFn(value NewMessageFn) Options {
+	opts := *o
+	opts.newMessageFn = value
+	return &opts
+}
+
+func (o *options) NewMessageFn() NewMessageFn {
+	return o.newMessageFn
+}
+
+func (o *options) SetDebounceInterval(value time.Duration) Options {
+	opts := *o
+	opts.debounceInterval = value
+	return &opts
+}
+
+func (o *options) DebounceInterval() time.Duration {
+	return o.debounceInterval
+}
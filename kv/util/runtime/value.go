@@ -28,11 +28,17 @@ import (
 
 	"github.com/m3db/m3cluster/kv"
 	"github.com/m3db/m3x/log"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/uber-go/tally"
 )
 
 var (
-	errInitWatchTimeout = errors.New("init watch timeout")
-	errNilValue         = errors.New("nil kv value")
+	errInitWatchTimeout  = errors.New("init watch timeout")
+	errNilValue          = errors.New("nil kv value")
+	errNilNewMessageFn   = errors.New("nil new message function")
+	errNoLastGoodValue   = errors.New("no last known good value")
+	errNoRejectedVersion = errors.New("no rejected version")
 )
 
 // Value is a value that can be updated during runtime.
@@ -45,6 +51,32 @@ type Value interface {
 
 	// Unwatch stops watching for value updates.
 	Unwatch()
+
+	// UpdateStatus returns the last known good version, and the rejected
+	// version along with the rejection error if the latest update was rejected.
+	UpdateStatus() UpdateStatus
+
+	// Rollback rewrites the key back to the last known good version if the
+	// latest update was rejected.
+	Rollback() error
+}
+
+// UpdateStatus describes the outcome of the updates received by a value.
+type UpdateStatus struct {
+	// LastGoodVersion is the version of the last update that was applied.
+	LastGoodVersion int
+
+	// RejectedVersion is the version of the latest update if it was rejected,
+	// or kv.UninitializedVersion otherwise.
+	RejectedVersion int
+
+	// RejectionError is the error the rejected update failed with.
+	RejectionError error
+}
+
+// Rejected returns true if the latest update was rejected.
+func (s UpdateStatus) Rejected() bool {
+	return s.RejectedVersion != kv.UninitializedVersion
 }
 
 // UnmarshalFn unmarshals a kv value and extracts its payload.
@@ -53,6 +85,13 @@ type UnmarshalFn func(value kv.Value) (interface{}, error)
 // ProcessFn processes a value.
 type ProcessFn func(value interface{}) error
 
+// RejectFn is called when an update is rejected with the version of the
+// update and the rejection error.
+type RejectFn func(version int, err error)
+
+// NewMessageFn creates a new proto message the value can be unmarshalled into.
+type NewMessageFn func() proto.Message
+
 // updateWithLockFn updates a value while holding a lock.
 type updateWithLockFn func(value kv.Value) error
 
@@ -72,11 +111,37 @@ type value struct {
 	log              log.Logger
 	unmarshalFn      UnmarshalFn
 	processFn        ProcessFn
+	rejectFn         RejectFn
+	newMessageFn     NewMessageFn
 	updateWithLockFn updateWithLockFn
+	m                valueMetrics
+
+	status          valueStatus
+	watch           kv.ValueWatch
+	version         int
+	lastGood        kv.Value
+	rejectedVersion int
+	rejectionErr    error
+}
 
-	status  valueStatus
-	watch   kv.ValueWatch
-	version int
+type valueMetrics struct {
+	updateSuccess   tally.Counter
+	updateRejected  tally.Counter
+	rollbackSuccess tally.Counter
+	rollbackErrors  tally.Counter
+	lastGoodVersion tally.Gauge
+	rejectedVersion tally.Gauge
+}
+
+func newValueMetrics(scope tally.Scope) valueMetrics {
+	return valueMetrics{
+		updateSuccess:   scope.Counter("update-success"),
+		updateRejected:  scope.Counter("update-rejected"),
+		rollbackSuccess: scope.Counter("rollback-success"),
+		rollbackErrors:  scope.Counter("rollback-errors"),
+		lastGoodVersion: scope.Gauge("last-good-version"),
+		rejectedVersion: scope.Gauge("rejected-version"),
+	}
 }
 
 // NewValue creates a new value.
@@ -84,14 +149,18 @@ func NewValue(
 	key string,
 	opts Options,
 ) Value {
+	scope := opts.InstrumentOptions().MetricsScope().Tagged(map[string]string{"key": key})
 	v := &value{
-		key:         key,
-		opts:        opts,
-		store:       opts.KVStore(),
-		log:         opts.InstrumentOptions().Logger(),
-		unmarshalFn: opts.UnmarshalFn(),
-		processFn:   opts.ProcessFn(),
-		version:     kv.UninitializedVersion,
+		key:          key,
+		opts:         opts,
+		store:        opts.KVStore(),
+		log:          opts.InstrumentOptions().Logger(),
+		unmarshalFn:  opts.UnmarshalFn(),
+		processFn:    opts.ProcessFn(),
+		rejectFn:     opts.RejectFn(),
+		newMessageFn: opts.NewMessageFn(),
+		m:            newValueMetrics(scope),
+		version:      kv.UninitializedVersion,
 	}
 	v.updateWithLockFn = v.updateWithLock
 	return v
@@ -147,6 +216,55 @@ func (v *value) Unwatch() {
 	v.watch = nil
 }
 
+func (v *value) UpdateStatus() UpdateStatus {
+	v.RLock()
+	status := UpdateStatus{
+		LastGoodVersion: v.version,
+		RejectedVersion: v.rejectedVersion,
+		RejectionError:  v.rejectionErr,
+	}
+	v.RUnlock()
+	return status
+}
+
+func (v *value) Rollback() error {
+	if err := v.rollback(); err != nil {
+		v.m.rollbackErrors.Inc(1)
+		return err
+	}
+	v.m.rollbackSuccess.Inc(1)
+	return nil
+}
+
+func (v *value) rollback() error {
+	if v.newMessageFn == nil {
+		return errNilNewMessageFn
+	}
+
+	v.RLock()
+	lastGood, rejectedVersion := v.lastGood, v.rejectedVersion
+	v.RUnlock()
+
+	if rejectedVersion == kv.UninitializedVersion {
+		return errNoRejectedVersion
+	}
+	if lastGood == nil {
+		return errNoLastGoodValue
+	}
+	msg := v.newMessageFn()
+	if err := lastGood.Unmarshal(msg); err != nil {
+		return fmt.Errorf("error unmarshalling last good version %d: %v", lastGood.Version(), err)
+	}
+
+	// NB: the rollback is conditioned on the rejected version so that a newer
+	// update written in the meantime is never overwritten.
+	if _, err := v.store.CheckAndSet(v.key, rejectedVersion, msg); err != nil {
+		return fmt.Errorf("error rolling back rejected version %d to last good version %d: %v",
+			rejectedVersion, lastGood.Version(), err)
+	}
+	return nil
+}
+
 func (v *value) watchUpdates(watch kv.ValueWatch) {
 	for range watch.C() {
 		v.Lock()
@@ -175,15 +293,33 @@ func (v *value) updateWithLock(update kv.Value) error {
 	latest, err := v.unmarshalFn(update)
 	if err != nil {
 		err = fmt.Errorf("error unmarshalling value for version %d: %v", newVersion, err)
+		v.rejectWithLock(newVersion, err)
 		return err
 	}
 	if err := v.processFn(latest); err != nil {
+		v.rejectWithLock(newVersion, err)
 		return err
 	}
 	v.version = newVersion
+	v.lastGood = update
+	v.rejectedVersion = kv.UninitializedVersion
+	v.rejectionErr = nil
+	v.m.updateSuccess.Inc(1)
+	v.m.lastGoodVersion.Update(float64(newVersion))
+	v.m.rejectedVersion.Update(0)
 	return nil
 }
 
+func (v *value) rejectWithLock(version int, err error) {
+	v.rejectedVersion = version
+	v.rejectionErr = err
+	v.m.updateRejected.Inc(1)
+	v.m.rejectedVersion.Update(float64(version))
+	if v.rejectFn != nil {
+		v.rejectFn(version, err)
+	}
+}
+
 // CreateWatchError is returned when encountering an error creating a watch.
 type CreateWatchError struct {
 	innerError error
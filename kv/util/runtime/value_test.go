@@ -32,11 +32,13 @@ import (
 	"github.com/m3db/m3x/instrument"
 
 	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/require"
 )
 
 const (
-	testValueKey = "testValue"
+	testValueKey    = "testValue"
+	testWaitTimeout = 5 * time.Second
 )
 
 func TestValueWatchAlreadyWatching(t *testing.T) {
@@ -280,6 +282,104 @@ func TestValueUpdateStaleUpdate(t *testing.T) {
 	require.Equal(t, 3, rv.version)
 }
 
+func TestValueUpdateRejectedStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		rejectedVersions []int
+		errProcess       = errors.New("error processing")
+	)
+	_, rv := testValueWithMockStore(ctrl)
+	rv.unmarshalFn = func(v kv.Value) (interface{}, error) { return v, nil }
+	rv.processFn = func(v interface{}) error {
+		if v.(kv.Value).Version() == 4 {
+			return errProcess
+		}
+		return nil
+	}
+	rv.rejectFn = func(version int, err error) {
+		require.Equal(t, errProcess, err)
+		rejectedVersions = append(rejectedVersions, version)
+	}
+
+	require.NoError(t, rv.updateWithLock(mem.NewValue(3, nil)))
+	require.Equal(t, UpdateStatus{LastGoodVersion: 3}, rv.UpdateStatus())
+	require.False(t, rv.UpdateStatus().Rejected())
+
+	require.Error(t, rv.updateWithLock(mem.NewValue(4, nil)))
+	status := rv.UpdateStatus()
+	require.True(t, status.Rejected())
+	require.Equal(t, UpdateStatus{
+		LastGoodVersion: 3,
+		RejectedVersion: 4,
+		RejectionError:  errProcess,
+	}, status)
+	require.Equal(t, []int{4}, rejectedVersions)
+
+	require.NoError(t, rv.updateWithLock(mem.NewValue(5, nil)))
+	require.Equal(t, UpdateStatus{LastGoodVersion: 5}, rv.UpdateStatus())
+}
+
+func TestValueRollback(t *testing.T) {
+	store, rv := testValueWithMemStore()
+	rv.newMessageFn = func() proto.Message { return &commonpb.Int64Proto{} }
+	rv.unmarshalFn = func(v kv.Value) (interface{}, error) {
+		var msg commonpb.Int64Proto
+		err := v.Unmarshal(&msg)
+		return msg.Value, err
+	}
+	errTooLarge := errors.New("value too large")
+	rv.processFn = func(v interface{}) error {
+		if v.(int64) > 10 {
+			return errTooLarge
+		}
+		return nil
+	}
+	_, err := store.Set(testValueKey, &commonpb.Int64Proto{Value: 5})
+	require.NoError(t, err)
+	require.NoError(t, rv.Watch())
+	defer rv.Unwatch()
+
+	require.Equal(t, errNoRejectedVersion, rv.Rollback())
+
+	_, err = store.Set(testValueKey, &commonpb.Int64Proto{Value: 20})
+	require.NoError(t, err)
+	waitUntil(t, func() bool { return rv.UpdateStatus().Rejected() })
+	require.Equal(t, UpdateStatus{
+		LastGoodVersion: 1,
+		RejectedVersion: 2,
+		RejectionError:  errTooLarge,
+	}, rv.UpdateStatus())
+
+	require.NoError(t, rv.Rollback())
+	waitUntil(t, func() bool { return !rv.UpdateStatus().Rejected() })
+	require.Equal(t, UpdateStatus{LastGoodVersion: 3}, rv.UpdateStatus())
+
+	v, err := store.Get(testValueKey)
+	require.NoError(t, err)
+	var msg commonpb.Int64Proto
+	require.NoError(t, v.Unmarshal(&msg))
+	require.Equal(t, int64(5), msg.Value)
+}
+
+func TestValueRollbackErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store, rv := testValueWithMockStore(ctrl)
+	require.Equal(t, errNilNewMessageFn, rv.Rollback())
+
+	rv.newMessageFn = func() proto.Message { return &commonpb.BoolProto{} }
+	rv.rejectedVersion = 2
+	require.Equal(t, errNoLastGoodValue, rv.Rollback())
+
+	errCAS := errors.New("error check and set")
+	rv.lastGood = mem.NewValue(1, &commonpb.BoolProto{Value: true})
+	store.EXPECT().CheckAndSet(testValueKey, 2, &commonpb.BoolProto{Value: true}).Return(0, errCAS)
+	require.Error(t, rv.Rollback())
+}
+
 func testValueOptions(store kv.Store) Options {
 	return NewOptions().
 		SetInstrumentOptions(instrument.NewOptions()).
@@ -300,3 +400,13 @@ func testValueWithMemStore() (kv.Store, *value) {
 	opts := testValueOptions(store)
 	return store, NewValue(testValueKey, opts).(*value)
 }
+
+func waitUntil(t *testing.T, fn func() bool) {
+	deadline := time.Now().Add(testWaitTimeout)
+	for !fn() {
+		if time.Now().After(deadline) {
+			require.Fail(t, "timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
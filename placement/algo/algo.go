@@ -24,13 +24,21 @@ import (
 	"github.com/m3db/m3cluster/placement"
 )
 
-// NewAlgorithm returns a placement algorithm with given options
+// NewAlgorithm returns a placement algorithm with given options,
+// the custom algorithm in the options is returned if there is one.
 func NewAlgorithm(opts placement.Options) placement.Algorithm {
+	if a := opts.Algorithm(); a != nil {
+		return a
+	}
+
 	if opts.IsMirrored() {
 		return newMirroredAlgorithm(opts)
 	}
 
 	if opts.IsSharded() {
+		if opts.IsConsistentHashing() {
+			return newConsistentHashingAlgorithm(opts)
+		}
 		return newShardedAlgorithm(opts)
 	}
 
@@ -0,0 +1,49 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package algo
+
+import (
+	"testing"
+
+	"github.com/m3db/m3cluster/placement"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAlgorithm(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	opts := placement.NewOptions()
+	assert.IsType(t, rackAwarePlacementAlgorithm{}, NewAlgorithm(opts))
+	assert.IsType(t, mirroredAlgorithm{}, NewAlgorithm(opts.SetIsMirrored(true)))
+	assert.IsType(t, nonShardedAlgorithm{}, NewAlgorithm(opts.SetIsSharded(false)))
+
+	opts = opts.SetIsConsistentHashing(true)
+	assert.IsType(t, consistentHashingAlgorithm{}, NewAlgorithm(opts))
+	assert.IsType(t, mirroredAlgorithm{}, NewAlgorithm(opts.SetIsMirrored(true)))
+	assert.IsType(t, nonShardedAlgorithm{}, NewAlgorithm(opts.SetIsSharded(false)))
+
+	custom := placement.NewMockAlgorithm(ctrl)
+	assert.Equal(t, custom, NewAlgorithm(opts.SetAlgorithm(custom)))
+	assert.Equal(t, custom, NewAlgorithm(opts.SetAlgorithm(custom).SetIsMirrored(true)))
+}
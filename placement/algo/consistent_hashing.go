@@ -0,0 +1,615 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package algo
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
+)
+
+var (
+	errIncompatibleWithConsistentHashingAlgo = errors.New("could not apply consistent hashing algo on the placement")
+	errNonPositiveVirtualNodesPerWeight      = errors.New("could not build hash ring with non-positive virtual nodes per weight")
+)
+
+// consistentHashingAlgorithm places the replicas of each shard on the first
+// instances met walking clockwise on a hash ring from the hash of the shard,
+// skipping the instances that would break the failure domain policy. Each
+// instance owns a number of virtual nodes on the ring proportional to its
+// weight, so an instance owns its share of the shards in expectation.
+//
+// The owners of a shard only depend on the instances on the ring, so the
+// placement is deterministic given the same instances and shards, and adding
+// or removing an instance with a share w of the total weight only moves the
+// replicas of the shards it gains or loses, which are about w of all replicas.
+type consistentHashingAlgorithm struct {
+	opts placement.Options
+}
+
+func newConsistentHashingAlgorithm(opts placement.Options) placement.Algorithm {
+	return consistentHashingAlgorithm{opts: opts}
+}
+
+func (a consistentHashingAlgorithm) IsCompatibleWith(p placement.Placement) error {
+	if !p.IsSharded() || p.IsMirrored() {
+		return errIncompatibleWithConsistentHashingAlgo
+	}
+
+	return nil
+}
+
+func (a consistentHashingAlgorithm) InitialPlacement(
+	instances []placement.Instance,
+	shards []uint32,
+	rf int,
+) (placement.Placement, error) {
+	instances = placement.Instances(instances).Clone()
+	for _, instance := range instances {
+		instance.SetShards(shard.NewShards(nil))
+	}
+
+	p := placement.NewPlacement().
+		SetInstances(instances).
+		SetShards(shards).
+		SetIsSharded(true)
+	return a.transition(p, instances, rf, 0)
+}
+
+func (a consistentHashingAlgorithm) AddReplica(p placement.Placement) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p = p.Clone()
+	return a.transition(p, nonLeavingInstances(p.Instances()), p.ReplicaFactor()+1, 0)
+}
+
+func (a consistentHashingAlgorithm) RemoveReplica(p placement.Placement) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	if p.ReplicaFactor() <= 1 {
+		return nil, errNoReplicaToRemove
+	}
+
+	p = p.Clone()
+	return a.transition(p, nonLeavingInstances(p.Instances()), p.ReplicaFactor()-1, 0)
+}
+
+func (a consistentHashingAlgorithm) SplitShards(p placement.Placement, factor int) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	return splitShards(p, factor, a.opts)
+}
+
+func (a consistentHashingAlgorithm) RemoveInstances(
+	p placement.Placement,
+	instanceIDs []string,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p = p.Clone()
+	removing := make(map[string]struct{}, len(instanceIDs))
+	for _, id := range instanceIDs {
+		if _, exist := p.Instance(id); !exist {
+			return nil, fmt.Errorf("instance %s does not exist in placement", id)
+		}
+		removing[id] = struct{}{}
+	}
+
+	return a.transition(p, membersWithout(p, removing), p.ReplicaFactor(), 0)
+}
+
+func (a consistentHashingAlgorithm) AddInstances(
+	p placement.Placement,
+	instances []placement.Instance,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p = p.Clone()
+	members := nonLeavingInstances(p.Instances())
+	p, adding, err := addInstancesToRing(p, instances)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = a.transition(p, append(members, adding...), p.ReplicaFactor(), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkAddedInstances(p, adding); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (a consistentHashingAlgorithm) ReplaceInstances(
+	p placement.Placement,
+	leavingInstanceIDs []string,
+	addingInstances []placement.Instance,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p = p.Clone()
+	removing := make(map[string]struct{}, len(leavingInstanceIDs))
+	for _, id := range leavingInstanceIDs {
+		if _, exist := p.Instance(id); !exist {
+			return nil, fmt.Errorf("instance %s does not exist in placement", id)
+		}
+		removing[id] = struct{}{}
+	}
+
+	members := membersWithout(p, removing)
+	p, adding, err := addInstancesToRing(p, addingInstances)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = a.transition(p, append(members, adding...), p.ReplicaFactor(), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkAddedInstances(p, adding); err != nil {
+		return nil, err
+	}
+
+	if a.opts.AllowPartialReplace() {
+		return p, nil
+	}
+
+	// NB: the shards of the leaving instances are taken by the owners the ring
+	// picks for them, which are not necessarily the adding instances.
+	addingIDs := make(map[string]struct{}, len(adding))
+	for _, instance := range adding {
+		addingIDs[instance.ID()] = struct{}{}
+	}
+	for _, id := range leavingInstanceIDs {
+		leavingInstance, exist := p.Instance(id)
+		if !exist {
+			continue
+		}
+		unassigned := 0
+		for _, s := range leavingInstance.Shards().ShardsForState(shard.Leaving) {
+			if !isTakenBy(p, s.ID(), id, addingIDs) {
+				unassigned++
+			}
+		}
+		if unassigned > 0 {
+			return nil, fmt.Errorf("could not fully replace all shards from %s, %d shards left unassigned",
+				id, unassigned)
+		}
+	}
+	return p, nil
+}
+
+func (a consistentHashingAlgorithm) Rebalance(
+	p placement.Placement,
+	maxMoves int,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p = p.Clone()
+	return a.transition(p, nonLeavingInstances(p.Instances()), p.ReplicaFactor(), maxMoves)
+}
+
+func (a consistentHashingAlgorithm) UpdateInstanceWeights(
+	p placement.Placement,
+	weights map[string]uint32,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p, err := setInstanceWeights(p.Clone(), weights)
+	if err != nil {
+		return nil, err
+	}
+
+	// The number of virtual nodes of an instance follows its weight, so only
+	// the shards whose owners changed on the ring are moved.
+	return a.transition(p, nonLeavingInstances(p.Instances()), p.ReplicaFactor(), 0)
+}
+
+// transition moves the replicas of the shards in the placement to the owners
+// picked for them on the hash ring built from the member instances. When
+// maxMoves is positive, at most maxMoves replicas are moved and the shards
+// that would exceed it are left untouched.
+func (a consistentHashingAlgorithm) transition(
+	p placement.Placement,
+	members []placement.Instance,
+	rf int,
+	maxMoves int,
+) (placement.Placement, error) {
+	if a.opts.VirtualNodesPerWeight() <= 0 {
+		return nil, errNonPositiveVirtualNodesPerWeight
+	}
+
+	var (
+		opts       = placement.OptionsWithPlacementPolicy(p, a.opts)
+		ring       = newHashRing(members, opts.VirtualNodesPerWeight())
+		policy     = placement.PolicyForOptions(opts)
+		numDomains = policy.NumDomains(members)
+		// NB: only the default policy can be loosened, an explicit policy is
+		// always enforced.
+		loose    = opts.LooseRackCheck() && opts.FailureDomainPolicy().IsEmpty()
+		shardIDs = append([]uint32(nil), p.Shards()...)
+		moves    = 0
+	)
+	sort.Sort(shard.SortableIDsAsc(shardIDs))
+
+	for _, shardID := range shardIDs {
+		owners, err := ring.owners(shardID, rf, policy, numDomains, loose)
+		if err != nil {
+			return nil, err
+		}
+
+		holders := p.InstancesForShard(shardID)
+		if maxMoves > 0 {
+			numMoves := numReplicasToMove(shardID, holders, owners)
+			if moves+numMoves > maxMoves {
+				continue
+			}
+			moves += numMoves
+		}
+
+		a.moveReplicas(shardID, holders, owners)
+	}
+
+	instances := make([]placement.Instance, 0, p.NumInstances())
+	for _, instance := range p.Instances() {
+		if instance.Shards().NumShards() > 0 {
+			instances = append(instances, instance)
+		}
+	}
+
+	return p.
+		SetInstances(instances).
+		SetReplicaFactor(rf).
+		SetFailureDomainPolicy(opts.FailureDomainPolicy()).
+		SetCutoverNanos(a.opts.PlacementCutoverNanosFn()()), nil
+}
+
+// moveReplicas moves the replicas of a shard from its current holders to its
+// owners. The Available replicas off the owners become Leaving and hand over
+// to the Initializing replicas on the owners, the Initializing replicas off the
+// owners are removed, and the Leaving replicas on the owners are reclaimed.
+func (a consistentHashingAlgorithm) moveReplicas(
+	shardID uint32,
+	holders []placement.Instance,
+	owners []placement.Instance,
+) {
+	isOwner := make(map[string]struct{}, len(owners))
+	for _, owner := range owners {
+		isOwner[owner.ID()] = struct{}{}
+	}
+
+	var leaving []placement.Instance
+	for _, holder := range holders {
+		shards := holder.Shards()
+		s, ok := shards.Shard(shardID)
+		if !ok {
+			continue
+		}
+
+		if _, ok := isOwner[holder.ID()]; ok {
+			if s.State() == shard.Leaving {
+				shards.Add(shard.NewShard(shardID).SetState(shard.Available))
+			}
+			continue
+		}
+
+		switch s.State() {
+		case shard.Available:
+			s.SetState(shard.Leaving).SetCutoffNanos(a.opts.ShardCutoffNanosFn()())
+			leaving = append(leaving, holder)
+		case shard.Leaving:
+			leaving = append(leaving, holder)
+		default:
+			shards.Remove(shardID)
+		}
+	}
+
+	var (
+		initializing []shard.Shard
+		handedOver   = make(map[string]struct{}, len(leaving))
+		isLeaving    = make(map[string]struct{}, len(leaving))
+	)
+	for _, instance := range leaving {
+		isLeaving[instance.ID()] = struct{}{}
+	}
+	for _, owner := range owners {
+		shards := owner.Shards()
+		s, ok := shards.Shard(shardID)
+		if !ok {
+			s = shard.NewShard(shardID).
+				SetState(shard.Initializing).
+				SetCutoverNanos(a.opts.ShardCutoverNanosFn()())
+			shards.Add(s)
+		}
+		if s.State() != shard.Initializing {
+			continue
+		}
+		initializing = append(initializing, s)
+
+		// Keep the Initializing replicas taking over from a replica still
+		// Leaving, each Leaving replica hands over to a single one.
+		sourceID := s.SourceID()
+		_, sourceLeaving := isLeaving[sourceID]
+		_, sourceTaken := handedOver[sourceID]
+		if sourceLeaving && !sourceTaken {
+			handedOver[sourceID] = struct{}{}
+			continue
+		}
+		s.SetSourceID("")
+	}
+
+	var unsourced []shard.Shard
+	for _, s := range initializing {
+		if s.SourceID() == "" {
+			unsourced = append(unsourced, s)
+		}
+	}
+
+	for _, instance := range leaving {
+		if _, ok := handedOver[instance.ID()]; ok {
+			continue
+		}
+		if len(unsourced) > 0 {
+			unsourced[0].SetSourceID(instance.ID())
+			unsourced = unsourced[1:]
+			continue
+		}
+		// NB: a Leaving replica with nothing to hand over to is dropped with
+		// the replica factor, it is kept until the shard is marked Available
+		// unless other replicas of the shard are still Initializing.
+		if len(initializing) > 0 {
+			instance.Shards().Remove(shardID)
+		}
+	}
+}
+
+// numReplicasToMove returns the number of owners of a shard that do not hold
+// one of its non Leaving replicas yet.
+func numReplicasToMove(shardID uint32, holders, owners []placement.Instance) int {
+	holding := make(map[string]struct{}, len(holders))
+	for _, holder := range holders {
+		if s, ok := holder.Shards().Shard(shardID); ok && s.State() != shard.Leaving {
+			holding[holder.ID()] = struct{}{}
+		}
+	}
+
+	numMoves := 0
+	for _, owner := range owners {
+		if _, ok := holding[owner.ID()]; !ok {
+			numMoves++
+		}
+	}
+	return numMoves
+}
+
+// addInstancesToRing adds the instances to the placement, reusing the
+// instances already leaving the placement.
+func addInstancesToRing(
+	p placement.Placement,
+	instances []placement.Instance,
+) (placement.Placement, []placement.Instance, error) {
+	var (
+		all    = p.Instances()
+		adding = make([]placement.Instance, 0, len(instances))
+	)
+	for _, instance := range instances {
+		if existing, exist := p.Instance(instance.ID()); exist {
+			if !existing.IsLeaving() {
+				return nil, nil, errAddingInstanceAlreadyExist
+			}
+			adding = append(adding, existing)
+			continue
+		}
+
+		instance = instance.Clone().SetShards(shard.NewShards(nil))
+		all = append(all, instance)
+		adding = append(adding, instance)
+	}
+	return p.SetInstances(all), adding, nil
+}
+
+// checkAddedInstances returns an error if any of the added instances got no
+// shards on the ring and was therefore left out of the placement.
+func checkAddedInstances(p placement.Placement, adding []placement.Instance) error {
+	for _, instance := range adding {
+		if _, exist := p.Instance(instance.ID()); !exist {
+			return fmt.Errorf("could not assign any shards to instance %s", instance.ID())
+		}
+	}
+	return nil
+}
+
+// membersWithout returns the non leaving instances of the placement other
+// than the given ones.
+func membersWithout(p placement.Placement, ids map[string]struct{}) []placement.Instance {
+	var members []placement.Instance
+	for _, instance := range nonLeavingInstances(p.Instances()) {
+		if _, ok := ids[instance.ID()]; !ok {
+			members = append(members, instance)
+		}
+	}
+	return members
+}
+
+// isTakenBy returns whether the shard is handed over from the instance to
+// one of the given instances.
+func isTakenBy(p placement.Placement, shardID uint32, from string, to map[string]struct{}) bool {
+	for _, instance := range p.InstancesForShard(shardID) {
+		if _, ok := to[instance.ID()]; !ok {
+			continue
+		}
+		if s, ok := instance.Shards().Shard(shardID); ok && s.SourceID() == from {
+			return true
+		}
+	}
+	return false
+}
+
+type virtualNode struct {
+	hash     uint64
+	instance placement.Instance
+}
+
+type virtualNodesByHash []virtualNode
+
+func (s virtualNodesByHash) Len() int      { return len(s) }
+func (s virtualNodesByHash) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s virtualNodesByHash) Less(i, j int) bool {
+	if s[i].hash == s[j].hash {
+		return s[i].instance.ID() < s[j].instance.ID()
+	}
+	return s[i].hash < s[j].hash
+}
+
+// hashRing is a ring of virtual nodes sorted by their hashes, each instance
+// owns weight * vnodesPerWeight virtual nodes.
+type hashRing struct {
+	nodes []virtualNode
+}
+
+func newHashRing(instances []placement.Instance, vnodesPerWeight int) hashRing {
+	var nodes []virtualNode
+	for _, instance := range instances {
+		numNodes := int(instance.Weight()) * vnodesPerWeight
+		for i := 0; i < numNodes; i++ {
+			nodes = append(nodes, virtualNode{
+				hash:     hashString(instance.ID() + "-" + strconv.Itoa(i)),
+				instance: instance,
+			})
+		}
+	}
+	sort.Sort(virtualNodesByHash(nodes))
+	return hashRing{nodes: nodes}
+}
+
+// owners returns the rf instances owning the shard, which are the first
+// distinct instances met walking clockwise from the hash of the shard that
+// keep the replicas of the shard within the failure domain policy. When loose
+// is set and not enough instances satisfy the policy, the rest of the
+// replicas are placed on the next distinct instances regardless of it.
+func (r hashRing) owners(
+	shardID uint32,
+	rf int,
+	policy placement.FailureDomainPolicy,
+	numDomains []int,
+	loose bool,
+) ([]placement.Instance, error) {
+	var (
+		hash     = hashShard(shardID)
+		start    = sort.Search(len(r.nodes), func(i int) bool { return r.nodes[i].hash >= hash })
+		owners   = make([]placement.Instance, 0, rf)
+		isOwner  = make(map[string]struct{}, rf)
+		replicas = make([]map[string]int, policy.NumLevels())
+	)
+	for level := range replicas {
+		replicas[level] = make(map[string]int, rf)
+	}
+
+	pick := func(instance placement.Instance) {
+		owners = append(owners, instance)
+		isOwner[instance.ID()] = struct{}{}
+		for level := range replicas {
+			replicas[level][policy.Domain(instance, level)]++
+		}
+	}
+
+	for i := 0; i < len(r.nodes) && len(owners) < rf; i++ {
+		instance := r.nodes[(start+i)%len(r.nodes)].instance
+		if _, ok := isOwner[instance.ID()]; ok {
+			continue
+		}
+		if r.hasDomainConflict(instance, rf, policy, numDomains, replicas) {
+			continue
+		}
+		pick(instance)
+	}
+
+	for i := 0; loose && i < len(r.nodes) && len(owners) < rf; i++ {
+		instance := r.nodes[(start+i)%len(r.nodes)].instance
+		if _, ok := isOwner[instance.ID()]; !ok {
+			pick(instance)
+		}
+	}
+
+	if len(owners) < rf {
+		return nil, errNotEnoughRacks
+	}
+	return owners, nil
+}
+
+func (r hashRing) hasDomainConflict(
+	instance placement.Instance,
+	rf int,
+	policy placement.FailureDomainPolicy,
+	numDomains []int,
+	replicas []map[string]int,
+) bool {
+	for level := range replicas {
+		maxReplicas := policy.MaxReplicas(level, rf, numDomains[level])
+		if replicas[level][policy.Domain(instance, level)] >= maxReplicas {
+			return true
+		}
+	}
+	return false
+}
+
+func hashShard(shardID uint32) uint64 {
+	return hashString(strconv.FormatUint(uint64(shardID), 10))
+}
+
+// hashString hashes the string with fnv-1a, then mixes the bits with the
+// murmur3 finalizer so similar strings, such as the virtual nodes of an
+// instance, are spread across the whole ring.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	v := h.Sum64()
+	v ^= v >> 33
+	v *= 0xff51afd7ed558ccd
+	v ^= v >> 33
+	v *= 0xc4ceb9fe1a85ec53
+	v ^= v >> 33
+	return v
+}
@@ -0,0 +1,339 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package algo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsistentHashingInitialPlacement(t *testing.T) {
+	instances := consistentHashingTestInstances(9, 3)
+	ids := consistentHashingTestShards(1024)
+	opts := consistentHashingTestOptions()
+
+	a := newConsistentHashingAlgorithm(opts)
+	p, err := a.InitialPlacement(instances, ids, 3)
+	require.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	assert.Equal(t, 3, p.ReplicaFactor())
+	assert.Equal(t, 9, p.NumInstances())
+	validateZoneSpread(t, p, 3)
+	validateCutoverCutoffNanos(t, p, opts)
+
+	// Each instance owns about its share of the replicas.
+	for _, instance := range p.Instances() {
+		load := loadOnInstance(instance)
+		assert.True(t, load > 1024*3/9/2 && load < 1024*3/9*3/2,
+			fmt.Sprintf("unexpected load %d on %s", load, instance.ID()))
+	}
+
+	// The same inputs give the same placement.
+	for i := 0; i < 5; i++ {
+		p2, err := a.InitialPlacement(consistentHashingTestInstances(9, 3), ids, 3)
+		require.NoError(t, err)
+		assertSamePlacement(t, p, p2)
+	}
+
+	// The input instances are not modified.
+	for _, instance := range instances {
+		assert.Equal(t, 0, instance.Shards().NumShards())
+	}
+}
+
+func TestConsistentHashingWeights(t *testing.T) {
+	instances := consistentHashingTestInstances(4, 4)
+	instances[0].SetWeight(3)
+
+	a := newConsistentHashingAlgorithm(placement.NewOptions())
+	p, err := a.InitialPlacement(instances, consistentHashingTestShards(1024), 1)
+	require.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+
+	heavy, _ := p.Instance("i0")
+	for _, instance := range p.Instances()[1:] {
+		assert.True(t, loadOnInstance(heavy) > 2*loadOnInstance(instance))
+	}
+
+	p, err = a.UpdateInstanceWeights(markAllShardsAsAvailable(t, p), map[string]uint32{"i0": 1})
+	require.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	heavy, _ = p.Instance("i0")
+	assert.Equal(t, 0, heavy.Shards().NumShardsForState(shard.Initializing))
+	p = markAllShardsAsAvailable(t, p)
+	heavy, _ = p.Instance("i0")
+	assert.True(t, loadOnInstance(heavy) < 1024/2)
+}
+
+func TestConsistentHashingAddInstance(t *testing.T) {
+	numShards, rf := 1024, 3
+	opts := consistentHashingTestOptions()
+	a := newConsistentHashingAlgorithm(opts)
+	p, err := a.InitialPlacement(consistentHashingTestInstances(9, 3), consistentHashingTestShards(numShards), rf)
+	require.NoError(t, err)
+	p = markAllShardsAsAvailable(t, p)
+
+	i9 := placement.NewEmptyInstance("i9", "r1", "z1", "endpoint", 1)
+	p1, err := a.AddInstances(p, []placement.Instance{i9})
+	require.NoError(t, err)
+	assert.NoError(t, placement.Validate(p1))
+	validateZoneSpread(t, p1, rf)
+	validateCutoverCutoffNanos(t, p1, opts)
+
+	// Only the replicas taken by the new instance are moved, which are
+	// about its share of all the replicas.
+	added, ok := p1.Instance("i9")
+	require.True(t, ok)
+	moved := added.Shards().NumShardsForState(shard.Initializing)
+	assert.Equal(t, added.Shards().NumShards(), moved)
+	assert.True(t, moved > 0 && moved <= 2*numShards*rf/10, fmt.Sprintf("moved %d replicas", moved))
+	assert.Equal(t, moved, numShardsInState(p1, shard.Initializing))
+	assert.Equal(t, moved, numShardsInState(p1, shard.Leaving))
+
+	_, err = a.AddInstances(p1, []placement.Instance{i9})
+	assert.Equal(t, errAddingInstanceAlreadyExist, err)
+
+	// Removing the instance again moves its replicas back to their
+	// previous owners.
+	p2, err := a.RemoveInstances(markAllShardsAsAvailable(t, p1), []string{"i9"})
+	require.NoError(t, err)
+	assert.NoError(t, placement.Validate(p2))
+	p2 = markAllShardsAsAvailable(t, p2)
+	_, ok = p2.Instance("i9")
+	assert.False(t, ok)
+	assertSamePlacement(t, p, p2)
+}
+
+func TestConsistentHashingRemoveInstance(t *testing.T) {
+	numShards, rf := 1024, 3
+	a := newConsistentHashingAlgorithm(placement.NewOptions())
+	p, err := a.InitialPlacement(consistentHashingTestInstances(10, 4), consistentHashingTestShards(numShards), rf)
+	require.NoError(t, err)
+	p = markAllShardsAsAvailable(t, p)
+
+	removed, _ := p.Instance("i3")
+	load := loadOnInstance(removed)
+	p1, err := a.RemoveInstances(p, []string{"i3"})
+	require.NoError(t, err)
+	assert.NoError(t, placement.Validate(p1))
+	validateZoneSpread(t, p1, rf)
+
+	// Only the replicas on the removed instance are moved.
+	leaving, _ := p1.Instance("i3")
+	assert.True(t, leaving.IsLeaving())
+	assert.Equal(t, load, numShardsInState(p1, shard.Leaving))
+	assert.Equal(t, load, numShardsInState(p1, shard.Initializing))
+	assert.True(t, load <= 2*numShards*rf/10, fmt.Sprintf("moved %d replicas", load))
+
+	// Adding the leaving instance back reclaims its replicas.
+	p2, err := a.AddInstances(p1, []placement.Instance{placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1)})
+	require.NoError(t, err)
+	assert.NoError(t, placement.Validate(p2))
+	assert.Equal(t, 0, numShardsInState(p2, shard.Leaving))
+	assert.Equal(t, 0, numShardsInState(p2, shard.Initializing))
+	assertSamePlacement(t, p, p2)
+
+	_, err = a.RemoveInstances(p, []string{"bad"})
+	assert.Error(t, err)
+}
+
+func TestConsistentHashingReplaceInstance(t *testing.T) {
+	a := newConsistentHashingAlgorithm(placement.NewOptions())
+	p, err := a.InitialPlacement(consistentHashingTestInstances(6, 3), consistentHashingTestShards(256), 2)
+	require.NoError(t, err)
+	p = markAllShardsAsAvailable(t, p)
+
+	p1, err := a.ReplaceInstances(p, []string{"i0"}, []placement.Instance{
+		placement.NewEmptyInstance("i6", "r0", "z1", "endpoint", 1),
+	})
+	require.NoError(t, err)
+	assert.NoError(t, placement.Validate(p1))
+	validateZoneSpread(t, p1, 2)
+	leaving, _ := p1.Instance("i0")
+	assert.True(t, leaving.IsLeaving())
+
+	p1 = markAllShardsAsAvailable(t, p1)
+	_, ok := p1.Instance("i0")
+	assert.False(t, ok)
+	assert.NoError(t, placement.Validate(p1))
+
+	_, err = newConsistentHashingAlgorithm(placement.NewOptions().SetAllowPartialReplace(false)).
+		ReplaceInstances(p, []string{"i0"}, []placement.Instance{
+			placement.NewEmptyInstance("i6", "r0", "z1", "endpoint", 1),
+		})
+	assert.Error(t, err)
+}
+
+func TestConsistentHashingReplicaFactor(t *testing.T) {
+	opts := consistentHashingTestOptions()
+	a := newConsistentHashingAlgorithm(opts)
+	p, err := a.InitialPlacement(consistentHashingTestInstances(8, 4), consistentHashingTestShards(256), 1)
+	require.NoError(t, err)
+
+	for rf := 2; rf <= 4; rf++ {
+		p, err = a.AddReplica(p)
+		require.NoError(t, err)
+		assert.NoError(t, placement.Validate(p))
+		assert.Equal(t, rf, p.ReplicaFactor())
+		validateZoneSpread(t, p, rf)
+		validateCutoverCutoffNanos(t, p, opts)
+		p = markAllShardsAsAvailable(t, p)
+	}
+
+	_, err = a.AddReplica(p)
+	assert.Equal(t, errNotEnoughRacks, err)
+
+	for rf := 3; rf >= 1; rf-- {
+		p, err = a.RemoveReplica(p)
+		require.NoError(t, err)
+		assert.NoError(t, placement.Validate(p))
+		assert.Equal(t, rf, p.ReplicaFactor())
+		assert.Equal(t, 0, numShardsInState(p, shard.Initializing))
+		p = removeAllDroppedReplicas(t, markAllShardsAsAvailable(t, p))
+		assert.NoError(t, placement.Validate(p))
+	}
+
+	_, err = a.RemoveReplica(p)
+	assert.Equal(t, errNoReplicaToRemove, err)
+
+	// Removing replicas gives back the placement built with the lower replica factor.
+	p1, err := a.InitialPlacement(consistentHashingTestInstances(8, 4), consistentHashingTestShards(256), 1)
+	require.NoError(t, err)
+	assertSamePlacement(t, markAllShardsAsAvailable(t, p1), p)
+}
+
+func TestConsistentHashingNotEnoughRacks(t *testing.T) {
+	instances := consistentHashingTestInstances(4, 2)
+	ids := consistentHashingTestShards(64)
+
+	_, err := newConsistentHashingAlgorithm(placement.NewOptions()).InitialPlacement(instances, ids, 3)
+	assert.Equal(t, errNotEnoughRacks, err)
+
+	p, err := newConsistentHashingAlgorithm(placement.NewOptions().SetLooseRackCheck(true)).
+		InitialPlacement(instances, ids, 3)
+	require.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+}
+
+func TestConsistentHashingAddedInstanceWithoutShards(t *testing.T) {
+	a := newConsistentHashingAlgorithm(placement.NewOptions())
+	p, err := a.InitialPlacement(consistentHashingTestInstances(3, 3), consistentHashingTestShards(16), 1)
+	require.NoError(t, err)
+	p = markAllShardsAsAvailable(t, p)
+
+	// An instance without weight owns no virtual nodes and so takes no shards.
+	i9 := placement.NewEmptyInstance("i9", "r1", "z1", "endpoint", 0)
+	_, err = a.AddInstances(p, []placement.Instance{i9})
+	assert.Error(t, err)
+
+	_, err = a.ReplaceInstances(p, []string{"i0"}, []placement.Instance{i9})
+	assert.Error(t, err)
+}
+
+func TestConsistentHashingNonPositiveVirtualNodesPerWeight(t *testing.T) {
+	a := newConsistentHashingAlgorithm(placement.NewOptions().SetVirtualNodesPerWeight(0))
+	_, err := a.InitialPlacement(consistentHashingTestInstances(3, 3), consistentHashingTestShards(16), 1)
+	assert.Equal(t, errNonPositiveVirtualNodesPerWeight, err)
+}
+
+func TestConsistentHashingRebalance(t *testing.T) {
+	instances := consistentHashingTestInstances(6, 3)
+	ids := consistentHashingTestShards(256)
+	p, err := newShardedAlgorithm(placement.NewOptions()).InitialPlacement(instances, ids, 2)
+	require.NoError(t, err)
+	p = markAllShardsAsAvailable(t, p)
+
+	a := newConsistentHashingAlgorithm(placement.NewOptions())
+	p1, err := a.Rebalance(p, 10)
+	require.NoError(t, err)
+	assert.NoError(t, placement.Validate(p1))
+	assert.True(t, numShardsInState(p1, shard.Initializing) <= 10)
+
+	// Rebalancing without a limit moves the replicas to their owners on the ring.
+	p2, err := a.Rebalance(p1, 0)
+	require.NoError(t, err)
+	assert.NoError(t, placement.Validate(p2))
+	expected, err := a.InitialPlacement(consistentHashingTestInstances(6, 3), ids, 2)
+	require.NoError(t, err)
+	assertSamePlacement(t, markAllShardsAsAvailable(t, expected), markAllShardsAsAvailable(t, p2))
+}
+
+func TestIncompatibleWithConsistentHashingAlgo(t *testing.T) {
+	p, err := newNonShardedAlgorithm().InitialPlacement([]placement.Instance{
+		placement.NewInstance().SetID("i1").SetEndpoint("e1"),
+	}, []uint32{}, 1)
+	require.NoError(t, err)
+
+	a := newConsistentHashingAlgorithm(placement.NewOptions())
+	_, err = a.AddReplica(p)
+	assert.Equal(t, errIncompatibleWithConsistentHashingAlgo, err)
+
+	_, err = a.AddInstances(p.SetIsSharded(true).SetIsMirrored(true), nil)
+	assert.Equal(t, errIncompatibleWithConsistentHashingAlgo, err)
+}
+
+func consistentHashingTestOptions() placement.Options {
+	return placement.NewOptions().
+		SetPlacementCutoverNanosFn(timeNanosGen(1)).
+		SetShardCutoverNanosFn(timeNanosGen(2)).
+		SetShardCutoffNanosFn(timeNanosGen(3))
+}
+
+func consistentHashingTestInstances(numInstances, numRacks int) []placement.Instance {
+	instances := make([]placement.Instance, numInstances)
+	for i := range instances {
+		instances[i] = placement.NewEmptyInstance(
+			fmt.Sprintf("i%d", i), fmt.Sprintf("r%d", i%numRacks), "z1", "endpoint", 1,
+		)
+	}
+	return instances
+}
+
+func consistentHashingTestShards(numShards int) []uint32 {
+	ids := make([]uint32, numShards)
+	for i := range ids {
+		ids[i] = uint32(i)
+	}
+	return ids
+}
+
+func numShardsInState(p placement.Placement, state shard.State) int {
+	n := 0
+	for _, instance := range p.Instances() {
+		n += instance.Shards().NumShardsForState(state)
+	}
+	return n
+}
+
+func assertSamePlacement(t *testing.T, expected, actual placement.Placement) {
+	require.Equal(t, expected.NumInstances(), actual.NumInstances())
+	for _, instance := range expected.Instances() {
+		other, ok := actual.Instance(instance.ID())
+		require.True(t, ok, fmt.Sprintf("instance %s is missing", instance.ID()))
+		assert.True(t, instance.Shards().Equals(other.Shards()), fmt.Sprintf("shards differ on %s", instance.ID()))
+	}
+}
@@ -23,6 +23,7 @@ package algo
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 
 	"github.com/m3db/m3cluster/placement"
@@ -63,7 +64,7 @@ func (a mirroredAlgorithm) InitialPlacement(
 	shards []uint32,
 	rf int,
 ) (placement.Placement, error) {
-	mirrorInstances, err := groupInstancesByShardSetID(instances, rf)
+	mirrorInstances, err := groupInstancesByShardSetID(instances, rf, a.opts.FailureDomainPolicy())
 	if err != nil {
 		return nil, err
 	}
@@ -83,6 +84,94 @@ func (a mirroredAlgorithm) AddReplica(p placement.Placement) (placement.Placemen
 	return nil, errors.New("not supported")
 }
 
+func (a mirroredAlgorithm) RemoveReplica(p placement.Placement) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	if p.ReplicaFactor() <= 1 {
+		return nil, errNoReplicaToRemove
+	}
+
+	p, err := completePreviousMoves(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the shard sets are valid before dropping replicas from them.
+	if _, err := mirrorFromPlacement(p); err != nil {
+		return nil, err
+	}
+
+	// NB(cw) The instances in a shard set own the same shards, so one instance
+	// is dropped from each shard set with all its shards marked as Leaving.
+	var (
+		policy       = placement.PolicyForOptions(a.opts)
+		shardSetMap  = make(map[uint32]map[placement.Instance]struct{})
+		domainCounts = make(map[string]int)
+	)
+	for _, instance := range p.Instances() {
+		replicas, ok := shardSetMap[instance.ShardSetID()]
+		if !ok {
+			replicas = make(map[placement.Instance]struct{}, p.ReplicaFactor())
+			shardSetMap[instance.ShardSetID()] = replicas
+		}
+		replicas[instance] = struct{}{}
+		domainCounts[policy.Domain(instance, 0)]++
+	}
+
+	shardSetIDs := make([]uint32, 0, len(shardSetMap))
+	for ssID := range shardSetMap {
+		shardSetIDs = append(shardSetIDs, ssID)
+	}
+	sort.Sort(shard.SortableIDsAsc(shardSetIDs))
+
+	cutoffNanos := a.opts.ShardCutoffNanosFn()()
+	for _, ssID := range shardSetIDs {
+		instance := instanceToRemoveFromShardSet(policy, shardSetMap[ssID], domainCounts)
+		domainCounts[policy.Domain(instance, 0)]--
+		for _, s := range instance.Shards().All() {
+			s.SetState(shard.Leaving).SetCutoffNanos(cutoffNanos)
+		}
+	}
+
+	return p.
+		SetReplicaFactor(p.ReplicaFactor() - 1).
+		SetCutoverNanos(a.opts.PlacementCutoverNanosFn()()), nil
+}
+
+// instanceToRemoveFromShardSet picks the instance of a shard set to be dropped, preferring
+// the instance sharing failure domains with the most other instances in the shard set,
+// then the instance on the outermost domain with the most remaining instances.
+func instanceToRemoveFromShardSet(
+	policy placement.FailureDomainPolicy,
+	replicas map[placement.Instance]struct{},
+	domainCounts map[string]int,
+) placement.Instance {
+	var res *removableReplica
+	for instance := range replicas {
+		candidate := &removableReplica{
+			instance: instance,
+			crowding: replicasSharingDomains(policy, instance, replicas),
+			load:     domainCounts[policy.Domain(instance, 0)],
+		}
+		if res == nil || candidate.preferredOver(res) {
+			res = candidate
+		}
+	}
+	return res.instance
+}
+
+func (a mirroredAlgorithm) SplitShards(p placement.Placement, factor int) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	// NB(cw) The instances in a shard set own the same shards, so they are
+	// given the same child shards.
+	return splitShards(p, factor, a.opts)
+}
+
 func (a mirroredAlgorithm) RemoveInstances(
 	p placement.Placement,
 	instanceIDs []string,
@@ -97,7 +186,7 @@ func (a mirroredAlgorithm) RemoveInstances(
 		return a.returnInitializingShards(p, instanceIDs)
 	}
 
-	p, err := placement.MarkAllShardsAsAvailable(p)
+	p, err := completePreviousMoves(p)
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +205,11 @@ func (a mirroredAlgorithm) RemoveInstances(
 		return nil, err
 	}
 
-	mirrorInstances, err := groupInstancesByShardSetID(removingInstances, p.ReplicaFactor())
+	mirrorInstances, err := groupInstancesByShardSetID(
+		removingInstances,
+		p.ReplicaFactor(),
+		placement.OptionsWithPlacementPolicy(p, a.opts).FailureDomainPolicy(),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -152,7 +245,7 @@ func (a mirroredAlgorithm) AddInstances(
 		return a.reclaimLeavingShards(p, addingInstances)
 	}
 
-	p, err := placement.MarkAllShardsAsAvailable(p)
+	p, err := completePreviousMoves(p)
 	if err != nil {
 		return nil, err
 	}
@@ -167,7 +260,11 @@ func (a mirroredAlgorithm) AddInstances(
 		return nil, err
 	}
 
-	mirrorInstances, err := groupInstancesByShardSetID(addingInstances, p.ReplicaFactor())
+	mirrorInstances, err := groupInstancesByShardSetID(
+		addingInstances,
+		p.ReplicaFactor(),
+		placement.OptionsWithPlacementPolicy(p, a.opts).FailureDomainPolicy(),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -192,6 +289,60 @@ func (a mirroredAlgorithm) AddInstances(
 	return placementFromMirror(mirrorPlacement, append(p.Instances(), addingInstances...), p.ReplicaFactor())
 }
 
+func (a mirroredAlgorithm) Rebalance(
+	p placement.Placement,
+	maxMoves int,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p, err := completePreviousMoves(p)
+	if err != nil {
+		return nil, err
+	}
+
+	mirrorPlacement, err := mirrorFromPlacement(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// NB(cw) Each move in the mirror placement moves the shard on all the
+	// instances of a shard set.
+	ph := newHelper(mirrorPlacement, mirrorPlacement.ReplicaFactor(), a.opts)
+	ph.Rebalance(maxMoves)
+	return placementFromMirror(ph.GeneratePlacement(), p.Instances(), p.ReplicaFactor())
+}
+
+func (a mirroredAlgorithm) UpdateInstanceWeights(
+	p placement.Placement,
+	weights map[string]uint32,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p, err := completePreviousMoves(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// NB(cw) The instances in a shard set must have the same weight, otherwise
+	// the mirror placement could not be built.
+	if p, err = setInstanceWeights(p, weights); err != nil {
+		return nil, err
+	}
+
+	mirrorPlacement, err := mirrorFromPlacement(p)
+	if err != nil {
+		return nil, err
+	}
+
+	ph := newHelper(mirrorPlacement, mirrorPlacement.ReplicaFactor(), a.opts)
+	ph.Rebalance(0)
+	return placementFromMirror(ph.GeneratePlacement(), p.Instances(), p.ReplicaFactor())
+}
+
 func (a mirroredAlgorithm) ReplaceInstances(
 	p placement.Placement,
 	leavingInstanceIDs []string,
@@ -205,7 +356,7 @@ func (a mirroredAlgorithm) ReplaceInstances(
 		return nil, fmt.Errorf("could not replace %d instances with %d instances for mirrored replace", len(leavingInstanceIDs), len(addingInstances))
 	}
 
-	p, err := placement.MarkAllShardsAsAvailable(p)
+	p, err := completePreviousMoves(p)
 	if err != nil {
 		return nil, err
 	}
@@ -362,7 +513,7 @@ func validAddingInstances(p placement.Placement, addingInstances []placement.Ins
 			return nil, fmt.Errorf("instance %s already exist in the placement", instance.ID())
 		}
 		if instance.IsLeaving() {
-			// The instance was leaving in placement, after MarkAllShardsAsAvailable it is now removed
+			// The instance was leaving in placement, after completePreviousMoves it is now removed
 			// from the placement, so we should treat them as fresh new instances.
 			addingInstances[i] = instance.SetShards(shard.NewShards(nil))
 		}
@@ -370,9 +521,13 @@ func validAddingInstances(p placement.Placement, addingInstances []placement.Ins
 	return addingInstances, nil
 }
 
+// groupInstancesByShardSetID zips the instances with the same shard set id into virtual
+// instances. The instances in each shard set hold the replicas of the same shards, so they
+// must satisfy the failure domain policy if one is given, or be on different racks otherwise.
 func groupInstancesByShardSetID(
 	instances []placement.Instance,
 	rf int,
+	policy placement.FailureDomainPolicy,
 ) ([]placement.Instance, error) {
 	var (
 		shardSetMap = make(map[uint32]*shardSetMetadata, len(instances))
@@ -388,13 +543,14 @@ func groupInstancesByShardSetID(
 		meta, ok := shardSetMap[ssID]
 		if !ok {
 			meta = &shardSetMetadata{
-				weight: weight,
-				racks:  make(map[string]struct{}, rf),
-				shards: shards,
+				weight:    weight,
+				racks:     make(map[string]struct{}, rf),
+				shards:    shards,
+				instances: make([]placement.Instance, 0, rf),
 			}
 			shardSetMap[ssID] = meta
 		}
-		if _, ok := meta.racks[rack]; ok {
+		if _, ok := meta.racks[rack]; ok && policy.IsEmpty() {
 			return nil, fmt.Errorf("found duplicated rack %s for shardset id %d", rack, ssID)
 		}
 
@@ -407,14 +563,25 @@ func groupInstancesByShardSetID(
 		}
 
 		meta.racks[rack] = struct{}{}
+		meta.instances = append(meta.instances, instance)
 		meta.count++
 	}
 
+	var numDomains []int
+	if !policy.IsEmpty() {
+		numDomains = policy.NumDomains(instances)
+	}
 	for ssID, meta := range shardSetMap {
 		if meta.count != rf {
 			return nil, fmt.Errorf("found %d count of shard set id %d, expecting %d", meta.count, ssID, rf)
 		}
 
+		if !policy.IsEmpty() {
+			if err := policy.Check(meta.instances, rf, numDomains); err != nil {
+				return nil, fmt.Errorf("shard set id %d violates failure domain policy %s: %v", ssID, policy.String(), err)
+			}
+		}
+
 		// NB(cw) The shard set ID should to be assigned in placement service,
 		// the algorithm does not change the shard set id assigned to each instance.
 		ssIDStr := strconv.Itoa(int(ssID))
@@ -432,10 +599,21 @@ func groupInstancesByShardSetID(
 	return res, nil
 }
 
+// completePreviousMoves marks all the shards as available and removes the
+// replicas dropped by lowering the replica factor, so the shard sets are
+// complete before the placement is changed again.
+func completePreviousMoves(p placement.Placement) (placement.Placement, error) {
+	p, err := placement.MarkAllShardsAsAvailable(p)
+	if err != nil {
+		return nil, err
+	}
+	return placement.RemoveAllDroppedReplicas(p)
+}
+
 // mirrorFromPlacement zips all instances with the same shardSetID into a virtual instance
 // and create a placement with those virtual instance and rf=1.
 func mirrorFromPlacement(p placement.Placement) (placement.Placement, error) {
-	mirrorInstances, err := groupInstancesByShardSetID(p.Instances(), p.ReplicaFactor())
+	mirrorInstances, err := groupInstancesByShardSetID(p.Instances(), p.ReplicaFactor(), p.FailureDomainPolicy())
 	if err != nil {
 		return nil, err
 	}
@@ -444,9 +622,11 @@ func mirrorFromPlacement(p placement.Placement) (placement.Placement, error) {
 		SetInstances(mirrorInstances).
 		SetReplicaFactor(1).
 		SetShards(p.Shards()).
+		SetShardSplits(p.ShardSplits()).
 		SetCutoverNanos(p.CutoverNanos()).
 		SetIsSharded(true).
-		SetIsMirrored(true), nil
+		SetIsMirrored(true).
+		SetFailureDomainPolicy(p.FailureDomainPolicy()), nil
 }
 
 // placementFromMirror duplicates the shards for each shard set id and assign
@@ -482,9 +662,11 @@ func placementFromMirror(
 		SetInstances(instancesWithShards).
 		SetReplicaFactor(rf).
 		SetShards(mirror.Shards()).
+		SetShardSplits(mirror.ShardSplits()).
 		SetCutoverNanos(mirror.CutoverNanos()).
 		SetIsMirrored(true).
-		SetIsSharded(true), nil
+		SetIsSharded(true).
+		SetFailureDomainPolicy(mirror.FailureDomainPolicy()), nil
 }
 
 func instancesFromMirror(
@@ -526,8 +708,9 @@ func instancesFromMirror(
 }
 
 type shardSetMetadata struct {
-	weight uint32
-	count  int
-	racks  map[string]struct{}
-	shards shard.Shards
+	weight    uint32
+	count     int
+	racks     map[string]struct{}
+	shards    shard.Shards
+	instances []placement.Instance
 }
@@ -741,6 +741,201 @@ func TestMirrorReplaceWithLeavingShards(t *testing.T) {
 	assert.NoError(t, placement.Validate(p2))
 }
 
+func TestMirrorRebalance(t *testing.T) {
+	newInstance := func(id, rack string, ssID uint32) placement.Instance {
+		return placement.NewInstance().
+			SetID(id).
+			SetRack(rack).
+			SetEndpoint("endpoint-" + id).
+			SetShardSetID(ssID).
+			SetWeight(1)
+	}
+	i1 := newInstance("i1", "r1", 0)
+	i2 := newInstance("i2", "r2", 0)
+	i3 := newInstance("i3", "r1", 1)
+	i4 := newInstance("i4", "r2", 1)
+	for id := uint32(0); id < 4; id++ {
+		shardSetID := uint32(0)
+		if id == 3 {
+			shardSetID = 1
+		}
+		for _, instance := range []placement.Instance{i1, i2, i3, i4} {
+			if instance.ShardSetID() == shardSetID {
+				instance.Shards().Add(shard.NewShard(id).SetState(shard.Available))
+			}
+		}
+	}
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2, i3, i4}).
+		SetShards([]uint32{0, 1, 2, 3}).
+		SetReplicaFactor(2).
+		SetIsSharded(true).
+		SetIsMirrored(true)
+	assert.NoError(t, placement.Validate(p))
+
+	a := NewAlgorithm(placement.NewOptions().SetIsMirrored(true))
+	p, err := a.Rebalance(p, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+
+	// One shard moves from shard set 0 to shard set 1 on both instances.
+	for _, id := range []string{"i1", "i2"} {
+		instance, ok := p.Instance(id)
+		assert.True(t, ok)
+		assert.Equal(t, 1, instance.Shards().NumShardsForState(shard.Leaving))
+	}
+	for _, pair := range [][]string{{"i3", "i1"}, {"i4", "i2"}} {
+		instance, ok := p.Instance(pair[0])
+		assert.True(t, ok)
+		initializing := instance.Shards().ShardsForState(shard.Initializing)
+		assert.Equal(t, 1, len(initializing))
+		assert.Equal(t, pair[1], initializing[0].SourceID())
+	}
+}
+
+func TestMirrorUpdateInstanceWeights(t *testing.T) {
+	instances := []placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "", "endpoint1", 1).SetShardSetID(0),
+		placement.NewEmptyInstance("i2", "r2", "", "endpoint2", 1).SetShardSetID(0),
+		placement.NewEmptyInstance("i3", "r1", "", "endpoint3", 1).SetShardSetID(1),
+		placement.NewEmptyInstance("i4", "r2", "", "endpoint4", 1).SetShardSetID(1),
+	}
+	ids := make([]uint32, 12)
+	for i := range ids {
+		ids[i] = uint32(i)
+	}
+
+	a := NewAlgorithm(placement.NewOptions().SetIsMirrored(true))
+	p, err := a.InitialPlacement(instances, ids, 2)
+	assert.NoError(t, err)
+	p, err = placement.MarkAllShardsAsAvailable(p)
+	assert.NoError(t, err)
+
+	// The instances in a shard set must share the same weight.
+	_, err = a.UpdateInstanceWeights(p, map[string]uint32{"i1": 2})
+	assert.Error(t, err)
+
+	p, err = a.UpdateInstanceWeights(p, map[string]uint32{"i1": 2, "i2": 2})
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	p, err = placement.MarkAllShardsAsAvailable(p)
+	assert.NoError(t, err)
+	for _, id := range []string{"i1", "i2"} {
+		instance, ok := p.Instance(id)
+		assert.True(t, ok)
+		assert.Equal(t, uint32(2), instance.Weight())
+		assert.Equal(t, 8, instance.Shards().NumShards())
+	}
+}
+
+func TestMirrorRemoveReplica(t *testing.T) {
+	instances := []placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "", "endpoint1", 1).SetShardSetID(0),
+		placement.NewEmptyInstance("i2", "r2", "", "endpoint2", 1).SetShardSetID(0),
+		placement.NewEmptyInstance("i3", "r1", "", "endpoint3", 1).SetShardSetID(1),
+		placement.NewEmptyInstance("i4", "r2", "", "endpoint4", 1).SetShardSetID(1),
+	}
+	ids := make([]uint32, 12)
+	for i := range ids {
+		ids[i] = uint32(i)
+	}
+
+	a := NewAlgorithm(placement.NewOptions().SetIsMirrored(true))
+	p, err := a.InitialPlacement(instances, ids, 2)
+	assert.NoError(t, err)
+
+	p, err = a.RemoveReplica(p)
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	assert.Equal(t, 1, p.ReplicaFactor())
+
+	// One instance of each shard set is dropped, leaving one instance on each rack.
+	for _, id := range []string{"i1", "i4"} {
+		instance, ok := p.Instance(id)
+		assert.True(t, ok)
+		assert.True(t, instance.IsLeaving())
+	}
+
+	p, err = placement.MarkAllShardsAsAvailable(p)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, p.NumInstances())
+	p, err = placement.RemoveAllDroppedReplicas(p)
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	assert.Equal(t, 2, p.NumInstances())
+	for _, id := range []string{"i2", "i3"} {
+		instance, ok := p.Instance(id)
+		assert.True(t, ok)
+		assert.Equal(t, 6, instance.Shards().NumShardsForState(shard.Available))
+	}
+
+	_, err = a.RemoveReplica(p)
+	assert.Equal(t, errNoReplicaToRemove, err)
+}
+
+func TestMirrorSplitShards(t *testing.T) {
+	instances := []placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "", "endpoint1", 1).SetShardSetID(0),
+		placement.NewEmptyInstance("i2", "r2", "", "endpoint2", 1).SetShardSetID(0),
+		placement.NewEmptyInstance("i3", "r1", "", "endpoint3", 1).SetShardSetID(1),
+		placement.NewEmptyInstance("i4", "r2", "", "endpoint4", 1).SetShardSetID(1),
+	}
+
+	a := NewAlgorithm(placement.NewOptions().SetIsMirrored(true))
+	p, err := a.InitialPlacement(instances, []uint32{0, 1, 2, 3}, 2)
+	assert.NoError(t, err)
+	p, err = placement.MarkAllShardsAsAvailable(p)
+	assert.NoError(t, err)
+
+	p, err = a.SplitShards(p, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	assert.Equal(t, 12, p.NumShards())
+	assert.Equal(t, []uint32{2, 6, 10}, p.ShardSplits()[2])
+
+	// The instances in a shard set still own the same shards.
+	p, err = placement.MarkAllShardsAsAvailable(p)
+	assert.NoError(t, err)
+	_, err = mirrorFromPlacement(p)
+	assert.NoError(t, err)
+	for _, instance := range p.Instances() {
+		assert.Equal(t, 6, instance.Shards().NumShardsForState(shard.Available))
+	}
+}
+
+func TestMirrorSplitShardsKeptByOperations(t *testing.T) {
+	instances := []placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "", "endpoint1", 1).SetShardSetID(0),
+		placement.NewEmptyInstance("i2", "r2", "", "endpoint2", 1).SetShardSetID(0),
+		placement.NewEmptyInstance("i3", "r1", "", "endpoint3", 1).SetShardSetID(1),
+		placement.NewEmptyInstance("i4", "r2", "", "endpoint4", 1).SetShardSetID(1),
+	}
+
+	a := NewAlgorithm(placement.NewOptions().SetIsMirrored(true))
+	p, err := a.InitialPlacement(instances, []uint32{0, 1, 2, 3}, 2)
+	assert.NoError(t, err)
+	p, err = placement.MarkAllShardsAsAvailable(p)
+	assert.NoError(t, err)
+
+	p, err = a.SplitShards(p, 2)
+	assert.NoError(t, err)
+	splits := p.ShardSplits()
+
+	mirror, err := mirrorFromPlacement(p)
+	assert.NoError(t, err)
+	assert.Equal(t, splits, mirror.ShardSplits())
+
+	p, err = placement.MarkAllShardsAsAvailable(p)
+	assert.NoError(t, err)
+	p, err = a.AddInstances(p, []placement.Instance{
+		placement.NewEmptyInstance("i5", "r1", "", "endpoint5", 1).SetShardSetID(2),
+		placement.NewEmptyInstance("i6", "r2", "", "endpoint6", 1).SetShardSetID(2),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	assert.Equal(t, splits, p.ShardSplits())
+}
+
 func TestIncompatibleWithMirroredAlgo(t *testing.T) {
 	a := newMirroredAlgorithm(placement.NewOptions())
 	p := placement.NewPlacement()
@@ -777,7 +972,7 @@ func TestGroupInstanceByShardSetID(t *testing.T) {
 			shard.NewShard(0).SetState(shard.Available),
 		}))
 
-	res, err := groupInstancesByShardSetID([]placement.Instance{i1, i2}, 2)
+	res, err := groupInstancesByShardSetID([]placement.Instance{i1, i2}, 2, placement.FailureDomainPolicy{})
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(res))
 	assert.Equal(t, placement.NewInstance().
@@ -789,11 +984,22 @@ func TestGroupInstanceByShardSetID(t *testing.T) {
 			shard.NewShard(0).SetState(shard.Available),
 		})), res[0])
 
-	_, err = groupInstancesByShardSetID([]placement.Instance{i1, i2.Clone().SetWeight(2)}, 2)
+	_, err = groupInstancesByShardSetID([]placement.Instance{i1, i2.Clone().SetWeight(2)}, 2, placement.FailureDomainPolicy{})
+	assert.Error(t, err)
+
+	_, err = groupInstancesByShardSetID([]placement.Instance{i1, i2.Clone().SetRack("r1")}, 2, placement.FailureDomainPolicy{})
 	assert.Error(t, err)
 
-	_, err = groupInstancesByShardSetID([]placement.Instance{i1, i2.Clone().SetRack("r1")}, 2)
+	// With a failure domain policy, the instances in a shard set are checked against the policy.
+	policy := placement.NewFailureDomainPolicy(placement.ZoneLevel, placement.RackLevel)
+	i3 := i1.Clone().SetID("i3").SetZone("z2").SetShardSetID(1)
+	i4 := i2.Clone().SetID("i4").SetZone("z2").SetShardSetID(1)
+	_, err = groupInstancesByShardSetID([]placement.Instance{i1, i2, i3, i4}, 2, policy)
 	assert.Error(t, err)
+
+	res, err = groupInstancesByShardSetID([]placement.Instance{i1, i2.Clone().SetZone("z2"), i3.Clone().SetZone("z1"), i4}, 2, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(res))
 }
 
 func TestReturnInitializingShards(t *testing.T) {
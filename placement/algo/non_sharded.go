@@ -30,6 +30,7 @@ import (
 var (
 	errShardsOnNonShardedAlgo         = errors.New("could not apply shards in non-sharded placement")
 	errInCompatibleWithNonShardedAlgo = errors.New("could not apply non-sharded algo on the placement")
+	errSplitShardsOnNonShardedAlgo    = errors.New("could not split shards in non-sharded placement")
 )
 
 type nonShardedAlgorithm struct{}
@@ -74,6 +75,26 @@ func (a nonShardedAlgorithm) AddReplica(p placement.Placement) (placement.Placem
 	return p.Clone().SetReplicaFactor(p.ReplicaFactor() + 1), nil
 }
 
+func (a nonShardedAlgorithm) RemoveReplica(p placement.Placement) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	if p.ReplicaFactor() <= 1 {
+		return nil, errNoReplicaToRemove
+	}
+
+	return p.Clone().SetReplicaFactor(p.ReplicaFactor() - 1), nil
+}
+
+func (a nonShardedAlgorithm) SplitShards(p placement.Placement, factor int) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	return nil, errSplitShardsOnNonShardedAlgo
+}
+
 func (a nonShardedAlgorithm) RemoveInstances(
 	p placement.Placement,
 	instanceIDs []string,
@@ -135,3 +156,26 @@ func (a nonShardedAlgorithm) ReplaceInstances(
 
 	return a.RemoveInstances(p, leavingInstanceIDs)
 }
+
+func (a nonShardedAlgorithm) Rebalance(
+	p placement.Placement,
+	maxMoves int,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	// There are no shards to move in a non-sharded placement.
+	return p.Clone(), nil
+}
+
+func (a nonShardedAlgorithm) UpdateInstanceWeights(
+	p placement.Placement,
+	weights map[string]uint32,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	return setInstanceWeights(p.Clone(), weights)
+}
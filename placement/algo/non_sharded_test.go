@@ -60,6 +60,19 @@ func TestNonShardedAlgo(t *testing.T) {
 	assert.Equal(t, 2, p.ReplicaFactor())
 	assert.False(t, p.IsSharded())
 
+	p1, err := a.RemoveReplica(p)
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p1))
+	assert.Equal(t, 2, p1.NumInstances())
+	assert.Equal(t, 1, p1.ReplicaFactor())
+	assert.Equal(t, 2, p.ReplicaFactor())
+
+	_, err = a.RemoveReplica(p1)
+	assert.Equal(t, errNoReplicaToRemove, err)
+
+	_, err = a.SplitShards(p, 2)
+	assert.Equal(t, errSplitShardsOnNonShardedAlgo, err)
+
 	p, err = a.AddInstances(p, []placement.Instance{i3})
 	assert.NoError(t, err)
 	assert.NoError(t, placement.Validate(p))
@@ -114,6 +127,14 @@ func TestIncompatibleWithNonShardedAlgo(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, errInCompatibleWithNonShardedAlgo, err)
 
+	_, err = a.RemoveReplica(p)
+	assert.Error(t, err)
+	assert.Equal(t, errInCompatibleWithNonShardedAlgo, err)
+
+	_, err = a.SplitShards(p, 2)
+	assert.Error(t, err)
+	assert.Equal(t, errInCompatibleWithNonShardedAlgo, err)
+
 	_, err = a.AddInstances(p, []placement.Instance{i3})
 	assert.Error(t, err)
 	assert.Equal(t, errInCompatibleWithNonShardedAlgo, err)
@@ -30,6 +30,7 @@ import (
 var (
 	errNotEnoughRacks              = errors.New("not enough racks to take shards, please make sure RF is less than number of racks")
 	errIncompatibleWithShardedAlgo = errors.New("could not apply sharded algo on the placement")
+	errNoReplicaToRemove           = errors.New("could not remove replica from the placement with replica factor 1")
 )
 
 type rackAwarePlacementAlgorithm struct {
@@ -90,6 +91,29 @@ func (a rackAwarePlacementAlgorithm) AddReplica(p placement.Placement) (placemen
 	return ph.GeneratePlacement(), nil
 }
 
+func (a rackAwarePlacementAlgorithm) RemoveReplica(p placement.Placement) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	if p.ReplicaFactor() <= 1 {
+		return nil, errNoReplicaToRemove
+	}
+
+	p = p.Clone()
+	ph := newHelper(p, p.ReplicaFactor()-1, a.opts)
+	ph.RemoveReplica()
+	return ph.GeneratePlacement(), nil
+}
+
+func (a rackAwarePlacementAlgorithm) SplitShards(p placement.Placement, factor int) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	return splitShards(p, factor, a.opts)
+}
+
 func (a rackAwarePlacementAlgorithm) RemoveInstances(
 	p placement.Placement,
 	instanceIDs []string,
@@ -145,6 +169,40 @@ func (a rackAwarePlacementAlgorithm) AddInstances(
 	return p, nil
 }
 
+func (a rackAwarePlacementAlgorithm) Rebalance(
+	p placement.Placement,
+	maxMoves int,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p = p.Clone()
+	ph := newHelper(p, p.ReplicaFactor(), a.opts)
+	ph.Rebalance(maxMoves)
+	return ph.GeneratePlacement(), nil
+}
+
+func (a rackAwarePlacementAlgorithm) UpdateInstanceWeights(
+	p placement.Placement,
+	weights map[string]uint32,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p, err := setInstanceWeights(p.Clone(), weights)
+	if err != nil {
+		return nil, err
+	}
+
+	// The target loads are calculated with the new weights, so rebalancing
+	// moves only the shards needed to reach them.
+	ph := newHelper(p, p.ReplicaFactor(), a.opts)
+	ph.Rebalance(0)
+	return ph.GeneratePlacement(), nil
+}
+
 func (a rackAwarePlacementAlgorithm) ReplaceInstances(
 	p placement.Placement,
 	leavingInstanceIDs []string,
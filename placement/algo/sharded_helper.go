@@ -25,6 +25,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 
 	"github.com/m3db/m3cluster/placement"
 	"github.com/m3db/m3cluster/shard"
@@ -35,6 +36,8 @@ var (
 	errAddingInstanceAlreadyExist         = errors.New("the adding instance is already in the placement")
 	errInstanceContainsNonLeavingShards   = errors.New("the adding instance contains non leaving shards")
 	errInstanceContainsInitializingShards = errors.New("the adding instance contains initializing shards")
+	errInvalidShardSplitFactor            = errors.New("the shard split factor must be at least 2")
+	errShardSplitInProgress               = errors.New("could not split shards before the previous shard split completes")
 )
 
 type instanceType int
@@ -64,9 +67,17 @@ type PlacementHelper interface {
 	// Instances returns the list of instances managed by the PlacementHelper.
 	Instances() []placement.Instance
 
+	// TargetLoadForInstance returns the number of shards the instance should own
+	// for the load to be balanced by weight.
+	TargetLoadForInstance(id string) int
+
 	// HasRackConflict checks if the rack constraint is violated when moving the shard to the target rack.
 	HasRackConflict(shard uint32, from placement.Instance, toRack string) bool
 
+	// HasDomainConflict checks if the failure domain policy is violated when moving the shard
+	// to the target instance.
+	HasDomainConflict(shard uint32, from, to placement.Instance) bool
+
 	// PlaceShards distributes shards to the instances in the helper, with aware of where are the shards coming from.
 	PlaceShards(shards []shard.Shard, from placement.Instance, candidates []placement.Instance) error
 
@@ -86,21 +97,40 @@ type PlacementHelper interface {
 	// ReturnInitializingShards returns all the initializing shards on the given instance
 	// by returning them back to the original owners.
 	ReturnInitializingShards(instance placement.Instance)
+
+	// Rebalance moves available shards from the instances loaded above their target load
+	// to the instances loaded below it, moving at most maxMoves shards when maxMoves is
+	// positive, and returns the number of shards moved.
+	Rebalance(maxMoves int) int
+
+	// RemoveReplica drops one replica of each shard, preferring the replicas on the most
+	// crowded failure domains and the most loaded instances. The dropped Available replicas
+	// are marked as Leaving, the dropped Initializing replicas are removed.
+	RemoveReplica()
 }
 
 type placementHelper struct {
-	targetLoad         map[string]int
-	shardToInstanceMap map[uint32]map[placement.Instance]struct{}
-	rackToInstancesMap map[string]map[placement.Instance]struct{}
-	rackToWeightMap    map[string]uint32
-	totalWeight        uint32
-	rf                 int
-	uniqueShards       []uint32
-	instances          map[string]placement.Instance
-	log                log.Logger
-	opts               placement.Options
+	targetLoad           map[string]int
+	shardToInstanceMap   map[uint32]map[placement.Instance]struct{}
+	domainToInstancesMap map[string]map[placement.Instance]struct{}
+	domainToWeightMap    map[string]uint32
+	domainFn             domainFn
+	maxReplicasPerDomain int
+	policy               placement.FailureDomainPolicy
+	numDomains           []int
+	totalWeight          uint32
+	rf                   int
+	uniqueShards         []uint32
+	shardSplits          map[uint32][]uint32
+	instances            map[string]placement.Instance
+	log                  log.Logger
+	opts                 placement.Options
 }
 
+// domainFn returns the isolation domain of an instance. Replicas of a shard
+// are spread across isolation domains.
+type domainFn func(instance placement.Instance) string
+
 // NewPlacementHelper returns a placement helper
 func NewPlacementHelper(p placement.Placement, opts placement.Options) PlacementHelper {
 	return newHelper(p, p.ReplicaFactor(), opts)
@@ -188,10 +218,13 @@ func newReplaceInstanceHelper(
 }
 
 func newHelper(p placement.Placement, targetRF int, opts placement.Options) PlacementHelper {
+	opts = placement.OptionsWithPlacementPolicy(p, opts)
 	ph := &placementHelper{
 		rf:           targetRF,
 		instances:    make(map[string]placement.Instance, p.NumInstances()),
 		uniqueShards: p.Shards(),
+		shardSplits:  p.ShardSplits(),
+		policy:       placement.PolicyForOptions(opts),
 		log:          opts.InstrumentOptions().Logger(),
 		opts:         opts,
 	}
@@ -200,6 +233,15 @@ func newHelper(p placement.Placement, targetRF int, opts placement.Options) Plac
 		ph.instances[instance.ID()] = instance
 	}
 
+	// NB: the load is balanced across the domains at the outermost level, when
+	// there are fewer domains than replicas, the replicas are spread as evenly
+	// as possible across them and further isolated at the inner levels.
+	ph.numDomains = ph.policy.NumDomains(nonLeavingInstances(p.Instances()))
+	ph.maxReplicasPerDomain = ph.policy.MaxReplicas(0, targetRF, ph.numDomains[0])
+	ph.domainFn = func(instance placement.Instance) string {
+		return ph.policy.Domain(instance, 0)
+	}
+
 	ph.scanCurrentLoad()
 	ph.buildTargetLoad()
 	return ph
@@ -207,21 +249,22 @@ func newHelper(p placement.Placement, targetRF int, opts placement.Options) Plac
 
 func (ph *placementHelper) scanCurrentLoad() {
 	ph.shardToInstanceMap = make(map[uint32]map[placement.Instance]struct{}, len(ph.uniqueShards))
-	ph.rackToInstancesMap = make(map[string]map[placement.Instance]struct{})
-	ph.rackToWeightMap = make(map[string]uint32)
+	ph.domainToInstancesMap = make(map[string]map[placement.Instance]struct{})
+	ph.domainToWeightMap = make(map[string]uint32)
 	totalWeight := uint32(0)
 	for _, instance := range ph.instances {
-		if _, exist := ph.rackToInstancesMap[instance.Rack()]; !exist {
-			ph.rackToInstancesMap[instance.Rack()] = make(map[placement.Instance]struct{})
+		domain := ph.domainFn(instance)
+		if _, exist := ph.domainToInstancesMap[domain]; !exist {
+			ph.domainToInstancesMap[domain] = make(map[placement.Instance]struct{})
 		}
-		ph.rackToInstancesMap[instance.Rack()][instance] = struct{}{}
+		ph.domainToInstancesMap[domain][instance] = struct{}{}
 
 		if instance.IsLeaving() {
 			// Leaving instances are not counted as usable capacities in the placement.
 			continue
 		}
 
-		ph.rackToWeightMap[instance.Rack()] = ph.rackToWeightMap[instance.Rack()] + instance.Weight()
+		ph.domainToWeightMap[domain] = ph.domainToWeightMap[domain] + instance.Weight()
 		totalWeight += instance.Weight()
 
 		for _, s := range instance.Shards().All() {
@@ -235,11 +278,11 @@ func (ph *placementHelper) scanCurrentLoad() {
 }
 
 func (ph *placementHelper) buildTargetLoad() {
-	overWeightedRack := 0
+	overWeightedDomain := 0
 	overWeight := uint32(0)
-	for _, weight := range ph.rackToWeightMap {
-		if isRackOverWeight(weight, ph.totalWeight, ph.rf) {
-			overWeightedRack++
+	for _, weight := range ph.domainToWeightMap {
+		if isDomainOverWeight(weight, ph.totalWeight, ph.rf, ph.maxReplicasPerDomain) {
+			overWeightedDomain++
 			overWeight += weight
 		}
 	}
@@ -250,13 +293,14 @@ func (ph *placementHelper) buildTargetLoad() {
 			// We should not set a target load for leaving instances.
 			continue
 		}
-		rackWeight := ph.rackToWeightMap[instance.Rack()]
-		if isRackOverWeight(rackWeight, ph.totalWeight, ph.rf) {
-			// if the instance is on a over-sized rack, the target load is topped at shardLen / rackSize
-			targetLoad[instance.ID()] = int(math.Ceil(float64(ph.getShardLen()) * float64(instance.Weight()) / float64(rackWeight)))
+		domainWeight := ph.domainToWeightMap[ph.domainFn(instance)]
+		if isDomainOverWeight(domainWeight, ph.totalWeight, ph.rf, ph.maxReplicasPerDomain) {
+			// if the instance is on a over-sized domain, the target load is topped at
+			// the number of replicas the domain may own / domainSize
+			targetLoad[instance.ID()] = int(math.Ceil(float64(ph.getShardLen()*ph.maxReplicasPerDomain) * float64(instance.Weight()) / float64(domainWeight)))
 		} else {
-			// if the instance is on a normal rack, get the target load with aware of other over-sized rack
-			targetLoad[instance.ID()] = ph.getShardLen() * (ph.rf - overWeightedRack) * int(instance.Weight()) / int(ph.totalWeight-overWeight)
+			// if the instance is on a normal domain, get the target load with aware of other over-sized domains
+			targetLoad[instance.ID()] = ph.getShardLen() * (ph.rf - overWeightedDomain*ph.maxReplicasPerDomain) * int(instance.Weight()) / int(ph.totalWeight-overWeight)
 		}
 	}
 	ph.targetLoad = targetLoad
@@ -274,7 +318,7 @@ func (ph *placementHelper) getShardLen() int {
 	return len(ph.uniqueShards)
 }
 
-func (ph *placementHelper) targetLoadForInstance(id string) int {
+func (ph *placementHelper) TargetLoadForInstance(id string) int {
 	return ph.targetLoad[id]
 }
 
@@ -363,7 +407,7 @@ func (ph *placementHelper) HasRackConflict(shard uint32, from placement.Instance
 }
 
 func (ph *placementHelper) buildInstanceHeap(instances []placement.Instance, availableCapacityAscending bool) (heap.Interface, error) {
-	return newHeap(instances, availableCapacityAscending, ph.targetLoad, ph.rackToWeightMap)
+	return newHeap(instances, availableCapacityAscending, ph.targetLoad, ph.domainToWeightMap, ph.domainFn)
 }
 
 func (ph *placementHelper) GeneratePlacement() placement.Placement {
@@ -388,9 +432,11 @@ func (ph *placementHelper) GeneratePlacement() placement.Placement {
 	return placement.NewPlacement().
 		SetInstances(instances).
 		SetShards(ph.uniqueShards).
+		SetShardSplits(ph.shardSplits).
 		SetReplicaFactor(ph.rf).
 		SetIsSharded(true).
 		SetIsMirrored(ph.opts.IsMirrored()).
+		SetFailureDomainPolicy(ph.opts.FailureDomainPolicy()).
 		SetCutoverNanos(ph.opts.PlacementCutoverNanosFn()())
 }
 
@@ -495,6 +541,228 @@ func (ph *placementHelper) mostUnderLoadedInstance() (placement.Instance, bool)
 	return nil, false
 }
 
+func (ph *placementHelper) Rebalance(maxMoves int) int {
+	moved := 0
+	for maxMoves <= 0 || moved < maxMoves {
+		if !ph.moveOneShardTowardsTarget() {
+			break
+		}
+		moved++
+	}
+	return moved
+}
+
+// moveOneShardTowardsTarget moves one available shard from an instance loaded above
+// its target load to an instance loaded below it, trying the most under loaded
+// instances and the most over loaded instances first.
+func (ph *placementHelper) moveOneShardTowardsTarget() bool {
+	var underLoaded, overLoaded []sortableInstance
+	for id, instance := range ph.instances {
+		if instance.IsLeaving() {
+			continue
+		}
+		loadGap := ph.targetLoad[id] - loadOnInstance(instance)
+		if loadGap > 0 {
+			underLoaded = append(underLoaded, sortableInstance{instance: instance, value: loadGap})
+		} else if loadGap < 0 {
+			overLoaded = append(overLoaded, sortableInstance{instance: instance, value: -loadGap})
+		}
+	}
+	sort.Sort(sortableInstancesByValueDesc(underLoaded))
+	sort.Sort(sortableInstancesByValueDesc(overLoaded))
+
+	for _, to := range underLoaded {
+		for _, from := range overLoaded {
+			if ph.moveOneShardInState(from.instance, to.instance, shard.Available) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (ph *placementHelper) RemoveReplica() {
+	remaining := make(map[uint32]struct{}, len(ph.uniqueShards))
+	for _, shardID := range ph.uniqueShards {
+		remaining[shardID] = struct{}{}
+	}
+
+	dropped := make(map[uint32]placement.Instance, len(ph.uniqueShards))
+	for len(remaining) > 0 {
+		shardID, instance, ok := ph.nextReplicaToRemove(remaining)
+		delete(remaining, shardID)
+		if !ok {
+			continue
+		}
+
+		s, _ := instance.Shards().Shard(shardID)
+		switch s.State() {
+		case shard.Unknown, shard.Initializing:
+			// NB(cw): The Initializing shard holds no data yet, the Leaving shard
+			// on its source, if any, becomes the dropped replica.
+			instance.Shards().Remove(shardID)
+		default:
+			s.SetState(shard.Leaving).SetCutoffNanos(ph.opts.ShardCutoffNanosFn()())
+			dropped[shardID] = instance
+		}
+		delete(ph.shardToInstanceMap[shardID], instance)
+	}
+
+	// NB(cw): The replicas are dropped greedily, which may leave some instances
+	// below their target loads, swap the dropped replicas to balance the load.
+	for ph.swapDroppedReplicas(dropped) {
+	}
+}
+
+// swapDroppedReplicas looks for a chain of instances, starting from an instance loaded
+// below its target load and ending at an instance loaded above it, where each instance
+// keeps a replica it dropped and the next instance drops its replica of the same shard
+// instead, without making the spread of the replicas worse. It swaps the replicas along
+// the chain and returns true if such a chain was found.
+func (ph *placementHelper) swapDroppedReplicas(dropped map[uint32]placement.Instance) bool {
+	droppedByInstance := make(map[placement.Instance][]uint32, len(ph.instances))
+	for shardID, instance := range dropped {
+		droppedByInstance[instance] = append(droppedByInstance[instance], shardID)
+	}
+
+	type hop struct {
+		from    placement.Instance
+		shardID uint32
+	}
+	for _, start := range ph.instances {
+		if loadOnInstance(start) >= ph.targetLoad[start.ID()] {
+			continue
+		}
+
+		var (
+			queue   = []placement.Instance{start}
+			parents = map[placement.Instance]hop{start: {}}
+		)
+		for len(queue) > 0 {
+			from := queue[0]
+			queue = queue[1:]
+			shardIDs := droppedByInstance[from]
+			sort.Sort(shard.SortableIDsAsc(shardIDs))
+			for _, shardID := range shardIDs {
+				for _, to := range ph.replicasToSwap(shardID, from) {
+					if _, visited := parents[to]; visited {
+						continue
+					}
+					parents[to] = hop{from: from, shardID: shardID}
+					if loadOnInstance(to) <= ph.targetLoad[to.ID()] {
+						queue = append(queue, to)
+						continue
+					}
+
+					for to != start {
+						h := parents[to]
+						ph.swapDroppedReplica(h.shardID, h.from, to)
+						dropped[h.shardID] = to
+						to = h.from
+					}
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// replicasToSwap returns the instances that could drop their replicas of the shard
+// instead of the given instance, sorted by id.
+func (ph *placementHelper) replicasToSwap(shardID uint32, dropping placement.Instance) []placement.Instance {
+	replicas := ph.shardToInstanceMap[shardID]
+	replicas[dropping] = struct{}{}
+	defer delete(replicas, dropping)
+
+	kept := ph.removableReplica(shardID, dropping)
+	var res []placement.Instance
+	for instance := range replicas {
+		if instance == dropping {
+			continue
+		}
+		candidate := ph.removableReplica(shardID, instance)
+		if !candidate.initializing && candidate.rank(kept) == 0 {
+			res = append(res, instance)
+		}
+	}
+	sort.Sort(placement.ByIDAscending(res))
+	return res
+}
+
+// swapDroppedReplica keeps the dropped replica of the shard on the from instance
+// and drops the replica on the to instance instead.
+func (ph *placementHelper) swapDroppedReplica(shardID uint32, from, to placement.Instance) {
+	s, _ := to.Shards().Shard(shardID)
+	s.SetState(shard.Leaving).SetCutoffNanos(ph.opts.ShardCutoffNanosFn()())
+	delete(ph.shardToInstanceMap[shardID], to)
+	ph.assignShardToInstance(shard.NewShard(shardID).SetState(shard.Available), from)
+}
+
+// nextReplicaToRemove picks the next replica to be dropped among the remaining shards.
+// The instances loaded the most above their target load drop their replicas first, as
+// long as the replica is one of the best to drop for its shard, otherwise the replica
+// of the smallest remaining shard is picked regardless of the load.
+func (ph *placementHelper) nextReplicaToRemove(
+	remaining map[uint32]struct{},
+) (uint32, placement.Instance, bool) {
+	instances := make([]sortableInstance, 0, len(ph.instances))
+	for id, instance := range ph.instances {
+		if instance.IsLeaving() {
+			continue
+		}
+		instances = append(instances, sortableInstance{
+			instance: instance,
+			value:    loadOnInstance(instance) - ph.targetLoad[id],
+		})
+	}
+	sort.Sort(sortableInstancesByValueDesc(instances))
+
+	for _, candidate := range instances {
+		for _, s := range candidate.instance.Shards().All() {
+			if _, ok := remaining[s.ID()]; !ok || s.State() == shard.Leaving {
+				continue
+			}
+			if best, ok := ph.replicaToRemove(s.ID()); ok && best.rank(ph.removableReplica(s.ID(), candidate.instance)) == 0 {
+				return s.ID(), candidate.instance, true
+			}
+		}
+	}
+
+	shardIDs := make([]uint32, 0, len(remaining))
+	for shardID := range remaining {
+		shardIDs = append(shardIDs, shardID)
+	}
+	sort.Sort(shard.SortableIDsAsc(shardIDs))
+	best, ok := ph.replicaToRemove(shardIDs[0])
+	if !ok {
+		return shardIDs[0], nil, false
+	}
+	return shardIDs[0], best.instance, true
+}
+
+// replicaToRemove returns the replica of the shard that is the best to be dropped.
+func (ph *placementHelper) replicaToRemove(shardID uint32) (*removableReplica, bool) {
+	var res *removableReplica
+	for instance := range ph.shardToInstanceMap[shardID] {
+		candidate := ph.removableReplica(shardID, instance)
+		if res == nil || candidate.preferredOver(res) {
+			res = candidate
+		}
+	}
+	return res, res != nil
+}
+
+func (ph *placementHelper) removableReplica(shardID uint32, instance placement.Instance) *removableReplica {
+	s, _ := instance.Shards().Shard(shardID)
+	return &removableReplica{
+		instance:     instance,
+		initializing: s.State() == shard.Initializing || s.State() == shard.Unknown,
+		crowding:     replicasSharingDomains(ph.policy, instance, ph.shardToInstanceMap[shardID]),
+		load:         loadOnInstance(instance) - ph.targetLoad[instance.ID()],
+	}
+}
+
 func (ph *placementHelper) Optimize(t optimizeType) error {
 	var fn assignLoadFn
 	switch t {
@@ -566,7 +834,7 @@ func (ph *placementHelper) assignTargetLoad(
 	targetInstance placement.Instance,
 	moveOneShardFn func(from, to placement.Instance) bool,
 ) error {
-	targetLoad := ph.targetLoadForInstance(targetInstance.ID())
+	targetLoad := ph.TargetLoadForInstance(targetInstance.ID())
 	// try to take shards from the most loaded instances until the adding instance reaches target load
 	instanceHeap, err := ph.buildInstanceHeap(nonLeavingInstances(ph.Instances()), false)
 	if err != nil {
@@ -592,7 +860,35 @@ func (ph *placementHelper) canAssignInstance(shardID uint32, from, to placement.
 		// and i1 should be able to take it and mark it as "Available"
 		return false
 	}
-	return ph.opts.LooseRackCheck() || !ph.HasRackConflict(shardID, from, to.Rack())
+	if ph.opts.FailureDomainPolicy().IsEmpty() {
+		if ph.opts.LooseRackCheck() {
+			return true
+		}
+		if !ph.opts.SpreadAcrossZones() {
+			return !ph.HasRackConflict(shardID, from, to.Rack())
+		}
+	}
+	return !ph.HasDomainConflict(shardID, from, to)
+}
+
+func (ph *placementHelper) HasDomainConflict(shardID uint32, from, to placement.Instance) bool {
+	innermost := ph.policy.NumLevels() - 1
+	if from != nil && ph.policy.Domain(from, innermost) == ph.policy.Domain(to, innermost) {
+		return false
+	}
+	for level := 0; level <= innermost; level++ {
+		domain := ph.policy.Domain(to, level)
+		replicas := 0
+		for instance := range ph.shardToInstanceMap[shardID] {
+			if instance != from && ph.policy.Domain(instance, level) == domain {
+				replicas++
+			}
+		}
+		if replicas >= ph.policy.MaxReplicas(level, ph.rf, ph.numDomains[level]) {
+			return true
+		}
+	}
+	return false
 }
 
 func (ph *placementHelper) assignShardToInstance(s shard.Shard, to placement.Instance) {
@@ -607,7 +903,8 @@ func (ph *placementHelper) assignShardToInstance(s shard.Shard, to placement.Ins
 // instanceHeap provides an easy way to get best candidate instance to assign/steal a shard
 type instanceHeap struct {
 	instances         []placement.Instance
-	rackToWeightMap   map[string]uint32
+	domainToWeightMap map[string]uint32
+	domainFn          domainFn
 	targetLoad        map[string]int
 	capacityAscending bool
 }
@@ -616,13 +913,15 @@ func newHeap(
 	instances []placement.Instance,
 	capacityAscending bool,
 	targetLoad map[string]int,
-	rackToWeightMap map[string]uint32,
+	domainToWeightMap map[string]uint32,
+	domainFn domainFn,
 ) (*instanceHeap, error) {
 	h := &instanceHeap{
 		capacityAscending: capacityAscending,
 		instances:         instances,
 		targetLoad:        targetLoad,
-		rackToWeightMap:   rackToWeightMap,
+		domainToWeightMap: domainToWeightMap,
+		domainFn:          domainFn,
 	}
 	heap.Init(h)
 	return h, nil
@@ -641,11 +940,12 @@ func (h *instanceHeap) Less(i, j int) bool {
 	instanceJ := h.instances[j]
 	leftLoadOnI := h.targetLoadForInstance(instanceI.ID()) - loadOnInstance(instanceI)
 	leftLoadOnJ := h.targetLoadForInstance(instanceJ.ID()) - loadOnInstance(instanceJ)
-	// if both instance has tokens to be filled, prefer the one on a bigger rack
+	// if both instance has tokens to be filled, prefer the one on a bigger domain
 	// since it tends to be more picky in accepting shards
 	if leftLoadOnI > 0 && leftLoadOnJ > 0 {
-		if instanceI.Rack() != instanceJ.Rack() {
-			return h.rackToWeightMap[instanceI.Rack()] > h.rackToWeightMap[instanceJ.Rack()]
+		domainI, domainJ := h.domainFn(instanceI), h.domainFn(instanceJ)
+		if domainI != domainJ {
+			return h.domainToWeightMap[domainI] > h.domainToWeightMap[domainJ]
 		}
 	}
 	// compare left capacity on both instances
@@ -671,8 +971,11 @@ func (h *instanceHeap) Pop() interface{} {
 	return instance
 }
 
-func isRackOverWeight(rackWeight, totalWeight uint32, rf int) bool {
-	return float64(rackWeight)/float64(totalWeight) >= 1.0/float64(rf)
+// isDomainOverWeight checks if an isolation domain holds too much weight to be
+// given its share of the load, as it may only own maxReplicas of the rf
+// replicas of each shard.
+func isDomainOverWeight(domainWeight, totalWeight uint32, rf, maxReplicas int) bool {
+	return float64(domainWeight)/float64(totalWeight) >= float64(maxReplicas)/float64(rf)
 }
 
 func addInstanceToPlacement(
@@ -706,6 +1009,169 @@ func removeInstanceFromPlacement(p placement.Placement, id string) (placement.Pl
 	return p.SetInstances(placement.RemoveInstanceFromList(p.Instances(), id)), leavingInstance, nil
 }
 
+// setInstanceWeights sets the weights of the instances in the placement.
+func setInstanceWeights(p placement.Placement, weights map[string]uint32) (placement.Placement, error) {
+	for id, weight := range weights {
+		instance, exist := p.Instance(id)
+		if !exist {
+			return nil, fmt.Errorf("instance %s does not exist in placement", id)
+		}
+		if weight == 0 {
+			return nil, fmt.Errorf("invalid weight 0 for instance %s", id)
+		}
+		instance.SetWeight(weight)
+	}
+	return p, nil
+}
+
+// splitShards splits each shard in the placement into factor child shards. With the
+// shard ids ranging in [0, n), the children of shard i are i, i+n, ..., i+(factor-1)*n,
+// so a key hashed to shard h among the new shards belongs to parent shard h % n.
+// The children are placed on the instances owning the parent, which already hold
+// their data. The first child keeps the id and the state of the parent, the other
+// children of the Available parents are Initializing, and those of the Initializing
+// and Leaving parents follow the parents in their handoff.
+func splitShards(p placement.Placement, factor int, opts placement.Options) (placement.Placement, error) {
+	if factor < 2 {
+		return nil, errInvalidShardSplitFactor
+	}
+
+	// NB: the shard splits only keep the latest generation of child shards, so
+	// the previous split must complete before the parent to child mapping that
+	// clients route on during the transition can be replaced.
+	if len(p.ShardSplits()) > 0 && !allShardsAvailable(p) {
+		return nil, errShardSplitInProgress
+	}
+
+	p = p.Clone()
+	var (
+		parents    = p.Shards()
+		numParents = uint32(0)
+	)
+	for _, id := range parents {
+		if id+1 > numParents {
+			numParents = id + 1
+		}
+	}
+
+	var (
+		shardIDs    = make([]uint32, 0, len(parents)*factor)
+		shardSplits = make(map[uint32][]uint32, len(parents))
+	)
+	for _, parent := range parents {
+		children := make([]uint32, factor)
+		for i := range children {
+			children[i] = parent + uint32(i)*numParents
+		}
+		shardSplits[parent] = children
+		shardIDs = append(shardIDs, children...)
+	}
+	sort.Sort(shard.SortableIDsAsc(shardIDs))
+
+	cutoverNanos := opts.ShardCutoverNanosFn()()
+	for _, instance := range p.Instances() {
+		shards := instance.Shards()
+		for _, s := range shards.All() {
+			for _, child := range shardSplits[s.ID()][1:] {
+				newShard := shard.NewShard(child)
+				switch s.State() {
+				case shard.Available:
+					newShard.SetState(shard.Initializing).SetCutoverNanos(cutoverNanos)
+				default:
+					newShard.
+						SetState(s.State()).
+						SetSourceID(s.SourceID()).
+						SetCutoverNanos(s.CutoverNanos()).
+						SetCutoffNanos(s.CutoffNanos())
+				}
+				shards.Add(newShard)
+			}
+		}
+	}
+
+	return p.
+		SetInstances(p.Instances()).
+		SetShards(shardIDs).
+		SetShardSplits(shardSplits).
+		SetCutoverNanos(opts.PlacementCutoverNanosFn()()), nil
+}
+
+func allShardsAvailable(p placement.Placement) bool {
+	for _, instance := range p.Instances() {
+		shards := instance.Shards()
+		if shards.NumShards() != shards.NumShardsForState(shard.Available) {
+			return false
+		}
+	}
+	return true
+}
+
+// removableReplica is a replica of a shard that could be dropped. Initializing replicas
+// are preferred as they are the cheapest to drop, then the replicas sharing failure
+// domains with the most other replicas, then the replicas with the highest load, which
+// measures how much the replica is over its fair share.
+type removableReplica struct {
+	instance     placement.Instance
+	initializing bool
+	crowding     []int
+	load         int
+}
+
+// rank compares the state and the failure domains of the replicas, it returns a
+// positive number if the replica is preferred to be dropped over the other one,
+// a negative number if the other one is preferred, and 0 if neither is.
+func (r *removableReplica) rank(other *removableReplica) int {
+	if r.initializing != other.initializing {
+		if r.initializing {
+			return 1
+		}
+		return -1
+	}
+	return compareInts(r.crowding, other.crowding)
+}
+
+func (r *removableReplica) preferredOver(other *removableReplica) bool {
+	if c := r.rank(other); c != 0 {
+		return c > 0
+	}
+	if r.load != other.load {
+		return r.load > other.load
+	}
+	return r.instance.ID() < other.instance.ID()
+}
+
+// replicasSharingDomains returns, for each level of the policy from the outermost to the
+// innermost, the number of other replicas in the same domain as the given instance.
+func replicasSharingDomains(
+	policy placement.FailureDomainPolicy,
+	instance placement.Instance,
+	replicas map[placement.Instance]struct{},
+) []int {
+	res := make([]int, policy.NumLevels())
+	for level := range res {
+		domain := policy.Domain(instance, level)
+		for replica := range replicas {
+			if replica != instance && policy.Domain(replica, level) == domain {
+				res[level]++
+			}
+		}
+	}
+	return res
+}
+
+// compareInts compares two slices of the same length lexicographically.
+func compareInts(a, b []int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
 func getShardMap(shards []shard.Shard) map[uint32]shard.Shard {
 	r := make(map[uint32]shard.Shard, len(shards))
 
@@ -715,6 +1181,30 @@ func getShardMap(shards []shard.Shard) map[uint32]shard.Shard {
 	return r
 }
 
+// sortableInstance is an instance with a value to sort by.
+type sortableInstance struct {
+	instance placement.Instance
+	value    int
+}
+
+// sortableInstancesByValueDesc sorts instances by value descending, then by id ascending.
+type sortableInstancesByValueDesc []sortableInstance
+
+func (s sortableInstancesByValueDesc) Len() int {
+	return len(s)
+}
+
+func (s sortableInstancesByValueDesc) Less(i, j int) bool {
+	if s[i].value != s[j].value {
+		return s[i].value > s[j].value
+	}
+	return s[i].instance.ID() < s[j].instance.ID()
+}
+
+func (s sortableInstancesByValueDesc) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
 func loadOnInstance(instance placement.Instance) int {
 	return instance.Shards().NumShards() - instance.Shards().NumShardsForState(shard.Leaving)
 }
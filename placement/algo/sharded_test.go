@@ -1123,12 +1123,560 @@ func TestIncompatibleWithShardedAlgo(t *testing.T) {
 	assert.Equal(t, errIncompatibleWithShardedAlgo, err)
 }
 
+func TestSpreadAcrossZones(t *testing.T) {
+	var instances []placement.Instance
+	for z := 1; z <= 3; z++ {
+		for r := 1; r <= 2; r++ {
+			for i := 1; i <= 2; i++ {
+				instances = append(instances, placement.NewEmptyInstance(
+					fmt.Sprintf("z%d-r%d-i%d", z, r, i), fmt.Sprintf("r%d", r), fmt.Sprintf("z%d", z), "endpoint", 1))
+			}
+		}
+	}
+
+	ids := make([]uint32, 64)
+	for i := range ids {
+		ids[i] = uint32(i)
+	}
+
+	opts := placement.NewOptions().SetSpreadAcrossZones(true)
+	a := newShardedAlgorithm(opts)
+	p, err := a.InitialPlacement(instances, ids, 3)
+	assert.NoError(t, err)
+	validateZoneSpread(t, p, 1)
+	p = markAllShardsAsAvailable(t, p)
+	ph := NewPlacementHelper(p, opts)
+	for _, i := range p.Instances() {
+		assert.Equal(t, 16, ph.TargetLoadForInstance(i.ID()))
+		assert.Equal(t, 16, loadOnInstance(i))
+	}
+
+	p, err = a.AddInstances(p, []placement.Instance{placement.NewEmptyInstance("z1-r3-i1", "r3", "z1", "endpoint", 1)})
+	assert.NoError(t, err)
+	validateZoneSpread(t, p, 1)
+	p = markAllShardsAsAvailable(t, p)
+
+	p, err = a.RemoveInstances(p, []string{"z2-r1-i1"})
+	assert.NoError(t, err)
+	validateZoneSpread(t, p, 1)
+	p = markAllShardsAsAvailable(t, p)
+
+	p, err = a.ReplaceInstances(p, []string{"z3-r2-i2"}, []placement.Instance{placement.NewEmptyInstance("z3-r3-i1", "r3", "z3", "endpoint", 1)})
+	assert.NoError(t, err)
+	validateZoneSpread(t, p, 1)
+	p = markAllShardsAsAvailable(t, p)
+
+	// With more replicas than zones, a zone may own two replicas of a shard
+	// but they must be on different racks
+	p, err = a.AddReplica(p)
+	assert.NoError(t, err)
+	validateZoneSpread(t, p, 2)
+	p = markAllShardsAsAvailable(t, p)
+	assert.NoError(t, placement.Validate(p))
+	ph = NewPlacementHelper(p, opts)
+	for _, i := range p.Instances() {
+		assert.InDelta(t, ph.TargetLoadForInstance(i.ID()), loadOnInstance(i), 2)
+	}
+}
+
+func TestSpreadAcrossZonesNotEnoughRacks(t *testing.T) {
+	instances := []placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i2", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i3", "r1", "z2", "endpoint", 1),
+	}
+
+	a := newShardedAlgorithm(placement.NewOptions().SetSpreadAcrossZones(true))
+	_, err := a.InitialPlacement(instances, []uint32{0, 1, 2, 3}, 3)
+	assert.Equal(t, errNotEnoughRacks, err)
+}
+
+func TestFailureDomainPolicy(t *testing.T) {
+	newInstance := func(region, zone, rack string, i int) placement.Instance {
+		return placement.NewEmptyInstance(
+			fmt.Sprintf("%s-%s-%s-i%d", region, zone, rack, i), rack, zone, "endpoint", 1,
+		).SetFailureDomains(map[string]string{placement.RegionLevel: region})
+	}
+
+	var instances []placement.Instance
+	for _, region := range []string{"re1", "re2"} {
+		for _, zone := range []string{"z1", "z2"} {
+			for _, rack := range []string{"r1", "r2"} {
+				instances = append(instances, newInstance(region, zone, rack, 1))
+			}
+		}
+	}
+
+	ids := make([]uint32, 32)
+	for i := range ids {
+		ids[i] = uint32(i)
+	}
+
+	policy := placement.NewFailureDomainPolicy(placement.RegionLevel, placement.ZoneLevel, placement.RackLevel)
+	opts := placement.NewOptions().SetFailureDomainPolicy(policy)
+	a := newShardedAlgorithm(opts)
+
+	// With 4 replicas, each region owns 2 replicas of a shard, one in each zone.
+	p, err := a.InitialPlacement(instances, ids, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, policy, p.FailureDomainPolicy())
+	assert.NoError(t, placement.Validate(p))
+	validateFailureDomainSpread(t, p, 2, 1)
+	p = markAllShardsAsAvailable(t, p)
+
+	p, err = a.AddInstances(p, []placement.Instance{newInstance("re2", "z1", "r3", 1)})
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	validateFailureDomainSpread(t, p, 2, 1)
+	p = markAllShardsAsAvailable(t, p)
+
+	p, err = a.RemoveInstances(p, []string{"re1-z2-r1-i1"})
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	validateFailureDomainSpread(t, p, 2, 1)
+	p = markAllShardsAsAvailable(t, p)
+
+	p, err = a.ReplaceInstances(p, []string{"re1-z1-r2-i1"}, []placement.Instance{newInstance("re1", "z1", "r3", 1)})
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	validateFailureDomainSpread(t, p, 2, 1)
+
+	// A rack in a region and zone with another replica conflicts, the same
+	// rack name in another region does not.
+	p = markAllShardsAsAvailable(t, p)
+	ph := NewPlacementHelper(p, opts)
+	replicas := p.InstancesForShard(0)
+	target := newInstance(replicas[0].FailureDomain(placement.RegionLevel), replicas[0].Zone(), "r9", 2)
+	assert.True(t, ph.HasDomainConflict(0, nil, target))
+	assert.False(t, ph.HasDomainConflict(0, replicas[0], target))
+}
+
+func TestFailureDomainPolicyKeptWithDefaultOptions(t *testing.T) {
+	newInstance := func(zone, rack string, i int) placement.Instance {
+		return placement.NewEmptyInstance(fmt.Sprintf("%s-%s-i%d", zone, rack, i), rack, zone, "endpoint", 1)
+	}
+
+	var instances []placement.Instance
+	for _, zone := range []string{"z1", "z2"} {
+		for _, rack := range []string{"r1", "r2"} {
+			instances = append(instances, newInstance(zone, rack, 1))
+		}
+	}
+
+	policy := placement.NewFailureDomainPolicy(placement.ZoneLevel, placement.RackLevel)
+	p, err := newShardedAlgorithm(placement.NewOptions().SetFailureDomainPolicy(policy)).
+		InitialPlacement(instances, []uint32{0, 1, 2, 3, 4, 5, 6, 7}, 2)
+	assert.NoError(t, err)
+	p = markAllShardsAsAvailable(t, p)
+
+	// Operations with default options keep following the recorded policy.
+	a := newShardedAlgorithm(placement.NewOptions())
+	p, err = a.AddInstances(p, []placement.Instance{newInstance("z1", "r3", 1)})
+	assert.NoError(t, err)
+	assert.Equal(t, policy, p.FailureDomainPolicy())
+	assert.NoError(t, placement.Validate(p))
+	validateZoneSpread(t, p, 1)
+	p = markAllShardsAsAvailable(t, p)
+
+	p, err = a.RemoveInstances(p, []string{"z2-r1-i1"})
+	assert.NoError(t, err)
+	assert.Equal(t, policy, p.FailureDomainPolicy())
+	assert.NoError(t, placement.Validate(p))
+	validateZoneSpread(t, p, 1)
+}
+
+func TestFailureDomainPolicyNotEnoughDomains(t *testing.T) {
+	instances := []placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1).SetHostname("h1"),
+		placement.NewEmptyInstance("i2", "r1", "z1", "endpoint", 1).SetHostname("h1"),
+		placement.NewEmptyInstance("i3", "r2", "z1", "endpoint", 1).SetHostname("h2"),
+	}
+
+	opts := placement.NewOptions().
+		SetFailureDomainPolicy(placement.NewFailureDomainPolicy(placement.RackLevel, placement.HostLevel))
+	_, err := newShardedAlgorithm(opts).InitialPlacement(instances, []uint32{0, 1, 2, 3}, 3)
+	assert.Equal(t, errNotEnoughRacks, err)
+
+	// The policy can not be loosened by the rack check option.
+	_, err = newShardedAlgorithm(opts.SetLooseRackCheck(true)).InitialPlacement(instances, []uint32{0, 1, 2, 3}, 3)
+	assert.Equal(t, errNotEnoughRacks, err)
+}
+
+func TestRebalance(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i3 := placement.NewEmptyInstance("i3", "r2", "z1", "endpoint", 1)
+	for id := uint32(0); id < 4; id++ {
+		i1.Shards().Add(shard.NewShard(id).SetState(shard.Available))
+		i2.Shards().Add(shard.NewShard(id).SetState(shard.Available))
+	}
+	i3.Shards().Add(shard.NewShard(4).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(4).SetState(shard.Available))
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2, i3}).
+		SetShards([]uint32{0, 1, 2, 3, 4}).
+		SetReplicaFactor(2).
+		SetIsSharded(true)
+	assert.NoError(t, placement.Validate(p))
+
+	a := newShardedAlgorithm(placement.NewOptions())
+	p1, err := a.Rebalance(p, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p1))
+	instance, _ := p1.Instance("i2")
+	assert.Equal(t, 1, instance.Shards().NumShardsForState(shard.Leaving))
+	instance, _ = p1.Instance("i3")
+	assert.Equal(t, 1, instance.Shards().NumShardsForState(shard.Initializing))
+
+	// The shards on r1 can not move to r2 where their other replicas are,
+	// so the load on r2 is balanced between i2 and i3 only.
+	p2, err := a.Rebalance(p, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p2))
+	p2 = markAllShardsAsAvailable(t, p2)
+	instance, _ = p2.Instance("i1")
+	assert.Equal(t, 5, loadOnInstance(instance))
+	instance, _ = p2.Instance("i2")
+	assert.Equal(t, 3, loadOnInstance(instance))
+	instance, _ = p2.Instance("i3")
+	assert.Equal(t, 2, loadOnInstance(instance))
+	validateZoneSpread(t, p2, 2)
+
+	// The input placement is not modified.
+	instance, _ = p.Instance("i2")
+	assert.Equal(t, 4, instance.Shards().NumShardsForState(shard.Available))
+}
+
+func TestRemoveReplica(t *testing.T) {
+	instances := []placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i2", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i3", "r2", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i4", "r2", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i5", "r3", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i6", "r3", "z1", "endpoint", 1),
+	}
+	ids := make([]uint32, 24)
+	for i := range ids {
+		ids[i] = uint32(i)
+	}
+
+	opts := placement.NewOptions().
+		SetPlacementCutoverNanosFn(timeNanosGen(1)).
+		SetShardCutoverNanosFn(timeNanosGen(2)).
+		SetShardCutoffNanosFn(timeNanosGen(3))
+	a := newShardedAlgorithm(opts)
+	p, err := a.InitialPlacement(instances, ids, 3)
+	assert.NoError(t, err)
+	p = markAllShardsAsAvailable(t, p)
+
+	p1, err := a.RemoveReplica(p)
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p1))
+	assert.Equal(t, 2, p1.ReplicaFactor())
+	validateCutoverCutoffNanos(t, p1, opts)
+	for _, id := range ids {
+		numLeaving := 0
+		for _, instance := range p1.InstancesForShard(id) {
+			if s, _ := instance.Shards().Shard(id); s.State() == shard.Leaving {
+				numLeaving++
+			}
+		}
+		assert.Equal(t, 1, numLeaving)
+	}
+	for _, instance := range p1.Instances() {
+		assert.Equal(t, 8, loadOnInstance(instance))
+		assert.Equal(t, 0, instance.Shards().NumShardsForState(shard.Initializing))
+	}
+
+	// Marking the shards available keeps the dropped replicas until they are removed.
+	p1 = markAllShardsAsAvailable(t, p1)
+	assert.NoError(t, placement.Validate(p1))
+	assert.Equal(t, 24, numShardsInState(p1, shard.Leaving))
+
+	p1 = removeAllDroppedReplicas(t, p1)
+	assert.NoError(t, placement.Validate(p1))
+	for _, instance := range p1.Instances() {
+		assert.Equal(t, 8, instance.Shards().NumShardsForState(shard.Available))
+	}
+	validateZoneSpread(t, p1, 2)
+
+	p2, err := a.RemoveReplica(p1)
+	assert.NoError(t, err)
+	p2 = removeAllDroppedReplicas(t, markAllShardsAsAvailable(t, p2))
+	assert.NoError(t, placement.Validate(p2))
+	for _, instance := range p2.Instances() {
+		assert.Equal(t, 4, instance.Shards().NumShards())
+	}
+
+	_, err = a.RemoveReplica(p2)
+	assert.Equal(t, errNoReplicaToRemove, err)
+
+	// The input placement is not modified.
+	assert.Equal(t, 3, p.ReplicaFactor())
+	for _, instance := range p.Instances() {
+		assert.Equal(t, 12, instance.Shards().NumShardsForState(shard.Available))
+	}
+}
+
+func TestRemoveReplicaPreferCrowdedDomains(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r1", "z1", "endpoint", 1)
+	i3 := placement.NewEmptyInstance("i3", "r2", "z1", "endpoint", 1)
+	i4 := placement.NewEmptyInstance("i4", "r3", "z1", "endpoint", 1)
+	for _, instance := range []placement.Instance{i1, i2, i3} {
+		instance.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	}
+	for _, instance := range []placement.Instance{i2, i3, i4} {
+		instance.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	}
+	for _, instance := range []placement.Instance{i1, i4} {
+		instance.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+	}
+	i3.Shards().Add(shard.NewShard(2).SetState(shard.Initializing))
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2, i3, i4}).
+		SetShards([]uint32{0, 1, 2}).
+		SetReplicaFactor(3).
+		SetIsSharded(true)
+	assert.NoError(t, placement.Validate(p))
+
+	a := newShardedAlgorithm(placement.NewOptions().SetLooseRackCheck(true))
+	p, err := a.RemoveReplica(p)
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+
+	// Shard 0 is dropped from r1 where two of its replicas are, shard 2 drops its
+	// Initializing replica, and shard 1 is dropped from the most loaded instance.
+	i1, _ = p.Instance("i1")
+	i2, _ = p.Instance("i2")
+	i3, _ = p.Instance("i3")
+	s, _ := i1.Shards().Shard(0)
+	assert.Equal(t, shard.Leaving, s.State())
+	s, _ = i2.Shards().Shard(0)
+	assert.Equal(t, shard.Available, s.State())
+	_, ok := i3.Shards().Shard(2)
+	assert.False(t, ok)
+	s, _ = i3.Shards().Shard(1)
+	assert.Equal(t, shard.Leaving, s.State())
+}
+
+func TestSplitShards(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i3 := placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Leaving).SetCutoffNanos(100))
+	i2.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i2.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i2.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+	i3.Shards().Add(shard.NewShard(1).SetState(shard.Initializing).SetSourceID("i1").SetCutoverNanos(200))
+	i3.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2, i3}).
+		SetShards([]uint32{0, 1, 2}).
+		SetReplicaFactor(2).
+		SetIsSharded(true)
+	assert.NoError(t, placement.Validate(p))
+
+	opts := placement.NewOptions().
+		SetPlacementCutoverNanosFn(timeNanosGen(1)).
+		SetShardCutoverNanosFn(timeNanosGen(2))
+	a := newShardedAlgorithm(opts)
+	_, err := a.SplitShards(p, 1)
+	assert.Equal(t, errInvalidShardSplitFactor, err)
+
+	p1, err := a.SplitShards(p, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p1))
+	assert.Equal(t, []uint32{0, 1, 2, 3, 4, 5}, p1.Shards())
+	assert.Equal(t, map[uint32][]uint32{0: {0, 3}, 1: {1, 4}, 2: {2, 5}}, p1.ShardSplits())
+	assert.Equal(t, int64(1), p1.CutoverNanos())
+
+	// The child shards stay on the instances owning their parents.
+	instanceIDs := func(instances []placement.Instance) []string {
+		ids := make([]string, 0, len(instances))
+		for _, instance := range instances {
+			ids = append(ids, instance.ID())
+		}
+		return ids
+	}
+	for parent, children := range p1.ShardSplits() {
+		for _, child := range children {
+			assert.Equal(t, instanceIDs(p.InstancesForShard(parent)), instanceIDs(p1.InstancesForShard(child)))
+		}
+	}
+	i1, _ = p1.Instance("i1")
+	s, _ := i1.Shards().Shard(3)
+	assert.Equal(t, shard.Initializing, s.State())
+	assert.Equal(t, "", s.SourceID())
+	assert.Equal(t, int64(2), s.CutoverNanos())
+	s, _ = i1.Shards().Shard(4)
+	assert.Equal(t, shard.Leaving, s.State())
+	assert.Equal(t, int64(100), s.CutoffNanos())
+	i3, _ = p1.Instance("i3")
+	s, _ = i3.Shards().Shard(4)
+	assert.Equal(t, shard.Initializing, s.State())
+	assert.Equal(t, "i1", s.SourceID())
+	assert.Equal(t, int64(200), s.CutoverNanos())
+
+	// The handoff of the parent shard completes along with its children.
+	p1 = markAllShardsAsAvailable(t, p1)
+	assert.NoError(t, placement.Validate(p1))
+	i1, _ = p1.Instance("i1")
+	assert.Equal(t, []uint32{0, 3}, i1.Shards().AllIDs())
+
+	// The input placement is not modified.
+	assert.Equal(t, []uint32{0, 1, 2}, p.Shards())
+	assert.Nil(t, p.ShardSplits())
+}
+
+func TestSplitShardsKeptByOperations(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i3 := placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1)
+	i4 := placement.NewEmptyInstance("i4", "r4", "z1", "endpoint", 1)
+
+	a := newShardedAlgorithm(placement.NewOptions())
+	p, err := a.InitialPlacement([]placement.Instance{i1, i2, i3}, []uint32{0, 1, 2, 3}, 2)
+	assert.NoError(t, err)
+	p = markAllShardsAsAvailable(t, p)
+
+	p, err = a.SplitShards(p, 2)
+	assert.NoError(t, err)
+	splits := p.ShardSplits()
+	assert.Equal(t, map[uint32][]uint32{0: {0, 4}, 1: {1, 5}, 2: {2, 6}, 3: {3, 7}}, splits)
+
+	// The mapping survives the operations while the child shards are Initializing.
+	p, err = a.AddInstances(p, []placement.Instance{i4})
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	assert.Equal(t, splits, p.ShardSplits())
+
+	p, err = a.Rebalance(p, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, splits, p.ShardSplits())
+
+	// Another split is refused until the previous one completes.
+	_, err = a.SplitShards(p, 2)
+	assert.Equal(t, errShardSplitInProgress, err)
+
+	p = markAllShardsAsAvailable(t, p)
+	p, err = a.RemoveInstances(p, []string{"i4"})
+	assert.NoError(t, err)
+	assert.Equal(t, splits, p.ShardSplits())
+
+	p = markAllShardsAsAvailable(t, p)
+	p, err = a.SplitShards(p, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{0, 8}, p.ShardSplits()[0])
+}
+
+func TestUpdateInstanceWeights(t *testing.T) {
+	instances := []placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1),
+	}
+	ids := make([]uint32, 30)
+	for i := range ids {
+		ids[i] = uint32(i)
+	}
+
+	a := newShardedAlgorithm(placement.NewOptions())
+	p, err := a.InitialPlacement(instances, ids, 1)
+	assert.NoError(t, err)
+	p = markAllShardsAsAvailable(t, p)
+
+	_, err = a.UpdateInstanceWeights(p, map[string]uint32{"i4": 2})
+	assert.Error(t, err)
+	_, err = a.UpdateInstanceWeights(p, map[string]uint32{"i1": 0})
+	assert.Error(t, err)
+
+	// The target load of i1 becomes 15, which takes 5 shards from the others.
+	p, err = a.UpdateInstanceWeights(p, map[string]uint32{"i1": 2})
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	i1, ok := p.Instance("i1")
+	assert.True(t, ok)
+	assert.Equal(t, uint32(2), i1.Weight())
+	assert.Equal(t, 5, i1.Shards().NumShardsForState(shard.Initializing))
+	numLeaving := 0
+	for _, instance := range p.Instances() {
+		numLeaving += instance.Shards().NumShardsForState(shard.Leaving)
+	}
+	assert.Equal(t, 5, numLeaving)
+
+	p = markAllShardsAsAvailable(t, p)
+	i1, _ = p.Instance("i1")
+	assert.Equal(t, 15, loadOnInstance(i1))
+
+	// Lowering the weight back moves the shards off i1 again.
+	p, err = a.UpdateInstanceWeights(p, map[string]uint32{"i1": 1})
+	assert.NoError(t, err)
+	p = markAllShardsAsAvailable(t, p)
+	for _, instance := range p.Instances() {
+		assert.Equal(t, 10, loadOnInstance(instance))
+	}
+}
+
+// validateFailureDomainSpread checks that no region owns more than maxPerRegion
+// replicas of any shard, and no zone in a region owns more than maxPerZone.
+func validateFailureDomainSpread(t *testing.T, p placement.Placement, maxPerRegion, maxPerZone int) {
+	for _, id := range p.Shards() {
+		regions := make(map[string]int)
+		zones := make(map[string]int)
+		for _, i := range p.InstancesForShard(id) {
+			if s, ok := i.Shards().Shard(id); !ok || s.State() == shard.Leaving {
+				continue
+			}
+			region := i.FailureDomain(placement.RegionLevel)
+			regions[region]++
+			zones[region+"/"+i.Zone()]++
+		}
+		for region, count := range regions {
+			assert.True(t, count <= maxPerRegion, fmt.Sprintf("shard %d has %d replicas in region %s", id, count, region))
+		}
+		for zone, count := range zones {
+			assert.True(t, count <= maxPerZone, fmt.Sprintf("shard %d has %d replicas in zone %s", id, count, zone))
+		}
+	}
+}
+
+// validateZoneSpread checks that no zone owns more than maxPerZone replicas
+// of any shard, and that no rack in a zone owns more than one
+func validateZoneSpread(t *testing.T, p placement.Placement, maxPerZone int) {
+	for _, id := range p.Shards() {
+		zones := make(map[string]int)
+		racks := make(map[string]struct{})
+		for _, i := range p.InstancesForShard(id) {
+			if s, ok := i.Shards().Shard(id); !ok || s.State() == shard.Leaving {
+				continue
+			}
+			zones[i.Zone()]++
+			rack := i.Zone() + "/" + i.Rack()
+			_, exist := racks[rack]
+			assert.False(t, exist, fmt.Sprintf("shard %d has more than one replica on rack %s", id, rack))
+			racks[rack] = struct{}{}
+		}
+		for zone, count := range zones {
+			assert.True(t, count <= maxPerZone, fmt.Sprintf("shard %d has %d replicas in zone %s", id, count, zone))
+		}
+	}
+}
+
 func markAllShardsAsAvailable(t *testing.T, p placement.Placement) placement.Placement {
 	p, err := placement.MarkAllShardsAsAvailable(p)
 	assert.NoError(t, err)
 	return p
 }
 
+func removeAllDroppedReplicas(t *testing.T, p placement.Placement) placement.Placement {
+	p, err := placement.RemoveAllDroppedReplicas(p)
+	assert.NoError(t, err)
+	return p
+}
+
 func validateCutoverCutoffNanos(t *testing.T, p placement.Placement, opts placement.Options) {
 	for _, i := range p.Instances() {
 		for _, s := range i.Shards().All() {
@@ -1164,7 +1712,7 @@ func validateDistribution(t *testing.T, p placement.Placement, expectPeakOverAvg
 				testCase, i.ID(), instanceOverAvg, expectPeakOverAvg, load, avgLoad))
 		}
 
-		targetLoad := ph.targetLoadForInstance(i.ID())
+		targetLoad := ph.TargetLoadForInstance(i.ID())
 		if targetLoad == 0 {
 			continue
 		}
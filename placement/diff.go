@@ -0,0 +1,345 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package placement
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/m3db/m3cluster/shard"
+)
+
+// PlacementDiff describes the differences between two placements.
+type PlacementDiff struct {
+	// Instances are the per-instance differences, sorted by instance id.
+	Instances []InstanceDiff `json:"instances"`
+
+	// Moves are the per-shard changes of ownership, sorted by shard id.
+	Moves []ShardMove `json:"moves"`
+
+	// Summary counts the differences.
+	Summary DiffSummary `json:"summary"`
+}
+
+// InstanceDiff describes the differences in the shards owned by an instance.
+type InstanceDiff struct {
+	// InstanceID is the id of the instance.
+	InstanceID string `json:"instanceID"`
+
+	// IsAdded is true if the instance is only in the new placement.
+	IsAdded bool `json:"isAdded,omitempty"`
+
+	// IsRemoved is true if the instance is only in the old placement.
+	IsRemoved bool `json:"isRemoved,omitempty"`
+
+	// AddedShards are the shards only on the instance in the new placement.
+	AddedShards []ShardStateChange `json:"addedShards,omitempty"`
+
+	// RemovedShards are the shards only on the instance in the old placement.
+	RemovedShards []ShardStateChange `json:"removedShards,omitempty"`
+
+	// StateChanges are the shards on the instance in both placements whose
+	// state has changed.
+	StateChanges []ShardStateChange `json:"stateChanges,omitempty"`
+}
+
+// ShardStateChange describes the change in state of a shard on an instance,
+// with Unknown standing for a shard the instance does not own.
+type ShardStateChange struct {
+	ShardID uint32
+	From    shard.State
+	To      shard.State
+}
+
+// MarshalJSON marshals the change with readable shard states.
+func (c ShardStateChange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ShardID uint32 `json:"shardID"`
+		From    string `json:"from"`
+		To      string `json:"to"`
+	}{c.ShardID, c.From.String(), c.To.String()})
+}
+
+// ShardMove describes a replica of a shard moving between instances. From is
+// empty for a replica that is only added, and To is empty for a replica that
+// is only removed.
+type ShardMove struct {
+	ShardID uint32 `json:"shardID"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+}
+
+// DiffSummary counts the differences between two placements.
+type DiffSummary struct {
+	InstancesAdded   int `json:"instancesAdded"`
+	InstancesRemoved int `json:"instancesRemoved"`
+	ShardsAdded      int `json:"shardsAdded"`
+	ShardsRemoved    int `json:"shardsRemoved"`
+	StateChanges     int `json:"stateChanges"`
+	Moves            int `json:"moves"`
+}
+
+// NewPlacementDiff compares two placements. A replica of a shard is considered
+// moved when an instance stops owning it, either by dropping the shard or by
+// marking it Leaving, and another instance starts owning it.
+func NewPlacementDiff(from, to Placement) PlacementDiff {
+	var d PlacementDiff
+	for _, id := range unionInstanceIDs(from, to) {
+		instanceDiff := diffInstance(id, from, to)
+		if instanceDiff.isEmpty() {
+			continue
+		}
+
+		d.Instances = append(d.Instances, instanceDiff)
+		if instanceDiff.IsAdded {
+			d.Summary.InstancesAdded++
+		}
+		if instanceDiff.IsRemoved {
+			d.Summary.InstancesRemoved++
+		}
+		d.Summary.ShardsAdded += len(instanceDiff.AddedShards)
+		d.Summary.ShardsRemoved += len(instanceDiff.RemovedShards)
+		d.Summary.StateChanges += len(instanceDiff.StateChanges)
+	}
+
+	for _, shardID := range unionShardIDs(from, to) {
+		d.Moves = append(d.Moves, diffOwners(shardID, from, to)...)
+	}
+	d.Summary.Moves = len(d.Moves)
+	return d
+}
+
+// IsEmpty returns true if the placements own the same shards in the same
+// states.
+func (d PlacementDiff) IsEmpty() bool {
+	return len(d.Instances) == 0 && len(d.Moves) == 0
+}
+
+// String renders the diff as text for review.
+func (d PlacementDiff) String() string {
+	var buf bytes.Buffer
+	s := d.Summary
+	fmt.Fprintf(&buf, "instances: %d added, %d removed; shards: %d added, %d removed, %d state changes; %d moves\n",
+		s.InstancesAdded, s.InstancesRemoved, s.ShardsAdded, s.ShardsRemoved, s.StateChanges, s.Moves)
+
+	for _, instanceDiff := range d.Instances {
+		switch {
+		case instanceDiff.IsAdded:
+			fmt.Fprintf(&buf, "instance %s (added):\n", instanceDiff.InstanceID)
+		case instanceDiff.IsRemoved:
+			fmt.Fprintf(&buf, "instance %s (removed):\n", instanceDiff.InstanceID)
+		default:
+			fmt.Fprintf(&buf, "instance %s:\n", instanceDiff.InstanceID)
+		}
+		for _, c := range instanceDiff.AddedShards {
+			fmt.Fprintf(&buf, "  + shard %d (%v)\n", c.ShardID, c.To)
+		}
+		for _, c := range instanceDiff.RemovedShards {
+			fmt.Fprintf(&buf, "  - shard %d (%v)\n", c.ShardID, c.From)
+		}
+		for _, c := range instanceDiff.StateChanges {
+			fmt.Fprintf(&buf, "  ~ shard %d %v -> %v\n", c.ShardID, c.From, c.To)
+		}
+	}
+
+	if len(d.Moves) > 0 {
+		buf.WriteString("moves:\n")
+	}
+	for _, m := range d.Moves {
+		from, to := m.From, m.To
+		if from == "" {
+			from = "(none)"
+		}
+		if to == "" {
+			to = "(none)"
+		}
+		fmt.Fprintf(&buf, "  shard %d: %s -> %s\n", m.ShardID, from, to)
+	}
+	return buf.String()
+}
+
+// JSON renders the diff as JSON for review.
+func (d PlacementDiff) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+func (d InstanceDiff) isEmpty() bool {
+	return !d.IsAdded && !d.IsRemoved &&
+		len(d.AddedShards) == 0 && len(d.RemovedShards) == 0 && len(d.StateChanges) == 0
+}
+
+func diffInstance(id string, from, to Placement) InstanceDiff {
+	var (
+		d                    = InstanceDiff{InstanceID: id}
+		oldShards, newShards = shard.NewShards(nil), shard.NewShards(nil)
+	)
+	if instance, ok := from.Instance(id); ok {
+		oldShards = instance.Shards()
+	} else {
+		d.IsAdded = true
+	}
+	if instance, ok := to.Instance(id); ok {
+		newShards = instance.Shards()
+	} else {
+		d.IsRemoved = true
+	}
+
+	for _, s := range newShards.All() {
+		old, ok := oldShards.Shard(s.ID())
+		if !ok {
+			d.AddedShards = append(d.AddedShards, ShardStateChange{ShardID: s.ID(), From: shard.Unknown, To: s.State()})
+			continue
+		}
+		if old.State() != s.State() {
+			d.StateChanges = append(d.StateChanges, ShardStateChange{ShardID: s.ID(), From: old.State(), To: s.State()})
+		}
+	}
+	for _, s := range oldShards.All() {
+		if !newShards.Contains(s.ID()) {
+			d.RemovedShards = append(d.RemovedShards, ShardStateChange{ShardID: s.ID(), From: s.State(), To: shard.Unknown})
+		}
+	}
+	return d
+}
+
+// diffOwners pairs the instances that stopped owning a shard with the
+// instances that started owning it, preferring the source recorded on the
+// new replica.
+func diffOwners(shardID uint32, from, to Placement) []ShardMove {
+	oldOwners, newOwners := owners(from, shardID), owners(to, shardID)
+
+	var lost []string
+	for _, id := range sortedOwnerIDs(oldOwners) {
+		if _, ok := newOwners[id]; !ok {
+			lost = append(lost, id)
+		}
+	}
+
+	var (
+		moves    []ShardMove
+		unpaired []string
+	)
+	for _, id := range sortedOwnerIDs(newOwners) {
+		if _, ok := oldOwners[id]; ok {
+			continue
+		}
+		sourceID := newOwners[id].SourceID()
+		if idx := indexOf(lost, sourceID); idx >= 0 {
+			moves = append(moves, ShardMove{ShardID: shardID, From: sourceID, To: id})
+			lost = append(lost[:idx], lost[idx+1:]...)
+			continue
+		}
+		unpaired = append(unpaired, id)
+	}
+
+	for _, id := range unpaired {
+		m := ShardMove{ShardID: shardID, To: id}
+		if len(lost) > 0 {
+			m.From, lost = lost[0], lost[1:]
+		}
+		moves = append(moves, m)
+	}
+	for _, id := range lost {
+		moves = append(moves, ShardMove{ShardID: shardID, From: id})
+	}
+
+	sort.Sort(shardMovesByInstances(moves))
+	return moves
+}
+
+// owners returns the replicas of a shard that are not leaving, by instance id.
+func owners(p Placement, shardID uint32) map[string]shard.Shard {
+	res := make(map[string]shard.Shard)
+	for _, instance := range p.InstancesForShard(shardID) {
+		s, ok := instance.Shards().Shard(shardID)
+		if !ok || s.State() == shard.Leaving {
+			continue
+		}
+		res[instance.ID()] = s
+	}
+	return res
+}
+
+func sortedOwnerIDs(owners map[string]shard.Shard) []string {
+	ids := make([]string, 0, len(owners))
+	for id := range owners {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func indexOf(ids []string, id string) int {
+	if id == "" {
+		return -1
+	}
+	for i, other := range ids {
+		if other == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func unionInstanceIDs(from, to Placement) []string {
+	seen := make(map[string]struct{}, from.NumInstances())
+	var ids []string
+	for _, p := range []Placement{from, to} {
+		for _, instance := range p.Instances() {
+			if _, ok := seen[instance.ID()]; ok {
+				continue
+			}
+			seen[instance.ID()] = struct{}{}
+			ids = append(ids, instance.ID())
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func unionShardIDs(from, to Placement) []uint32 {
+	seen := make(map[uint32]struct{}, from.NumShards())
+	var ids []uint32
+	for _, p := range []Placement{from, to} {
+		for _, id := range p.Shards() {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(shard.SortableIDsAsc(ids))
+	return ids
+}
+
+type shardMovesByInstances []ShardMove
+
+func (s shardMovesByInstances) Len() int      { return len(s) }
+func (s shardMovesByInstances) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s shardMovesByInstances) Less(i, j int) bool {
+	if s[i].From != s[j].From {
+		return s[i].From < s[j].From
+	}
+	return s[i].To < s[j].To
+}
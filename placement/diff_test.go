@@ -0,0 +1,101 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package placement
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/m3db/m3cluster/shard"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlacementDiff(t *testing.T) {
+	i1 := NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i2 := NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i2.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+	i2.Shards().Add(shard.NewShard(3).SetState(shard.Available))
+	from := NewPlacement().
+		SetInstances([]Instance{i1, i2}).
+		SetShards([]uint32{0, 1, 2, 3}).
+		SetReplicaFactor(1).
+		SetIsSharded(true)
+
+	to := from.Clone()
+	i1, _ = to.Instance("i1")
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Leaving))
+	i3 := NewEmptyInstance("i3", "r3", "z1", "endpoint", 1)
+	i3.Shards().Add(shard.NewShard(1).SetState(shard.Initializing).SetSourceID("i1"))
+	to = to.SetInstances(append(to.Instances(), i3))
+	require.NoError(t, Validate(to))
+
+	d := NewPlacementDiff(from, to)
+	assert.Equal(t, []InstanceDiff{
+		{
+			InstanceID:   "i1",
+			StateChanges: []ShardStateChange{{ShardID: 1, From: shard.Available, To: shard.Leaving}},
+		},
+		{
+			InstanceID:  "i3",
+			IsAdded:     true,
+			AddedShards: []ShardStateChange{{ShardID: 1, From: shard.Unknown, To: shard.Initializing}},
+		},
+	}, d.Instances)
+	assert.Equal(t, []ShardMove{{ShardID: 1, From: "i1", To: "i3"}}, d.Moves)
+	assert.Equal(t, DiffSummary{InstancesAdded: 1, ShardsAdded: 1, StateChanges: 1, Moves: 1}, d.Summary)
+	assert.Equal(t, `instances: 1 added, 0 removed; shards: 1 added, 0 removed, 1 state changes; 1 moves
+instance i1:
+  ~ shard 1 Available -> Leaving
+instance i3 (added):
+  + shard 1 (Initializing)
+moves:
+  shard 1: i1 -> i3
+`, d.String())
+
+	b, err := d.JSON()
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	stateChange := decoded["instances"].([]interface{})[0].(map[string]interface{})["stateChanges"].([]interface{})[0]
+	assert.Equal(t, map[string]interface{}{"shardID": float64(1), "from": "Available", "to": "Leaving"}, stateChange)
+
+	// Completing the handoff does not move the shard again
+	done, err := MarkAllShardsAsAvailable(to)
+	require.NoError(t, err)
+	d = NewPlacementDiff(to, done)
+	assert.Empty(t, d.Moves)
+	assert.Equal(t, DiffSummary{ShardsRemoved: 1, StateChanges: 1}, d.Summary)
+
+	// Moves without a source are paired up in instance order
+	i4 := NewEmptyInstance("i4", "r4", "z1", "endpoint", 1)
+	i4.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+	i4.Shards().Add(shard.NewShard(3).SetState(shard.Available))
+	replaced := done.Clone().SetInstances(append(RemoveInstanceFromList(done.Clone().Instances(), "i2"), i4))
+	d = NewPlacementDiff(done, replaced)
+	assert.Equal(t, []ShardMove{{ShardID: 2, From: "i2", To: "i4"}, {ShardID: 3, From: "i2", To: "i4"}}, d.Moves)
+	assert.Equal(t, DiffSummary{InstancesAdded: 1, InstancesRemoved: 1, ShardsAdded: 2, ShardsRemoved: 2, Moves: 2}, d.Summary)
+
+	assert.True(t, NewPlacementDiff(done, done.Clone()).IsEmpty())
+}
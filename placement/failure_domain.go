@@ -0,0 +1,169 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package placement
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// RegionLevel is the failure domain level of regions.
+	RegionLevel = "region"
+
+	// ZoneLevel is the failure domain level of zones, which defaults to
+	// the zone of the instance.
+	ZoneLevel = "zone"
+
+	// RackLevel is the failure domain level of racks, which defaults to
+	// the rack of the instance.
+	RackLevel = "rack"
+
+	// HostLevel is the failure domain level of hosts, which defaults to
+	// the hostname of the instance.
+	HostLevel = "host"
+
+	domainSeparator = "/"
+)
+
+// FailureDomainPolicy describes the ordered failure domain levels, from the
+// outermost (e.g. region) to the innermost (e.g. host), that the replicas of
+// each shard are spread across.
+//
+// At the innermost level no two replicas of a shard may share a domain, at
+// every other level the replicas are spread as evenly as possible, so that no
+// domain holds more than ceil(rf / number of domains at that level) replicas
+// of a shard.
+type FailureDomainPolicy struct {
+	levels []string
+}
+
+// NewFailureDomainPolicy creates a FailureDomainPolicy from the levels
+// ordered from the outermost to the innermost.
+func NewFailureDomainPolicy(levels ...string) FailureDomainPolicy {
+	return FailureDomainPolicy{levels: levels}
+}
+
+// PolicyForOptions returns the failure domain policy that placements built
+// with the options follow. Unless a policy is set explicitly, replicas are
+// spread across zones then racks if SpreadAcrossZones is set, or across racks
+// otherwise.
+func PolicyForOptions(opts Options) FailureDomainPolicy {
+	if policy := opts.FailureDomainPolicy(); !policy.IsEmpty() {
+		return policy
+	}
+	if opts.SpreadAcrossZones() {
+		return NewFailureDomainPolicy(ZoneLevel, RackLevel)
+	}
+	return NewFailureDomainPolicy(RackLevel)
+}
+
+// OptionsWithPlacementPolicy returns the options with the failure domain policy
+// recorded in the placement unless the options set a policy explicitly, so the
+// placement keeps following the policy it was built with.
+func OptionsWithPlacementPolicy(p Placement, opts Options) Options {
+	if !opts.FailureDomainPolicy().IsEmpty() || p.FailureDomainPolicy().IsEmpty() {
+		return opts
+	}
+	return opts.SetFailureDomainPolicy(p.FailureDomainPolicy())
+}
+
+// Levels returns the levels of the policy from the outermost to the innermost.
+func (p FailureDomainPolicy) Levels() []string {
+	return p.levels
+}
+
+// NumLevels returns the number of levels in the policy.
+func (p FailureDomainPolicy) NumLevels() int {
+	return len(p.levels)
+}
+
+// IsEmpty returns whether the policy has no levels.
+func (p FailureDomainPolicy) IsEmpty() bool {
+	return len(p.levels) == 0
+}
+
+// HasLevel returns whether the policy contains the given level.
+func (p FailureDomainPolicy) HasLevel(level string) bool {
+	for _, l := range p.levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// Domain returns the domain of the instance at the level with the given
+// index, qualified by the domains of the instance at all the outer levels.
+func (p FailureDomainPolicy) Domain(instance Instance, level int) string {
+	parts := make([]string, level+1)
+	for i := 0; i <= level; i++ {
+		parts[i] = instance.FailureDomain(p.levels[i])
+	}
+	return strings.Join(parts, domainSeparator)
+}
+
+// NumDomains returns the number of distinct domains at each level among the
+// given instances.
+func (p FailureDomainPolicy) NumDomains(instances []Instance) []int {
+	res := make([]int, len(p.levels))
+	for level := range p.levels {
+		domains := make(map[string]struct{}, len(instances))
+		for _, instance := range instances {
+			domains[p.Domain(instance, level)] = struct{}{}
+		}
+		res[level] = len(domains)
+	}
+	return res
+}
+
+// MaxReplicas returns the maximum number of replicas of a shard allowed in
+// a single domain at the level with the given index.
+func (p FailureDomainPolicy) MaxReplicas(level, rf, numDomains int) int {
+	if level == len(p.levels)-1 || numDomains <= 0 {
+		return 1
+	}
+	return (rf + numDomains - 1) / numDomains
+}
+
+// Check checks whether the replicas of a shard satisfy the policy, where
+// numDomains is the number of domains available at each level.
+func (p FailureDomainPolicy) Check(replicas []Instance, rf int, numDomains []int) error {
+	for level := range p.levels {
+		maxReplicas := p.MaxReplicas(level, rf, numDomains[level])
+		counts := make(map[string]int, len(replicas))
+		for _, replica := range replicas {
+			domain := p.Domain(replica, level)
+			counts[domain]++
+			if counts[domain] > maxReplicas {
+				return fmt.Errorf(
+					"%d replicas in %s %s, expecting at most %d",
+					counts[domain], p.levels[level], domain, maxReplicas,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+func (p FailureDomainPolicy) String() string {
+	return "[" + strings.Join(p.levels, ", ") + "]"
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package placement
+
+import (
+	"testing"
+
+	"github.com/m3db/m3cluster/shard"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceFailureDomain(t *testing.T) {
+	i := NewInstance().
+		SetID("i1").
+		SetZone("z1").
+		SetRack("r1").
+		SetHostname("h1").
+		SetFailureDomains(map[string]string{RegionLevel: "us-east", RackLevel: "rack-a"})
+
+	assert.Equal(t, "us-east", i.FailureDomain(RegionLevel))
+	assert.Equal(t, "z1", i.FailureDomain(ZoneLevel))
+	assert.Equal(t, "rack-a", i.FailureDomain(RackLevel))
+	assert.Equal(t, "h1", i.FailureDomain(HostLevel))
+	assert.Equal(t, "", i.FailureDomain("pod"))
+
+	cloned := i.Clone()
+	cloned.FailureDomains()[RegionLevel] = "us-west"
+	assert.Equal(t, "us-east", i.FailureDomain(RegionLevel))
+
+	pi, err := i.Proto()
+	require.NoError(t, err)
+	assert.Equal(t, i.FailureDomains(), pi.FailureDomains)
+	fromProto, err := NewInstanceFromProto(pi)
+	require.NoError(t, err)
+	assert.Equal(t, i.FailureDomains(), fromProto.FailureDomains())
+}
+
+func TestFailureDomainPolicy(t *testing.T) {
+	policy := NewFailureDomainPolicy(RegionLevel, ZoneLevel, RackLevel)
+	assert.False(t, policy.IsEmpty())
+	assert.Equal(t, 3, policy.NumLevels())
+	assert.True(t, policy.HasLevel(ZoneLevel))
+	assert.False(t, policy.HasLevel(HostLevel))
+	assert.Equal(t, "[region, zone, rack]", policy.String())
+
+	newInstance := func(id, region, zone, rack string) Instance {
+		return NewEmptyInstance(id, rack, zone, "e", 1).
+			SetFailureDomains(map[string]string{RegionLevel: region})
+	}
+	i1 := newInstance("i1", "re1", "z1", "r1")
+	i2 := newInstance("i2", "re1", "z1", "r2")
+	i3 := newInstance("i3", "re1", "z2", "r1")
+	i4 := newInstance("i4", "re2", "z1", "r1")
+
+	assert.Equal(t, "re1", policy.Domain(i1, 0))
+	assert.Equal(t, "re1/z1", policy.Domain(i1, 1))
+	assert.Equal(t, "re1/z1/r1", policy.Domain(i1, 2))
+	assert.Equal(t, "re2/z1/r1", policy.Domain(i4, 2))
+
+	numDomains := policy.NumDomains([]Instance{i1, i2, i3, i4})
+	assert.Equal(t, []int{2, 3, 4}, numDomains)
+
+	assert.Equal(t, 2, policy.MaxReplicas(0, 3, 2))
+	assert.Equal(t, 1, policy.MaxReplicas(1, 3, 3))
+	assert.Equal(t, 1, policy.MaxReplicas(2, 3, 1))
+
+	assert.NoError(t, policy.Check([]Instance{i1, i3, i4}, 3, numDomains))
+	assert.Error(t, policy.Check([]Instance{i1, i2, i4}, 3, numDomains))
+	assert.Error(t, policy.Check([]Instance{i1, i2, i3}, 3, numDomains))
+}
+
+func TestPolicyForOptions(t *testing.T) {
+	opts := NewOptions()
+	assert.True(t, opts.FailureDomainPolicy().IsEmpty())
+	assert.Equal(t, []string{RackLevel}, PolicyForOptions(opts).Levels())
+
+	opts = opts.SetSpreadAcrossZones(true)
+	assert.Equal(t, []string{ZoneLevel, RackLevel}, PolicyForOptions(opts).Levels())
+
+	opts = opts.SetFailureDomainPolicy(NewFailureDomainPolicy(RegionLevel, HostLevel))
+	assert.Equal(t, []string{RegionLevel, HostLevel}, PolicyForOptions(opts).Levels())
+}
+
+func TestValidateFailureDomainPolicy(t *testing.T) {
+	newPlacement := func(shardsByInstance map[string][]uint32) Placement {
+		instances := []Instance{
+			NewEmptyInstance("i1", "r1", "z1", "e1", 1),
+			NewEmptyInstance("i2", "r2", "z1", "e2", 1),
+			NewEmptyInstance("i3", "r1", "z2", "e3", 1),
+			NewEmptyInstance("i4", "r2", "z2", "e4", 1),
+		}
+		for _, instance := range instances {
+			for _, id := range shardsByInstance[instance.ID()] {
+				instance.Shards().Add(shard.NewShard(id).SetState(shard.Available))
+			}
+		}
+		return NewPlacement().
+			SetInstances(instances).
+			SetShards([]uint32{0, 1}).
+			SetReplicaFactor(2).
+			SetIsSharded(true).
+			SetFailureDomainPolicy(NewFailureDomainPolicy(ZoneLevel, RackLevel))
+	}
+
+	p := newPlacement(map[string][]uint32{"i1": {0}, "i2": {1}, "i3": {0}, "i4": {1}})
+	assert.NoError(t, Validate(p))
+
+	pp, err := p.Proto()
+	require.NoError(t, err)
+	assert.Equal(t, []string{ZoneLevel, RackLevel}, pp.FailureDomainLevels)
+	fromProto, err := NewPlacementFromProto(pp)
+	require.NoError(t, err)
+	assert.Equal(t, p.FailureDomainPolicy(), fromProto.FailureDomainPolicy())
+	assert.Equal(t, p.FailureDomainPolicy(), p.Clone().FailureDomainPolicy())
+
+	// Both replicas of shard 0 are in zone z1.
+	p = newPlacement(map[string][]uint32{"i1": {0}, "i2": {0}, "i3": {1}, "i4": {1}})
+	err = Validate(p)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "shard 0 violates failure domain policy [zone, rack]")
+
+	// Without a policy only the replica counts are validated.
+	assert.NoError(t, Validate(p.SetFailureDomainPolicy(FailureDomainPolicy{})))
+}
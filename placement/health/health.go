@@ -0,0 +1,329 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package health analyzes the health and balance of placements.
+package health
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/placement/algo"
+	"github.com/m3db/m3cluster/shard"
+)
+
+// Severity is the severity of a finding.
+type Severity int
+
+const (
+	// Info findings are worth knowing about but need no action.
+	Info Severity = iota
+	// Warning findings need attention but do not put data at risk.
+	Warning
+	// Critical findings put data or availability at risk.
+	Critical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Critical:
+		return "critical"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Check identifies the check that produced a finding.
+type Check string
+
+// List of checks.
+const (
+	// CheckValidity reports placements failing placement.ValidateWithOptions.
+	CheckValidity Check = "validity"
+	// CheckUnderReplicated reports shards with fewer non-leaving replicas than the replica factor.
+	CheckUnderReplicated Check = "under-replicated"
+	// CheckRackSpread reports shards with more than one replica on the same rack.
+	CheckRackSpread Check = "rack-spread"
+	// CheckZoneSpread reports shards whose replicas are not spread over the zones of the placement.
+	CheckZoneSpread Check = "zone-spread"
+	// CheckLoadSkew reports instances whose load is too far from their weighted target load.
+	CheckLoadSkew Check = "load-skew"
+	// CheckStuckInitializing reports shards Initializing for too long.
+	CheckStuckInitializing Check = "stuck-initializing"
+	// CheckStuckLeaving reports shards Leaving for too long.
+	CheckStuckLeaving Check = "stuck-leaving"
+	// CheckLeavingInstance reports instances whose shards are all Leaving.
+	CheckLeavingInstance Check = "leaving-instance"
+)
+
+// Finding is a single problem found in a placement.
+type Finding struct {
+	Check    Check
+	Severity Severity
+
+	// InstanceID is the instance the finding is about, if any.
+	InstanceID string
+
+	// ShardIDs are the shards the finding is about, if any.
+	ShardIDs []uint32
+
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%v] %s: %s", f.Severity, f.Check, f.Message)
+}
+
+// Report is the health report of a placement.
+type Report struct {
+	Findings []Finding
+}
+
+// MaxSeverity returns the highest severity of all the findings, and false if
+// there are no findings.
+func (r Report) MaxSeverity() (Severity, bool) {
+	if len(r.Findings) == 0 {
+		return Info, false
+	}
+	max := Info
+	for _, f := range r.Findings {
+		if f.Severity > max {
+			max = f.Severity
+		}
+	}
+	return max, true
+}
+
+// FindingsAtLeast returns the findings with at least the given severity.
+func (r Report) FindingsAtLeast(s Severity) []Finding {
+	var res []Finding
+	for _, f := range r.Findings {
+		if f.Severity >= s {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// IsHealthy returns true if there are no findings of Warning severity or above.
+func (r Report) IsHealthy() bool {
+	return len(r.FindingsAtLeast(Warning)) == 0
+}
+
+// Analyzer analyzes the health of placements.
+type Analyzer interface {
+	// Analyze returns the health report of the placement.
+	Analyze(p placement.Placement) Report
+}
+
+type analyzer struct {
+	opts Options
+}
+
+// NewAnalyzer returns a new placement health Analyzer.
+func NewAnalyzer(opts Options) Analyzer {
+	return analyzer{opts: opts}
+}
+
+func (a analyzer) Analyze(p placement.Placement) Report {
+	var r Report
+	if err := placement.ValidateWithOptions(p, a.opts.PlacementOptions()); err != nil {
+		r.add(Finding{Check: CheckValidity, Severity: Critical, Message: err.Error()})
+	}
+
+	if !p.IsSharded() {
+		return r
+	}
+
+	a.checkShards(p, &r)
+	a.checkLoad(p, &r)
+	a.checkInstances(p, &r)
+	return r
+}
+
+func (a analyzer) checkShards(p placement.Placement, r *Report) {
+	numZones := len(zones(p.Instances()))
+	var underReplicated, rackConflicts, zoneConflicts []uint32
+	for _, shardID := range p.Shards() {
+		owners := nonLeavingOwners(p, shardID)
+		if len(owners) < p.ReplicaFactor() {
+			underReplicated = append(underReplicated, shardID)
+		}
+
+		racks := make(map[string]struct{}, len(owners))
+		for _, instance := range owners {
+			racks[instance.Rack()] = struct{}{}
+		}
+		if len(racks) < len(owners) {
+			rackConflicts = append(rackConflicts, shardID)
+		}
+
+		if numZones > 1 {
+			expected := numZones
+			if len(owners) < expected {
+				expected = len(owners)
+			}
+			if len(zones(owners)) < expected {
+				zoneConflicts = append(zoneConflicts, shardID)
+			}
+		}
+	}
+
+	if len(underReplicated) > 0 {
+		r.add(Finding{
+			Check:    CheckUnderReplicated,
+			Severity: Critical,
+			ShardIDs: underReplicated,
+			Message:  fmt.Sprintf("%d shards have fewer than %d replicas", len(underReplicated), p.ReplicaFactor()),
+		})
+	}
+	if len(rackConflicts) > 0 {
+		r.add(Finding{
+			Check:    CheckRackSpread,
+			Severity: Warning,
+			ShardIDs: rackConflicts,
+			Message:  fmt.Sprintf("%d shards have more than one replica on the same rack", len(rackConflicts)),
+		})
+	}
+	if len(zoneConflicts) > 0 {
+		r.add(Finding{
+			Check:    CheckZoneSpread,
+			Severity: Info,
+			ShardIDs: zoneConflicts,
+			Message:  fmt.Sprintf("%d shards are not spread over all %d zones", len(zoneConflicts), numZones),
+		})
+	}
+}
+
+// checkLoad compares the load on each instance with the target load computed
+// by the placement algorithm.  Target loads are rounded down to whole shards,
+// so an instance is only reported when it is more than one shard away from
+// its target load.
+func (a analyzer) checkLoad(p placement.Placement, r *Report) {
+	helper := algo.NewPlacementHelper(p.Clone(), a.opts.PlacementOptions())
+	for _, instance := range p.Instances() {
+		if instance.IsLeaving() {
+			continue
+		}
+		target := helper.TargetLoadForInstance(instance.ID())
+		if target == 0 {
+			continue
+		}
+		load := instance.Shards().NumShards() - instance.Shards().NumShardsForState(shard.Leaving)
+		diff := load - target
+		skew := float64(diff) / float64(target)
+		if math.Abs(float64(diff)) <= 1 || math.Abs(skew) <= a.opts.MaxLoadSkew() {
+			continue
+		}
+		r.add(Finding{
+			Check:      CheckLoadSkew,
+			Severity:   Warning,
+			InstanceID: instance.ID(),
+			Message: fmt.Sprintf("instance %s owns %d shards, %+.0f%% from its target load of %d",
+				instance.ID(), load, skew*100, target),
+		})
+	}
+}
+
+func (a analyzer) checkInstances(p placement.Placement, r *Report) {
+	nowNanos := a.opts.NowFn()().UnixNano()
+	for _, instance := range p.Instances() {
+		if instance.IsLeaving() {
+			r.add(Finding{
+				Check:      CheckLeavingInstance,
+				Severity:   Warning,
+				InstanceID: instance.ID(),
+				Message:    fmt.Sprintf("all %d shards on instance %s are leaving", instance.Shards().NumShards(), instance.ID()),
+			})
+		}
+
+		var stuckInit, stuckLeaving []uint32
+		for _, s := range instance.Shards().All() {
+			switch s.State() {
+			case shard.Initializing:
+				cutover := s.CutoverNanos()
+				if cutover > 0 && nowNanos-cutover > int64(a.opts.MaxInitializingDuration()) {
+					stuckInit = append(stuckInit, s.ID())
+				}
+			case shard.Leaving:
+				cutoff := s.CutoffNanos()
+				if cutoff > 0 && cutoff != shard.DefaultShardCutoffNanos &&
+					nowNanos-cutoff > int64(a.opts.MaxLeavingDuration()) {
+					stuckLeaving = append(stuckLeaving, s.ID())
+				}
+			}
+		}
+
+		if len(stuckInit) > 0 {
+			r.add(Finding{
+				Check:      CheckStuckInitializing,
+				Severity:   Warning,
+				InstanceID: instance.ID(),
+				ShardIDs:   stuckInit,
+				Message: fmt.Sprintf("%d shards on instance %s have been initializing for more than %v",
+					len(stuckInit), instance.ID(), a.opts.MaxInitializingDuration()),
+			})
+		}
+		if len(stuckLeaving) > 0 {
+			r.add(Finding{
+				Check:      CheckStuckLeaving,
+				Severity:   Warning,
+				InstanceID: instance.ID(),
+				ShardIDs:   stuckLeaving,
+				Message: fmt.Sprintf("%d shards on instance %s have been leaving for more than %v",
+					len(stuckLeaving), instance.ID(), a.opts.MaxLeavingDuration()),
+			})
+		}
+	}
+}
+
+func (r *Report) add(f Finding) {
+	r.Findings = append(r.Findings, f)
+}
+
+func nonLeavingOwners(p placement.Placement, shardID uint32) []placement.Instance {
+	var res []placement.Instance
+	for _, instance := range p.InstancesForShard(shardID) {
+		if s, ok := instance.Shards().Shard(shardID); ok && s.State() != shard.Leaving {
+			res = append(res, instance)
+		}
+	}
+	return res
+}
+
+func zones(instances []placement.Instance) []string {
+	seen := make(map[string]struct{}, len(instances))
+	var res []string
+	for _, instance := range instances {
+		if _, ok := seen[instance.Zone()]; ok {
+			continue
+		}
+		seen[instance.Zone()] = struct{}{}
+		res = append(res, instance.Zone())
+	}
+	sort.Strings(res)
+	return res
+}
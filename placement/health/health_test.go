@@ -0,0 +1,178 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package health
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/placement/algo"
+	"github.com/m3db/m3cluster/shard"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeHealthyPlacement(t *testing.T) {
+	instances := []placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i4", "r4", "z1", "endpoint", 1),
+	}
+	p, err := algo.NewAlgorithm(placement.NewOptions()).InitialPlacement(instances, ids(64), 2)
+	require.NoError(t, err)
+	p, err = placement.MarkAllShardsAsAvailable(p)
+	require.NoError(t, err)
+
+	r := NewAnalyzer(NewOptions()).Analyze(p)
+	assert.Empty(t, r.Findings)
+	assert.True(t, r.IsHealthy())
+	_, ok := r.MaxSeverity()
+	assert.False(t, ok)
+}
+
+func TestAnalyzeUnhealthyPlacement(t *testing.T) {
+	now := time.Unix(0, 0).Add(100 * time.Hour)
+	old := now.Add(-48 * time.Hour).UnixNano()
+
+	// i1 and i2 share a rack, i3 is leaving everything it owns to i4 and i5
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r1", "z2", "endpoint", 1)
+	i3 := placement.NewEmptyInstance("i3", "r2", "z1", "endpoint", 1)
+	i4 := placement.NewEmptyInstance("i4", "r3", "z1", "endpoint", 1)
+	i5 := placement.NewEmptyInstance("i5", "r4", "z2", "endpoint", 1)
+	for id := uint32(0); id < 8; id++ {
+		i1.Shards().Add(shard.NewShard(id).SetState(shard.Available))
+	}
+	i2.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i2.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	for id := uint32(2); id < 4; id++ {
+		i3.Shards().Add(shard.NewShard(id).SetState(shard.Leaving).SetCutoffNanos(old))
+		i4.Shards().Add(shard.NewShard(id).SetState(shard.Initializing).SetSourceID("i3").SetCutoverNanos(old))
+	}
+	for id := uint32(4); id < 8; id++ {
+		i5.Shards().Add(shard.NewShard(id).SetState(shard.Available))
+	}
+
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2, i3, i4, i5}).
+		SetShards(ids(8)).
+		SetReplicaFactor(2).
+		SetIsSharded(true)
+
+	r := NewAnalyzer(NewOptions().SetNowFn(func() time.Time { return now })).Analyze(p)
+	byCheck := make(map[Check][]Finding)
+	for _, f := range r.Findings {
+		byCheck[f.Check] = append(byCheck[f.Check], f)
+	}
+
+	assert.Empty(t, byCheck[CheckValidity])
+	require.Len(t, byCheck[CheckRackSpread], 1)
+	assert.Equal(t, []uint32{0, 1}, byCheck[CheckRackSpread][0].ShardIDs)
+
+	require.Len(t, byCheck[CheckZoneSpread], 1)
+	assert.Equal(t, []uint32{2, 3}, byCheck[CheckZoneSpread][0].ShardIDs)
+	assert.Equal(t, Info, byCheck[CheckZoneSpread][0].Severity)
+
+	// Every non-leaving instance should own 4 shards
+	require.Len(t, byCheck[CheckLoadSkew], 3)
+	assert.Equal(t, "i1", byCheck[CheckLoadSkew][0].InstanceID)
+	assert.Equal(t, "i2", byCheck[CheckLoadSkew][1].InstanceID)
+	assert.Equal(t, "i4", byCheck[CheckLoadSkew][2].InstanceID)
+	assert.Equal(t, "[warning] load-skew: instance i1 owns 8 shards, +100% from its target load of 4",
+		byCheck[CheckLoadSkew][0].String())
+
+	require.Len(t, byCheck[CheckLeavingInstance], 1)
+	assert.Equal(t, "i3", byCheck[CheckLeavingInstance][0].InstanceID)
+
+	require.Len(t, byCheck[CheckStuckLeaving], 1)
+	assert.Equal(t, []uint32{2, 3}, byCheck[CheckStuckLeaving][0].ShardIDs)
+
+	require.Len(t, byCheck[CheckStuckInitializing], 1)
+	assert.Equal(t, "i4", byCheck[CheckStuckInitializing][0].InstanceID)
+
+	assert.Empty(t, byCheck[CheckUnderReplicated])
+
+	severity, ok := r.MaxSeverity()
+	assert.True(t, ok)
+	assert.Equal(t, Warning, severity)
+	assert.False(t, r.IsHealthy())
+	assert.Empty(t, r.FindingsAtLeast(Critical))
+}
+
+func TestAnalyzeUnderReplicated(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i2.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i2.Shards().Add(shard.NewShard(1).SetState(shard.Leaving))
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2}).
+		SetShards(ids(2)).
+		SetReplicaFactor(2).
+		SetIsSharded(true)
+
+	r := NewAnalyzer(NewOptions()).Analyze(p)
+	require.Len(t, r.Findings, 2)
+	assert.Equal(t, CheckValidity, r.Findings[0].Check)
+	assert.Equal(t, Critical, r.Findings[0].Severity)
+	assert.Equal(t, CheckUnderReplicated, r.Findings[1].Check)
+	assert.Equal(t, Critical, r.Findings[1].Severity)
+	assert.Equal(t, []uint32{1}, r.Findings[1].ShardIDs)
+}
+
+func TestAnalyzeValidatesWithPlacementOptions(t *testing.T) {
+	instances := []placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1),
+	}
+	p, err := algo.NewAlgorithm(placement.NewOptions()).InitialPlacement(instances, ids(8), 1)
+	require.NoError(t, err)
+	p, err = placement.MarkAllShardsAsAvailable(p)
+	require.NoError(t, err)
+
+	maxShards := placement.NewValidator("max-shards", func(p placement.Placement) error {
+		for _, instance := range p.Instances() {
+			if instance.Shards().NumShards() > 2 {
+				return fmt.Errorf("instance %s owns more than 2 shards", instance.ID())
+			}
+		}
+		return nil
+	})
+	placementOpts := placement.NewOptions().SetValidators([]placement.Validator{maxShards})
+	r := NewAnalyzer(NewOptions().SetPlacementOptions(placementOpts)).Analyze(p)
+	require.Len(t, r.Findings, 1)
+	assert.Equal(t, CheckValidity, r.Findings[0].Check)
+	assert.Equal(t, Critical, r.Findings[0].Severity)
+	assert.Contains(t, r.Findings[0].Message, "validator max-shards failed")
+}
+
+func ids(n int) []uint32 {
+	res := make([]uint32, n)
+	for i := range res {
+		res[i] = uint32(i)
+	}
+	return res
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package health
+
+import (
+	"time"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3x/clock"
+)
+
+const (
+	defaultMaxLoadSkew             = 0.1
+	defaultMaxInitializingDuration = 24 * time.Hour
+	defaultMaxLeavingDuration      = 24 * time.Hour
+)
+
+// Options are the options for the placement health analyzer.
+type Options interface {
+	// PlacementOptions returns the placement options used to validate placements
+	// and compute target loads.
+	PlacementOptions() placement.Options
+
+	// SetPlacementOptions sets the placement options used to validate placements
+	// and compute target loads.
+	SetPlacementOptions(value placement.Options) Options
+
+	// MaxLoadSkew returns the largest tolerated difference between the load on
+	// an instance and its target load, as a fraction of the target load.
+	MaxLoadSkew() float64
+
+	// SetMaxLoadSkew sets the largest tolerated difference between the load on
+	// an instance and its target load, as a fraction of the target load.
+	SetMaxLoadSkew(value float64) Options
+
+	// MaxInitializingDuration returns how long a shard may stay Initializing
+	// after its cutover time.
+	MaxInitializingDuration() time.Duration
+
+	// SetMaxInitializingDuration sets how long a shard may stay Initializing
+	// after its cutover time.
+	SetMaxInitializingDuration(value time.Duration) Options
+
+	// MaxLeavingDuration returns how long a shard may stay Leaving after its
+	// cutoff time.
+	MaxLeavingDuration() time.Duration
+
+	// SetMaxLeavingDuration sets how long a shard may stay Leaving after its
+	// cutoff time.
+	SetMaxLeavingDuration(value time.Duration) Options
+
+	// NowFn returns the function used to get the current time.
+	NowFn() clock.NowFn
+
+	// SetNowFn sets the function used to get the current time.
+	SetNowFn(value clock.NowFn) Options
+}
+
+type options struct {
+	placementOpts           placement.Options
+	maxLoadSkew             float64
+	maxInitializingDuration time.Duration
+	maxLeavingDuration      time.Duration
+	nowFn                   clock.NowFn
+}
+
+// NewOptions returns a default Options.
+func NewOptions() Options {
+	return options{
+		placementOpts:           placement.NewOptions(),
+		maxLoadSkew:             defaultMaxLoadSkew,
+		maxInitializingDuration: defaultMaxInitializingDuration,
+		maxLeavingDuration:      defaultMaxLeavingDuration,
+		nowFn:                   time.Now,
+	}
+}
+
+func (o options) PlacementOptions() placement.Options {
+	return o.placementOpts
+}
+
+func (o options) SetPlacementOptions(value placement.Options) Options {
+	o.placementOpts = value
+	return o
+}
+
+func (o options) MaxLoadSkew() float64 {
+	return o.maxLoadSkew
+}
+
+func (o options) SetMaxLoadSkew(value float64) Options {
+	o.maxLoadSkew = value
+	return o
+}
+
+func (o options) MaxInitializingDuration() time.Duration {
+	return o.maxInitializingDuration
+}
+
+func (o options) SetMaxInitializingDuration(value time.Duration) Options {
+	o.maxInitializingDuration = value
+	return o
+}
+
+func (o options) MaxLeavingDuration() time.Duration {
+	return o.maxLeavingDuration
+}
+
+func (o options) SetMaxLeavingDuration(value time.Duration) Options {
+	o.maxLeavingDuration = value
+	return o
+}
+
+func (o options) NowFn() clock.NowFn {
+	return o.nowFn
+}
+
+func (o options) SetNowFn(value clock.NowFn) Options {
+	o.nowFn = value
+	return o
+}
@@ -30,6 +30,9 @@ const (
 	defaultIsSharded   = true
 	// By default partial replace should be allowed for better distribution.
 	defaultAllowPartialReplace = true
+	// Each unit of instance weight places this many points on the hash ring
+	// of the consistent hashing algorithm.
+	defaultVirtualNodesPerWeight = 64
 )
 
 type deploymentOptions struct {
@@ -58,8 +61,15 @@ type options struct {
 	isSharded           bool
 	isMirrored          bool
 	isStaged            bool
+	isConsistentHashing bool
+	vnodesPerWeight     int
+	algorithm           Algorithm
+	instanceSelector    InstanceSelector
+	validators          []Validator
 	iopts               instrument.Options
 	validZone           string
+	spreadAcrossZones   bool
+	failureDomainPolicy FailureDomainPolicy
 	dryrun              bool
 	placementCutOverFn  TimeNanosFn
 	shardCutOverFn      TimeNanosFn
@@ -71,6 +81,7 @@ func NewOptions() Options {
 	return options{
 		allowPartialReplace: defaultAllowPartialReplace,
 		isSharded:           defaultIsSharded,
+		vnodesPerWeight:     defaultVirtualNodesPerWeight,
 		iopts:               instrument.NewOptions(),
 		placementCutOverFn:  defaultTimeNanosFn,
 		shardCutOverFn:      defaultTimeNanosFn,
@@ -123,6 +134,51 @@ func (o options) SetIsStaged(v bool) Options {
 	return o
 }
 
+func (o options) IsConsistentHashing() bool {
+	return o.isConsistentHashing
+}
+
+func (o options) SetIsConsistentHashing(v bool) Options {
+	o.isConsistentHashing = v
+	return o
+}
+
+func (o options) VirtualNodesPerWeight() int {
+	return o.vnodesPerWeight
+}
+
+func (o options) SetVirtualNodesPerWeight(n int) Options {
+	o.vnodesPerWeight = n
+	return o
+}
+
+func (o options) Algorithm() Algorithm {
+	return o.algorithm
+}
+
+func (o options) SetAlgorithm(algorithm Algorithm) Options {
+	o.algorithm = algorithm
+	return o
+}
+
+func (o options) InstanceSelector() InstanceSelector {
+	return o.instanceSelector
+}
+
+func (o options) SetInstanceSelector(selector InstanceSelector) Options {
+	o.instanceSelector = selector
+	return o
+}
+
+func (o options) Validators() []Validator {
+	return o.validators
+}
+
+func (o options) SetValidators(validators []Validator) Options {
+	o.validators = validators
+	return o
+}
+
 func (o options) Dryrun() bool {
 	return o.dryrun
 }
@@ -150,6 +206,24 @@ func (o options) SetValidZone(z string) Options {
 	return o
 }
 
+func (o options) SpreadAcrossZones() bool {
+	return o.spreadAcrossZones
+}
+
+func (o options) SetSpreadAcrossZones(v bool) Options {
+	o.spreadAcrossZones = v
+	return o
+}
+
+func (o options) FailureDomainPolicy() FailureDomainPolicy {
+	return o.failureDomainPolicy
+}
+
+func (o options) SetFailureDomainPolicy(policy FailureDomainPolicy) Options {
+	o.failureDomainPolicy = policy
+	return o
+}
+
 func (o options) PlacementCutoverNanosFn() TimeNanosFn {
 	return o.placementCutOverFn
 }
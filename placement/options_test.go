@@ -25,6 +25,7 @@ import (
 
 	"github.com/m3db/m3x/instrument"
 
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,6 +37,9 @@ func TestDeploymentOptions(t *testing.T) {
 }
 
 func TestPlacementOptions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
 	o := NewOptions()
 	assert.False(t, o.LooseRackCheck())
 	assert.True(t, o.AllowPartialReplace())
@@ -43,6 +47,12 @@ func TestPlacementOptions(t *testing.T) {
 	assert.False(t, o.Dryrun())
 	assert.False(t, o.IsMirrored())
 	assert.False(t, o.IsStaged())
+	assert.False(t, o.IsConsistentHashing())
+	assert.Equal(t, defaultVirtualNodesPerWeight, o.VirtualNodesPerWeight())
+	assert.Nil(t, o.Algorithm())
+	assert.Nil(t, o.InstanceSelector())
+	assert.Empty(t, o.Validators())
+	assert.False(t, o.SpreadAcrossZones())
 	assert.Equal(t, instrument.NewOptions(), o.InstrumentOptions())
 	assert.Equal(t, int64(0), o.PlacementCutoverNanosFn()())
 	assert.Equal(t, int64(0), o.ShardCutoffNanosFn()())
@@ -66,6 +76,27 @@ func TestPlacementOptions(t *testing.T) {
 	o = o.SetIsStaged(true)
 	assert.True(t, o.IsStaged())
 
+	o = o.SetIsConsistentHashing(true)
+	assert.True(t, o.IsConsistentHashing())
+
+	o = o.SetVirtualNodesPerWeight(16)
+	assert.Equal(t, 16, o.VirtualNodesPerWeight())
+
+	algorithm := NewMockAlgorithm(ctrl)
+	o = o.SetAlgorithm(algorithm)
+	assert.Equal(t, algorithm, o.Algorithm())
+
+	selector := NewMockInstanceSelector(ctrl)
+	o = o.SetInstanceSelector(selector)
+	assert.Equal(t, selector, o.InstanceSelector())
+
+	validators := []Validator{NewValidator("v", func(Placement) error { return nil })}
+	o = o.SetValidators(validators)
+	assert.Equal(t, validators, o.Validators())
+
+	o = o.SetSpreadAcrossZones(true)
+	assert.True(t, o.SpreadAcrossZones())
+
 	iopts := instrument.NewOptions().SetMetricsSamplingRate(0.5)
 	o = o.SetInstrumentOptions(iopts)
 	assert.Equal(t, iopts, o.InstrumentOptions())
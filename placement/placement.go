@@ -46,6 +46,8 @@ type placement struct {
 	shards           []uint32
 	isSharded        bool
 	isMirrored       bool
+	policy           FailureDomainPolicy
+	shardSplits      map[uint32][]uint32
 	cutoverNanos     int64
 	version          int
 }
@@ -74,13 +76,26 @@ func NewPlacementFromProto(p *placementpb.Placement) (Placement, error) {
 		instances = append(instances, pi)
 	}
 
+	var shardSplits map[uint32][]uint32
+	if len(p.ShardSplits) > 0 {
+		shardSplits = make(map[uint32][]uint32, len(p.ShardSplits))
+		for parent, split := range p.ShardSplits {
+			if split == nil {
+				continue
+			}
+			shardSplits[parent] = split.ChildShards
+		}
+	}
+
 	return NewPlacement().
 		SetInstances(instances).
 		SetShards(shards).
 		SetReplicaFactor(int(p.ReplicaFactor)).
 		SetIsSharded(p.IsSharded).
 		SetCutoverNanos(p.CutoverTime).
-		SetIsMirrored(p.IsMirrored), nil
+		SetIsMirrored(p.IsMirrored).
+		SetFailureDomainPolicy(NewFailureDomainPolicy(p.FailureDomainLevels...)).
+		SetShardSplits(shardSplits), nil
 }
 
 func (p *placement) InstancesForShard(shard uint32) []Instance {
@@ -168,6 +183,24 @@ func (p *placement) SetIsMirrored(v bool) Placement {
 	return p
 }
 
+func (p *placement) FailureDomainPolicy() FailureDomainPolicy {
+	return p.policy
+}
+
+func (p *placement) SetFailureDomainPolicy(policy FailureDomainPolicy) Placement {
+	p.policy = policy
+	return p
+}
+
+func (p *placement) ShardSplits() map[uint32][]uint32 {
+	return p.shardSplits
+}
+
+func (p *placement) SetShardSplits(splits map[uint32][]uint32) Placement {
+	p.shardSplits = splits
+	return p
+}
+
 func (p *placement) CutoverNanos() int64 {
 	return p.cutoverNanos
 }
@@ -203,13 +236,23 @@ func (p *placement) Proto() (*placementpb.Placement, error) {
 		instances[instance.ID()] = pi
 	}
 
+	var shardSplits map[uint32]*placementpb.ShardSplit
+	if splits := p.ShardSplits(); len(splits) > 0 {
+		shardSplits = make(map[uint32]*placementpb.ShardSplit, len(splits))
+		for parent, children := range splits {
+			shardSplits[parent] = &placementpb.ShardSplit{ChildShards: children}
+		}
+	}
+
 	return &placementpb.Placement{
-		Instances:     instances,
-		ReplicaFactor: uint32(p.ReplicaFactor()),
-		NumShards:     uint32(p.NumShards()),
-		IsSharded:     p.IsSharded(),
-		CutoverTime:   p.CutoverNanos(),
-		IsMirrored:    p.IsMirrored(),
+		Instances:           instances,
+		ReplicaFactor:       uint32(p.ReplicaFactor()),
+		NumShards:           uint32(p.NumShards()),
+		IsSharded:           p.IsSharded(),
+		CutoverTime:         p.CutoverNanos(),
+		IsMirrored:          p.IsMirrored(),
+		FailureDomainLevels: p.FailureDomainPolicy().Levels(),
+		ShardSplits:         shardSplits,
 	}, nil
 }
 
@@ -220,9 +263,22 @@ func (p *placement) Clone() Placement {
 		SetReplicaFactor(p.ReplicaFactor()).
 		SetIsSharded(p.IsSharded()).
 		SetIsMirrored(p.IsMirrored()).
+		SetFailureDomainPolicy(p.FailureDomainPolicy()).
+		SetShardSplits(cloneShardSplits(p.ShardSplits())).
 		SetCutoverNanos(p.CutoverNanos())
 }
 
+func cloneShardSplits(splits map[uint32][]uint32) map[uint32][]uint32 {
+	if splits == nil {
+		return nil
+	}
+	res := make(map[uint32][]uint32, len(splits))
+	for parent, children := range splits {
+		res[parent] = append([]uint32(nil), children...)
+	}
+	return res
+}
+
 // Placements represents a list of placements.
 type Placements []Placement
 
@@ -271,6 +327,35 @@ func (placements Placements) ActiveIndex(timeNanos int64) int {
 	return idx
 }
 
+type validator struct {
+	name string
+	fn   ValidateFn
+}
+
+// NewValidator returns a Validator with the name running the validate function.
+func NewValidator(name string, fn ValidateFn) Validator {
+	return validator{name: name, fn: fn}
+}
+
+func (v validator) Name() string { return v.name }
+
+func (v validator) Validate(p Placement) error { return v.fn(p) }
+
+// ValidateWithOptions validates a placement with the built-in checks
+// and then the custom validators in the options.
+func ValidateWithOptions(p Placement, opts Options) error {
+	if err := Validate(p); err != nil {
+		return err
+	}
+
+	for _, v := range opts.Validators() {
+		if err := v.Validate(p); err != nil {
+			return fmt.Errorf("invalid placement, validator %s failed: %v", v.Name(), err)
+		}
+	}
+	return nil
+}
+
 // Validate validates a placement
 func Validate(p Placement) error {
 	if p.IsMirrored() && !p.IsSharded() {
@@ -282,6 +367,19 @@ func Validate(p Placement) error {
 		return errDuplicatedShards
 	}
 
+	for parent, children := range p.ShardSplits() {
+		for _, child := range children {
+			if _, ok := shardCountMap[child]; !ok {
+				return fmt.Errorf("invalid placement, child shard %d of parent shard %d does not exist", child, parent)
+			}
+		}
+	}
+
+	// NB: a Leaving replica dropped by lowering the replica factor is not handed
+	// over to another instance, any other Leaving replica must be matched by an
+	// Initializing replica.
+	droppedShards := droppedShardIDs(p)
+
 	expectedTotal := len(p.Shards()) * p.ReplicaFactor()
 	totalCapacity := 0
 	totalLeaving := 0
@@ -314,7 +412,9 @@ func Validate(p Placement) error {
 					totalInitWithSourceID++
 				}
 			case shard.Leaving:
-				totalLeaving++
+				if _, ok := droppedShards[s.ID()]; !ok {
+					totalLeaving++
+				}
 			default:
 				return fmt.Errorf("invalid shard state %v for shard %d", s.State(), s.ID())
 			}
@@ -343,6 +443,36 @@ func Validate(p Placement) error {
 			return fmt.Errorf("invalid shard count for shard %d: expected %d, actual %d", shard, p.ReplicaFactor(), c)
 		}
 	}
+
+	return validateFailureDomainPolicy(p)
+}
+
+func validateFailureDomainPolicy(p Placement) error {
+	policy := p.FailureDomainPolicy()
+	if policy.IsEmpty() {
+		return nil
+	}
+
+	instances := p.Instances()
+	nonLeaving := make([]Instance, 0, len(instances))
+	for _, instance := range instances {
+		if !instance.IsLeaving() {
+			nonLeaving = append(nonLeaving, instance)
+		}
+	}
+	numDomains := policy.NumDomains(nonLeaving)
+
+	for _, shardID := range p.Shards() {
+		var replicas []Instance
+		for _, instance := range p.InstancesForShard(shardID) {
+			if s, ok := instance.Shards().Shard(shardID); ok && s.State() != shard.Leaving {
+				replicas = append(replicas, instance)
+			}
+		}
+		if err := policy.Check(replicas, p.ReplicaFactor(), numDomains); err != nil {
+			return fmt.Errorf("invalid placement, shard %d violates failure domain policy %s: %v", shardID, policy.String(), err)
+		}
+	}
 	return nil
 }
 
@@ -390,7 +520,8 @@ func NewInstanceFromProto(instance *placementpb.Instance) (Instance, error) {
 		SetShards(shards).
 		SetShardSetID(instance.ShardSetId).
 		SetHostname(instance.Hostname).
-		SetPort(instance.Port), nil
+		SetPort(instance.Port).
+		SetFailureDomains(instance.FailureDomains), nil
 }
 
 type instance struct {
@@ -403,6 +534,7 @@ type instance struct {
 	port       uint32
 	shards     shard.Shards
 	shardSetID uint32
+	domains    map[string]string
 }
 
 func (i *instance) String() string {
@@ -475,6 +607,30 @@ func (i *instance) SetPort(value uint32) Instance {
 	return i
 }
 
+func (i *instance) FailureDomains() map[string]string {
+	return i.domains
+}
+
+func (i *instance) SetFailureDomains(value map[string]string) Instance {
+	i.domains = value
+	return i
+}
+
+func (i *instance) FailureDomain(level string) string {
+	if domain, ok := i.domains[level]; ok {
+		return domain
+	}
+	switch level {
+	case ZoneLevel:
+		return i.zone
+	case RackLevel:
+		return i.rack
+	case HostLevel:
+		return i.hostname
+	}
+	return ""
+}
+
 func (i *instance) ShardSetID() uint32 {
 	return i.shardSetID
 }
@@ -500,15 +656,16 @@ func (i *instance) Proto() (*placementpb.Instance, error) {
 	}
 
 	return &placementpb.Instance{
-		Id:         i.ID(),
-		Rack:       i.Rack(),
-		Zone:       i.Zone(),
-		Weight:     i.Weight(),
-		Endpoint:   i.Endpoint(),
-		Shards:     ss,
-		ShardSetId: i.ShardSetID(),
-		Hostname:   i.Hostname(),
-		Port:       i.Port(),
+		Id:             i.ID(),
+		Rack:           i.Rack(),
+		Zone:           i.Zone(),
+		Weight:         i.Weight(),
+		Endpoint:       i.Endpoint(),
+		Shards:         ss,
+		ShardSetId:     i.ShardSetID(),
+		Hostname:       i.Hostname(),
+		Port:           i.Port(),
+		FailureDomains: i.FailureDomains(),
 	}, nil
 }
 
@@ -539,9 +696,21 @@ func (i *instance) Clone() Instance {
 		SetHostname(i.Hostname()).
 		SetPort(i.Port()).
 		SetShardSetID(i.ShardSetID()).
+		SetFailureDomains(cloneFailureDomains(i.FailureDomains())).
 		SetShards(i.Shards().Clone())
 }
 
+func cloneFailureDomains(domains map[string]string) map[string]string {
+	if domains == nil {
+		return nil
+	}
+	cloned := make(map[string]string, len(domains))
+	for level, domain := range domains {
+		cloned[level] = domain
+	}
+	return cloned
+}
+
 // Instances is a slice of instances that can produce a debug string.
 type Instances []Instance
 
@@ -663,3 +832,78 @@ func MarkAllShardsAsAvailable(p Placement) (Placement, error) {
 	}
 	return p, nil
 }
+
+// RemoveDroppedReplicas removes the Leaving shards on the given instance that are
+// replicas dropped by lowering the replica factor, i.e. not handed over to any other
+// instance while the shard has more replicas than the replica factor. The instance
+// is removed if it owns no more shards.
+func RemoveDroppedReplicas(p Placement, instanceID string) (Placement, error) {
+	if _, ok := p.Instance(instanceID); !ok {
+		return nil, fmt.Errorf("instance %s does not exist in placement", instanceID)
+	}
+	return removeDroppedReplicas(p.Clone(), instanceID), nil
+}
+
+// RemoveAllDroppedReplicas removes the replicas dropped by lowering the replica
+// factor from all the instances, completing the removal of the replica. The
+// instances are removed if they own no more shards.
+func RemoveAllDroppedReplicas(p Placement) (Placement, error) {
+	p = p.Clone()
+	for _, instance := range p.Instances() {
+		p = removeDroppedReplicas(p, instance.ID())
+	}
+	return p, nil
+}
+
+func removeDroppedReplicas(p Placement, instanceID string) Placement {
+	instance, ok := p.Instance(instanceID)
+	if !ok {
+		return p
+	}
+
+	var (
+		droppedShards = droppedShardIDs(p)
+		shards        = instance.Shards()
+		removed       = 0
+	)
+	for _, s := range shards.ShardsForState(shard.Leaving) {
+		if _, ok := droppedShards[s.ID()]; ok {
+			shards.Remove(s.ID())
+			removed++
+		}
+	}
+	if removed == 0 {
+		return p
+	}
+	if shards.NumShards() == 0 {
+		return p.SetInstances(RemoveInstanceFromList(p.Instances(), instanceID))
+	}
+	return p.SetInstances(p.Instances())
+}
+
+// droppedShardIDs returns the shards with Leaving replicas dropped by lowering
+// the replica factor, which are the shards without any Initializing replica that
+// have more replicas than the replica factor with the Leaving ones counted.
+func droppedShardIDs(p Placement) map[uint32]struct{} {
+	var (
+		numReplicas  = make(map[uint32]int)
+		initializing = make(map[uint32]struct{})
+	)
+	for _, instance := range p.Instances() {
+		for _, s := range instance.Shards().All() {
+			numReplicas[s.ID()]++
+			if s.State() == shard.Initializing {
+				initializing[s.ID()] = struct{}{}
+			}
+		}
+	}
+
+	res := make(map[uint32]struct{})
+	for id, n := range numReplicas {
+		if _, ok := initializing[id]; ok || n <= p.ReplicaFactor() {
+			continue
+		}
+		res[id] = struct{}{}
+	}
+	return res
+}
@@ -245,6 +245,36 @@ func (_mr *_MockInstanceRecorder) SetPort(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetPort", arg0)
 }
 
+func (_m *MockInstance) FailureDomains() map[string]string {
+	ret := _m.ctrl.Call(_m, "FailureDomains")
+	ret0, _ := ret[0].(map[string]string)
+	return ret0
+}
+
+func (_mr *_MockInstanceRecorder) FailureDomains() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "FailureDomains")
+}
+
+func (_m *MockInstance) SetFailureDomains(value map[string]string) Instance {
+	ret := _m.ctrl.Call(_m, "SetFailureDomains", value)
+	ret0, _ := ret[0].(Instance)
+	return ret0
+}
+
+func (_mr *_MockInstanceRecorder) SetFailureDomains(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetFailureDomains", arg0)
+}
+
+func (_m *MockInstance) FailureDomain(level string) string {
+	ret := _m.ctrl.Call(_m, "FailureDomain", level)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+func (_mr *_MockInstanceRecorder) FailureDomain(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "FailureDomain", arg0)
+}
+
 func (_m *MockInstance) Proto() (*placementpb.Instance, error) {
 	ret := _m.ctrl.Call(_m, "Proto")
 	ret0, _ := ret[0].(*placementpb.Instance)
@@ -468,6 +498,46 @@ func (_mr *_MockPlacementRecorder) SetIsMirrored(arg0 interface{}) *gomock.Call
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetIsMirrored", arg0)
 }
 
+func (_m *MockPlacement) FailureDomainPolicy() FailureDomainPolicy {
+	ret := _m.ctrl.Call(_m, "FailureDomainPolicy")
+	ret0, _ := ret[0].(FailureDomainPolicy)
+	return ret0
+}
+
+func (_mr *_MockPlacementRecorder) FailureDomainPolicy() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "FailureDomainPolicy")
+}
+
+func (_m *MockPlacement) SetFailureDomainPolicy(policy FailureDomainPolicy) Placement {
+	ret := _m.ctrl.Call(_m, "SetFailureDomainPolicy", policy)
+	ret0, _ := ret[0].(Placement)
+	return ret0
+}
+
+func (_mr *_MockPlacementRecorder) SetFailureDomainPolicy(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetFailureDomainPolicy", arg0)
+}
+
+func (_m *MockPlacement) ShardSplits() map[uint32][]uint32 {
+	ret := _m.ctrl.Call(_m, "ShardSplits")
+	ret0, _ := ret[0].(map[uint32][]uint32)
+	return ret0
+}
+
+func (_mr *_MockPlacementRecorder) ShardSplits() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ShardSplits")
+}
+
+func (_m *MockPlacement) SetShardSplits(splits map[uint32][]uint32) Placement {
+	ret := _m.ctrl.Call(_m, "SetShardSplits", splits)
+	ret0, _ := ret[0].(Placement)
+	return ret0
+}
+
+func (_mr *_MockPlacementRecorder) SetShardSplits(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetShardSplits", arg0)
+}
+
 func (_m *MockPlacement) String() string {
 	ret := _m.ctrl.Call(_m, "String")
 	ret0, _ := ret[0].(string)
@@ -1285,6 +1355,28 @@ func (_mr *_MockStorageRecorder) Proto() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Proto")
 }
 
+func (_m *MockStorage) History(from int, to int) ([]Placement, error) {
+	ret := _m.ctrl.Call(_m, "History", from, to)
+	ret0, _ := ret[0].([]Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockStorageRecorder) History(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "History", arg0, arg1)
+}
+
+func (_m *MockStorage) PlacementForVersion(version int) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "PlacementForVersion", version)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockStorageRecorder) PlacementForVersion(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "PlacementForVersion", arg0)
+}
+
 // Mock of Service interface
 type MockService struct {
 	ctrl     *gomock.Controller
@@ -1390,6 +1482,28 @@ func (_mr *_MockServiceRecorder) Proto() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Proto")
 }
 
+func (_m *MockService) History(from int, to int) ([]Placement, error) {
+	ret := _m.ctrl.Call(_m, "History", from, to)
+	ret0, _ := ret[0].([]Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) History(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "History", arg0, arg1)
+}
+
+func (_m *MockService) PlacementForVersion(version int) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "PlacementForVersion", version)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) PlacementForVersion(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "PlacementForVersion", arg0)
+}
+
 func (_m *MockService) BuildInitialPlacement(instances []Instance, numShards int, rf int) (Placement, error) {
 	ret := _m.ctrl.Call(_m, "BuildInitialPlacement", instances, numShards, rf)
 	ret0, _ := ret[0].(Placement)
@@ -1412,6 +1526,28 @@ func (_mr *_MockServiceRecorder) AddReplica() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "AddReplica")
 }
 
+func (_m *MockService) RemoveReplica() (Placement, error) {
+	ret := _m.ctrl.Call(_m, "RemoveReplica")
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) RemoveReplica() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RemoveReplica")
+}
+
+func (_m *MockService) SplitShards(factor int) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "SplitShards", factor)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) SplitShards(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SplitShards", arg0)
+}
+
 func (_m *MockService) AddInstances(candidates []Instance) (Placement, []Instance, error) {
 	ret := _m.ctrl.Call(_m, "AddInstances", candidates)
 	ret0, _ := ret[0].(Placement)
@@ -1467,6 +1603,119 @@ func (_mr *_MockServiceRecorder) MarkInstanceAvailable(arg0 interface{}) *gomock
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "MarkInstanceAvailable", arg0)
 }
 
+func (_m *MockService) Rebalance(maxMoves int) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "Rebalance", maxMoves)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) Rebalance(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Rebalance", arg0)
+}
+
+func (_m *MockService) UpdateInstanceWeights(weights map[string]uint32) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "UpdateInstanceWeights", weights)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) UpdateInstanceWeights(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "UpdateInstanceWeights", arg0)
+}
+
+func (_m *MockService) PlanAddInstances(candidates []Instance, maxMoves int) (Plan, []Instance, error) {
+	ret := _m.ctrl.Call(_m, "PlanAddInstances", candidates, maxMoves)
+	ret0, _ := ret[0].(Plan)
+	ret1, _ := ret[1].([]Instance)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+func (_mr *_MockServiceRecorder) PlanAddInstances(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "PlanAddInstances", arg0, arg1)
+}
+
+func (_m *MockService) PlanRemoveInstances(leavingInstanceIDs []string, maxMoves int) (Plan, error) {
+	ret := _m.ctrl.Call(_m, "PlanRemoveInstances", leavingInstanceIDs, maxMoves)
+	ret0, _ := ret[0].(Plan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) PlanRemoveInstances(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "PlanRemoveInstances", arg0, arg1)
+}
+
+func (_m *MockService) PlanReplaceInstances(leavingInstanceIDs []string, candidates []Instance, maxMoves int) (Plan, []Instance, error) {
+	ret := _m.ctrl.Call(_m, "PlanReplaceInstances", leavingInstanceIDs, candidates, maxMoves)
+	ret0, _ := ret[0].(Plan)
+	ret1, _ := ret[1].([]Instance)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+func (_mr *_MockServiceRecorder) PlanReplaceInstances(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "PlanReplaceInstances", arg0, arg1, arg2)
+}
+
+func (_m *MockService) ApplyPlanStep(plan Plan, step int) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "ApplyPlanStep", plan, step)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) ApplyPlanStep(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ApplyPlanStep", arg0, arg1)
+}
+
+func (_m *MockService) Rollback(version int) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "Rollback", version)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) Rollback(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Rollback", arg0)
+}
+
+func (_m *MockService) StagedPlacements() (Placements, int, error) {
+	ret := _m.ctrl.Call(_m, "StagedPlacements")
+	ret0, _ := ret[0].(Placements)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+func (_mr *_MockServiceRecorder) StagedPlacements() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "StagedPlacements")
+}
+
+func (_m *MockService) AppendStagedPlacement(p Placement) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "AppendStagedPlacement", p)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) AppendStagedPlacement(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "AppendStagedPlacement", arg0)
+}
+
+func (_m *MockService) PruneStagedPlacements(timeNanos int64) (Placements, error) {
+	ret := _m.ctrl.Call(_m, "PruneStagedPlacements", timeNanos)
+	ret0, _ := ret[0].(Placements)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) PruneStagedPlacements(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "PruneStagedPlacements", arg0)
+}
+
 // Mock of Algorithm interface
 type MockAlgorithm struct {
 	ctrl     *gomock.Controller
@@ -1510,6 +1759,28 @@ func (_mr *_MockAlgorithmRecorder) AddReplica(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "AddReplica", arg0)
 }
 
+func (_m *MockAlgorithm) RemoveReplica(p Placement) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "RemoveReplica", p)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAlgorithmRecorder) RemoveReplica(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RemoveReplica", arg0)
+}
+
+func (_m *MockAlgorithm) SplitShards(p Placement, factor int) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "SplitShards", p, factor)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAlgorithmRecorder) SplitShards(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SplitShards", arg0, arg1)
+}
+
 func (_m *MockAlgorithm) AddInstances(p Placement, instances []Instance) (Placement, error) {
 	ret := _m.ctrl.Call(_m, "AddInstances", p, instances)
 	ret0, _ := ret[0].(Placement)
@@ -1553,6 +1824,28 @@ func (_mr *_MockAlgorithmRecorder) IsCompatibleWith(arg0 interface{}) *gomock.Ca
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "IsCompatibleWith", arg0)
 }
 
+func (_m *MockAlgorithm) Rebalance(p Placement, maxMoves int) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "Rebalance", p, maxMoves)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAlgorithmRecorder) Rebalance(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Rebalance", arg0, arg1)
+}
+
+func (_m *MockAlgorithm) UpdateInstanceWeights(p Placement, weights map[string]uint32) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "UpdateInstanceWeights", p, weights)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAlgorithmRecorder) UpdateInstanceWeights(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "UpdateInstanceWeights", arg0, arg1)
+}
+
 // Mock of InstanceSelector interface
 type MockInstanceSelector struct {
 	ctrl     *gomock.Controller
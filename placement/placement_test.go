@@ -21,6 +21,7 @@
 package placement
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"testing"
@@ -29,6 +30,7 @@ import (
 	"github.com/m3db/m3cluster/shard"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPlacement(t *testing.T) {
@@ -244,10 +246,12 @@ func TestValidateLeavingMoreThanInitializing(t *testing.T) {
 	i2 := NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
 	i2.Shards().Add(shard.NewShard(1).SetState(shard.Leaving))
 	i2.Shards().Add(shard.NewShard(2).SetState(shard.Leaving))
-	i2.Shards().Add(shard.NewShard(3).SetState(shard.Leaving))
+
+	i3 := NewEmptyInstance("i3", "r3", "z1", "endpoint", 1)
+	i3.Shards().Add(shard.NewShard(1).SetState(shard.Leaving))
 
 	p := NewPlacement().
-		SetInstances([]Instance{i1, i2}).
+		SetInstances([]Instance{i1, i2, i3}).
 		SetShards([]uint32{1, 2, 3}).
 		SetReplicaFactor(1).
 		SetIsSharded(true)
@@ -276,6 +280,91 @@ func TestValidateLeavingNotMatchInitializingWithSourceID(t *testing.T) {
 	assert.Equal(t, err.Error(), "invalid placement, 2 shards in Leaving state, not equal 1 in Initializing state with source id")
 }
 
+func TestValidateDroppedReplicas(t *testing.T) {
+	i1 := NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(2).SetState(shard.Leaving))
+
+	i2 := NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i2.Shards().Add(shard.NewShard(1).SetState(shard.Leaving))
+	i2.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+
+	i3 := NewEmptyInstance("i3", "r3", "z1", "endpoint", 1)
+	i3.Shards().Add(shard.NewShard(1).SetState(shard.Leaving))
+	i3.Shards().Add(shard.NewShard(2).SetState(shard.Leaving))
+
+	p := NewPlacement().
+		SetInstances([]Instance{i1, i2, i3}).
+		SetShards([]uint32{1, 2}).
+		SetReplicaFactor(1).
+		SetIsSharded(true)
+	assert.NoError(t, Validate(p))
+
+	_, err := RemoveDroppedReplicas(p, "i4")
+	assert.Error(t, err)
+
+	p1, err := RemoveDroppedReplicas(p, "i1")
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(p1))
+	i1, ok := p1.Instance("i1")
+	assert.True(t, ok)
+	assert.Equal(t, []uint32{1}, i1.Shards().AllIDs())
+	assert.Equal(t, 3, p1.NumInstances())
+	i1, _ = p.Instance("i1")
+	assert.Equal(t, 2, i1.Shards().NumShards())
+
+	p2, err := RemoveDroppedReplicas(p1, "i3")
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(p2))
+	_, ok = p2.Instance("i3")
+	assert.False(t, ok)
+
+	// Marking all shards available keeps the dropped replicas.
+	p3, err := MarkAllShardsAsAvailable(p)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, p3.NumInstances())
+	i3, _ = p3.Instance("i3")
+	assert.Equal(t, 2, i3.Shards().NumShardsForState(shard.Leaving))
+
+	p4, err := RemoveAllDroppedReplicas(p3)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(p4))
+	assert.Equal(t, 2, p4.NumInstances())
+	for _, instance := range p4.Instances() {
+		assert.Equal(t, 1, instance.Shards().NumShards())
+		assert.Equal(t, 1, instance.Shards().NumShardsForState(shard.Available))
+	}
+}
+
+func TestValidateOrphanedLeavingReplica(t *testing.T) {
+	// i2 was handing shard 1 over to an instance that is no longer in the
+	// placement, its Leaving replica is not a dropped replica.
+	i1 := NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+
+	i2 := NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i2.Shards().Add(shard.NewShard(1).SetState(shard.Leaving))
+	i2.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+
+	p := NewPlacement().
+		SetInstances([]Instance{i1, i2}).
+		SetShards([]uint32{1, 2}).
+		SetReplicaFactor(2).
+		SetIsSharded(true)
+	assert.Error(t, Validate(p))
+
+	p1, err := RemoveDroppedReplicas(p, "i2")
+	assert.NoError(t, err)
+	i2, ok := p1.Instance("i2")
+	assert.True(t, ok)
+	assert.Equal(t, 1, i2.Shards().NumShardsForState(shard.Leaving))
+
+	p2, err := MarkAllShardsAsAvailable(p)
+	assert.NoError(t, err)
+	assert.Error(t, Validate(p2))
+}
+
 func TestValidateNoEndpoint(t *testing.T) {
 	i1 := NewEmptyInstance("i1", "r1", "z1", "", 1)
 	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
@@ -309,6 +398,51 @@ func TestValidateInstanceWithNoShard(t *testing.T) {
 	assert.Contains(t, err.Error(), "contains no shard")
 }
 
+func TestValidateWithOptions(t *testing.T) {
+	i1 := NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+
+	i2 := NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i2.Shards().Add(shard.NewShard(3).SetState(shard.Available))
+
+	p := NewPlacement().
+		SetInstances([]Instance{i1, i2}).
+		SetShards([]uint32{1, 2, 3}).
+		SetReplicaFactor(1).
+		SetIsSharded(true)
+
+	maxShardsPerInstance := func(max int) ValidateFn {
+		return func(p Placement) error {
+			for _, instance := range p.Instances() {
+				if n := instance.Shards().NumShards(); n > max {
+					return fmt.Errorf("instance %s owns %d shards", instance.ID(), n)
+				}
+			}
+			return nil
+		}
+	}
+
+	assert.NoError(t, ValidateWithOptions(p, NewOptions()))
+	assert.NoError(t, ValidateWithOptions(p, NewOptions().SetValidators([]Validator{
+		NewValidator("max-shards", maxShardsPerInstance(2)),
+	})))
+
+	err := ValidateWithOptions(p, NewOptions().SetValidators([]Validator{
+		NewValidator("max-shards", maxShardsPerInstance(1)),
+	}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "validator max-shards failed")
+	assert.Contains(t, err.Error(), "instance i1 owns 2 shards")
+
+	// The built-in checks run before the custom validators.
+	err = ValidateWithOptions(p.SetReplicaFactor(2), NewOptions().SetValidators([]Validator{
+		NewValidator("always-fail", func(Placement) error { return errors.New("failed") }),
+	}))
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "always-fail")
+}
+
 func TestInstance(t *testing.T) {
 	i1 := NewInstance().
 		SetID("id").
@@ -588,6 +722,46 @@ func TestConvertBetweenProtoAndPlacement(t *testing.T) {
 	}
 }
 
+func TestShardSplits(t *testing.T) {
+	i1 := NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(2).SetState(shard.Initializing))
+
+	i2 := NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i2.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i2.Shards().Add(shard.NewShard(3).SetState(shard.Initializing))
+
+	splits := map[uint32][]uint32{0: {0, 2}, 1: {1, 3}}
+	p := NewPlacement().
+		SetInstances([]Instance{i1, i2}).
+		SetShards([]uint32{0, 1, 2, 3}).
+		SetReplicaFactor(1).
+		SetIsSharded(true).
+		SetShardSplits(splits)
+	assert.NoError(t, Validate(p))
+	assert.Equal(t, splits, p.ShardSplits())
+
+	pb, err := p.Proto()
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1, 3}, pb.ShardSplits[1].ChildShards)
+	p2, err := NewPlacementFromProto(pb)
+	assert.NoError(t, err)
+	assert.Equal(t, splits, p2.ShardSplits())
+
+	// The splits are copied when the placement is cloned.
+	p3 := p.Clone()
+	assert.Equal(t, splits, p3.ShardSplits())
+	p3.ShardSplits()[0][1] = 4
+	assert.Equal(t, uint32(2), p.ShardSplits()[0][1])
+	err = Validate(p3)
+	assert.Error(t, err)
+	assert.Equal(t, "invalid placement, child shard 4 of parent shard 0 does not exist", err.Error())
+
+	pb, err = NewPlacement().SetShards([]uint32{0}).Proto()
+	assert.NoError(t, err)
+	assert.Nil(t, pb.ShardSplits)
+}
+
 func TestPlacementInstanceFromProto(t *testing.T) {
 	protoShardsUnsorted := getProtoShards([]uint32{2, 1, 0})
 
@@ -0,0 +1,190 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package placement
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/m3db/m3cluster/shard"
+)
+
+var (
+	errPlanFromUnstablePlacement = errors.New("could not plan from a placement with shards not available")
+	errPlanStepOutOfRange        = errors.New("the plan step is out of range")
+)
+
+// Plan is an ordered sequence of placements leading from a placement to a
+// target placement. Each step hands over at most the maximum number of shards
+// the plan was made with, and is to be applied only once all the shards of the
+// previous step are available. All the replica moves of a shard are made in
+// the same step, so a shard with more moves than the maximum gets a step of
+// its own. The cutover and cutoff times of a step are set when it is applied.
+type Plan struct {
+	// From is the placement the plan starts from.
+	From Placement
+
+	// Steps are the placements to apply in order, the last one reaches the target.
+	Steps []Placement
+}
+
+// NewPlan splits the transition from a placement with all its shards available
+// to a target placement with the same replica factor, as produced by a placement
+// algorithm, into steps each moving at most maxMoves shards. With maxMoves not
+// positive, the plan has the target as its single step. Each step is validated
+// with the options, as it will be when applied.
+func NewPlan(from, to Placement, maxMoves int, opts Options) (Plan, error) {
+	for _, instance := range from.Instances() {
+		shards := instance.Shards()
+		if shards.NumShardsForState(shard.Available) != shards.NumShards() {
+			return Plan{}, errPlanFromUnstablePlacement
+		}
+	}
+	if from.ReplicaFactor() != to.ReplicaFactor() {
+		return Plan{}, fmt.Errorf("could not plan from replica factor %d to %d", from.ReplicaFactor(), to.ReplicaFactor())
+	}
+
+	plan := Plan{From: from}
+	batches := batchMoves(NewPlacementDiff(from, to).Moves, maxMoves)
+	if maxMoves <= 0 || len(batches) <= 1 {
+		if err := ValidateWithOptions(to, opts); err != nil {
+			return Plan{}, fmt.Errorf("invalid plan step 0: %v", err)
+		}
+		plan.Steps = []Placement{to}
+		return plan, nil
+	}
+
+	cur := from
+	for i, batch := range batches {
+		step, err := planStep(cur, to, batch, i == 0)
+		if err != nil {
+			return Plan{}, err
+		}
+		if err := ValidateWithOptions(step, opts); err != nil {
+			return Plan{}, fmt.Errorf("invalid plan step %d: %v", i, err)
+		}
+		plan.Steps = append(plan.Steps, step)
+
+		if cur, err = MarkAllShardsAsAvailable(step); err != nil {
+			return Plan{}, err
+		}
+	}
+	return plan, nil
+}
+
+// Expected returns the placement expected before the step with the given
+// index is applied, which is the placement of the previous step with all its
+// shards available.
+func (p Plan) Expected(step int) (Placement, error) {
+	if step < 0 || step >= len(p.Steps) {
+		return nil, errPlanStepOutOfRange
+	}
+	if step == 0 {
+		return p.From, nil
+	}
+	return MarkAllShardsAsAvailable(p.Steps[step-1])
+}
+
+// batchMoves groups the moves by shard and packs the shards in order into
+// batches of at most maxMoves moves.
+func batchMoves(moves []ShardMove, maxMoves int) [][]ShardMove {
+	byShard := make(map[uint32][]ShardMove)
+	for _, m := range moves {
+		byShard[m.ShardID] = append(byShard[m.ShardID], m)
+	}
+	shardIDs := make([]uint32, 0, len(byShard))
+	for id := range byShard {
+		shardIDs = append(shardIDs, id)
+	}
+	sort.Sort(shard.SortableIDsAsc(shardIDs))
+
+	var (
+		batches [][]ShardMove
+		batch   []ShardMove
+	)
+	for _, id := range shardIDs {
+		shardMoves := byShard[id]
+		if len(batch) > 0 && maxMoves > 0 && len(batch)+len(shardMoves) > maxMoves {
+			batches = append(batches, batch)
+			batch = nil
+		}
+		batch = append(batch, shardMoves...)
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// planStep applies the moves on the placement, taking the moved shards and
+// the added instances from the target placement. The attributes of the
+// instances in both placements, such as their weights, are updated in the
+// first step.
+func planStep(cur, to Placement, moves []ShardMove, first bool) (Placement, error) {
+	p := cur.Clone()
+	if first {
+		for _, instance := range p.Instances() {
+			if target, ok := to.Instance(instance.ID()); ok {
+				instance.SetWeight(target.Weight())
+			}
+		}
+	}
+
+	instances := p.Instances()
+	for _, m := range moves {
+		if m.From != "" {
+			instance, ok := p.Instance(m.From)
+			if !ok {
+				return nil, fmt.Errorf("instance %s does not exist in placement", m.From)
+			}
+			leaving := shard.NewShard(m.ShardID).SetState(shard.Leaving)
+			if target, ok := to.Instance(m.From); ok {
+				if s, ok := target.Shards().Shard(m.ShardID); ok {
+					leaving = s.Clone()
+				}
+			}
+			instance.Shards().Add(leaving)
+		}
+
+		if m.To != "" {
+			target, ok := to.Instance(m.To)
+			if !ok {
+				return nil, fmt.Errorf("instance %s does not exist in the target placement", m.To)
+			}
+			s, ok := target.Shards().Shard(m.ShardID)
+			if !ok {
+				return nil, fmt.Errorf("shard %d does not exist on instance %s in the target placement", m.ShardID, m.To)
+			}
+			instance, ok := p.Instance(m.To)
+			if !ok {
+				instance = target.Clone().SetShards(shard.NewShards(nil))
+				instances = append(instances, instance)
+				p = p.SetInstances(instances)
+			}
+			// NB: the source is set to the paired replica as the target may
+			// hand the shard over from another one.
+			instance.Shards().Add(s.Clone().SetSourceID(m.From))
+		}
+	}
+
+	return p.SetInstances(instances).SetCutoverNanos(to.CutoverNanos()), nil
+}
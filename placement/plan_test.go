@@ -0,0 +1,166 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package placement
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/m3db/m3cluster/shard"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPlanPlacements returns a placement with 4 shards on i1 and i2, and the
+// placement adding i3, which takes shards 0 and 1 from i1 and shard 2 from i2.
+func testPlanPlacements() (Placement, Placement) {
+	i1 := NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	for id := uint32(0); id < 4; id++ {
+		i1.Shards().Add(shard.NewShard(id).SetState(shard.Available))
+		i2.Shards().Add(shard.NewShard(id).SetState(shard.Available))
+	}
+	from := NewPlacement().
+		SetInstances([]Instance{i1, i2}).
+		SetShards([]uint32{0, 1, 2, 3}).
+		SetReplicaFactor(2).
+		SetIsSharded(true)
+
+	to := from.Clone()
+	i1, _ = to.Instance("i1")
+	i2, _ = to.Instance("i2")
+	i3 := NewEmptyInstance("i3", "r3", "z1", "endpoint", 2)
+	for _, id := range []uint32{0, 1} {
+		i1.Shards().Add(shard.NewShard(id).SetState(shard.Leaving).SetCutoffNanos(100))
+		i3.Shards().Add(shard.NewShard(id).SetState(shard.Initializing).SetSourceID("i1").SetCutoverNanos(200))
+	}
+	i2.Shards().Add(shard.NewShard(2).SetState(shard.Leaving).SetCutoffNanos(100))
+	i3.Shards().Add(shard.NewShard(2).SetState(shard.Initializing).SetSourceID("i2").SetCutoverNanos(200))
+	to = to.SetInstances([]Instance{i1, i2, i3}).SetCutoverNanos(300)
+	return from, to
+}
+
+func TestNewPlan(t *testing.T) {
+	from, to := testPlanPlacements()
+	require.NoError(t, Validate(to))
+
+	plan, err := NewPlan(from, to, 2, NewOptions())
+	require.NoError(t, err)
+	require.Equal(t, 2, len(plan.Steps))
+	assert.Equal(t, from, plan.From)
+
+	step := plan.Steps[0]
+	assert.NoError(t, Validate(step))
+	assert.Equal(t, int64(300), step.CutoverNanos())
+	i1, _ := step.Instance("i1")
+	assert.Equal(t, 2, i1.Shards().NumShardsForState(shard.Leaving))
+	i3, ok := step.Instance("i3")
+	require.True(t, ok)
+	assert.Equal(t, []uint32{0, 1}, i3.Shards().AllIDs())
+	s, _ := i3.Shards().Shard(0)
+	assert.Equal(t, shard.Initializing, s.State())
+	assert.Equal(t, "i1", s.SourceID())
+	assert.Equal(t, int64(200), s.CutoverNanos())
+	s, _ = i1.Shards().Shard(0)
+	assert.Equal(t, int64(100), s.CutoffNanos())
+
+	expected, err := plan.Expected(1)
+	require.NoError(t, err)
+	i1, _ = expected.Instance("i1")
+	assert.Equal(t, []uint32{2, 3}, i1.Shards().AllIDs())
+
+	step = plan.Steps[1]
+	assert.NoError(t, Validate(step))
+	i2, _ := step.Instance("i2")
+	assert.Equal(t, 1, i2.Shards().NumShardsForState(shard.Leaving))
+	i3, _ = step.Instance("i3")
+	assert.Equal(t, 1, i3.Shards().NumShardsForState(shard.Initializing))
+	assert.Equal(t, 2, i3.Shards().NumShardsForState(shard.Available))
+
+	// The plan reaches the target.
+	last, err := MarkAllShardsAsAvailable(step)
+	require.NoError(t, err)
+	target, err := MarkAllShardsAsAvailable(to)
+	require.NoError(t, err)
+	assert.True(t, NewPlacementDiff(target, last).IsEmpty())
+
+	_, err = plan.Expected(2)
+	assert.Equal(t, errPlanStepOutOfRange, err)
+	expected, err = plan.Expected(0)
+	require.NoError(t, err)
+	assert.Equal(t, from, expected)
+}
+
+func TestNewPlanSingleStep(t *testing.T) {
+	from, to := testPlanPlacements()
+	for _, maxMoves := range []int{0, 3, 10} {
+		plan, err := NewPlan(from, to, maxMoves, NewOptions())
+		require.NoError(t, err)
+		require.Equal(t, 1, len(plan.Steps))
+		assert.Equal(t, to, plan.Steps[0])
+	}
+
+	// A shard with more moves than the maximum gets a step of its own.
+	plan, err := NewPlan(from, to, 1, NewOptions())
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(plan.Steps))
+}
+
+func TestNewPlanErrors(t *testing.T) {
+	from, to := testPlanPlacements()
+	_, err := NewPlan(to, from, 1, NewOptions())
+	assert.Equal(t, errPlanFromUnstablePlacement, err)
+
+	_, err = NewPlan(from, to.Clone().SetReplicaFactor(3), 1, NewOptions())
+	assert.Error(t, err)
+}
+
+func TestNewPlanValidatesStepsWithOptions(t *testing.T) {
+	from, to := testPlanPlacements()
+	// The validator only accepts instances taking all their shards at once,
+	// which holds for the target but not for the second step.
+	opts := NewOptions().SetValidators([]Validator{
+		NewValidator("all-at-once", func(p Placement) error {
+			for _, instance := range p.Instances() {
+				shards := instance.Shards()
+				if shards.NumShardsForState(shard.Initializing) > 0 && shards.NumShardsForState(shard.Available) > 0 {
+					return fmt.Errorf("instance %s takes shards in several steps", instance.ID())
+				}
+			}
+			return nil
+		}),
+	})
+	require.NoError(t, ValidateWithOptions(to, opts))
+
+	_, err := NewPlan(from, to, 2, opts)
+	assert.Error(t, err)
+
+	plan, err := NewPlan(from, to, 0, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(plan.Steps))
+
+	_, err = NewPlan(from, to, 0, NewOptions().SetValidators([]Validator{
+		NewValidator("reject", func(Placement) error { return errors.New("rejected") }),
+	}))
+	assert.Error(t, err)
+}
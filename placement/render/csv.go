@@ -0,0 +1,117 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
+)
+
+// CSV renders the placement as a shard by instance matrix in CSV, with a row
+// per shard and a column per instance. Each cell holds the state of the shard
+// on the instance, A for Available, I for Initializing and L for Leaving, or
+// is empty when the instance does not own the shard.
+func CSV(p placement.Placement) ([]byte, error) {
+	ids := instanceIDs(p)
+	return writeMatrix(ids, shardIDs(p), func(shardID uint32, instanceID string) string {
+		return stateIn(p, instanceID, shardID)
+	})
+}
+
+// DiffCSV renders the differences between two placements as a shard by
+// instance matrix in CSV. Each cell holds the state of the shard on the
+// instance as in CSV when it is unchanged, or the old and the new states
+// separated by an arrow when it has changed, with - standing for a shard the
+// instance does not own, e.g. -> I for a shard added to the instance.
+func DiffCSV(from, to placement.Placement) ([]byte, error) {
+	ids := instanceIDs(from, to)
+	return writeMatrix(ids, shardIDs(from, to), func(shardID uint32, instanceID string) string {
+		oldState, newState := stateIn(from, instanceID, shardID), stateIn(to, instanceID, shardID)
+		if oldState == newState {
+			return newState
+		}
+		if oldState == "" {
+			oldState = "-"
+		}
+		if newState == "" {
+			newState = "-"
+		}
+		return oldState + "->" + newState
+	})
+}
+
+func writeMatrix(
+	instanceIDs []string,
+	shardIDs []uint32,
+	cellFn func(shardID uint32, instanceID string) string,
+) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		w   = csv.NewWriter(&buf)
+		row = make([]string, len(instanceIDs)+1)
+	)
+	row[0] = "shard"
+	copy(row[1:], instanceIDs)
+	if err := w.Write(row); err != nil {
+		return nil, err
+	}
+
+	for _, shardID := range shardIDs {
+		row[0] = strconv.FormatUint(uint64(shardID), 10)
+		for i, instanceID := range instanceIDs {
+			row[i+1] = cellFn(shardID, instanceID)
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func stateIn(p placement.Placement, instanceID string, shardID uint32) string {
+	instance, ok := p.Instance(instanceID)
+	if !ok {
+		return ""
+	}
+	s, ok := instance.Shards().Shard(shardID)
+	if !ok {
+		return ""
+	}
+	switch s.State() {
+	case shard.Available:
+		return "A"
+	case shard.Initializing:
+		return "I"
+	case shard.Leaving:
+		return "L"
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSV(t *testing.T) {
+	_, p, _ := testPlacements()
+	b, err := CSV(p)
+	require.NoError(t, err)
+	expected := "shard,i1,i2,i3\n" +
+		"0,A,A,\n" +
+		"1,A,L,I\n"
+	assert.Equal(t, expected, string(b))
+}
+
+func TestDiffCSV(t *testing.T) {
+	before, during, after := testPlacements()
+	b, err := DiffCSV(before, during)
+	require.NoError(t, err)
+	expected := "shard,i1,i2,i3\n" +
+		"0,A,A,\n" +
+		"1,A,A->L,-->I\n"
+	assert.Equal(t, expected, string(b))
+
+	b, err = DiffCSV(during, after)
+	require.NoError(t, err)
+	expected = "shard,i1,i2,i3\n" +
+		"0,A,A,\n" +
+		"1,A,L->-,I->A\n"
+	assert.Equal(t, expected, string(b))
+}
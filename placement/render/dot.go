@@ -0,0 +1,182 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
+)
+
+const noInstance = "(none)"
+
+type edge struct {
+	from, to string
+}
+
+// DOT renders the placement as a Graphviz DOT graph, with the instances
+// grouped by rack and an edge from each instance handing shards over to
+// each instance taking them, labeled with the number of shards.
+func DOT(p placement.Placement) string {
+	var buf bytes.Buffer
+	buf.WriteString("digraph placement {\n")
+	buf.WriteString("  rankdir=LR;\n")
+	buf.WriteString("  node [shape=box];\n")
+
+	racks, byRack := instancesByRack(p.Instances())
+	for _, rack := range racks {
+		writeRack(&buf, rack, byRack[rack], func(instance placement.Instance) string {
+			shards := instance.Shards()
+			return fmt.Sprintf("%s\nweight %d\n%d available, %d initializing, %d leaving",
+				instance.ID(), instance.Weight(),
+				shards.NumShardsForState(shard.Available),
+				shards.NumShardsForState(shard.Initializing),
+				shards.NumShardsForState(shard.Leaving))
+		}, nil)
+	}
+
+	handoffs := make(map[edge]int)
+	for _, instance := range p.Instances() {
+		for _, s := range instance.Shards().ShardsForState(shard.Initializing) {
+			if s.SourceID() != "" {
+				handoffs[edge{from: s.SourceID(), to: instance.ID()}]++
+			}
+		}
+	}
+	writeEdges(&buf, handoffs)
+
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// DiffDOT renders the differences between two placements as a Graphviz DOT
+// graph, with the added instances in green, the removed instances in red, the
+// other changed instances in orange, and an edge for each pair of instances
+// shards moved between, labeled with the number of shards. Shards added or
+// removed without a counterpart move from or to a (none) node.
+func DiffDOT(from, to placement.Placement) string {
+	d := placement.NewPlacementDiff(from, to)
+
+	var buf bytes.Buffer
+	buf.WriteString("digraph diff {\n")
+	buf.WriteString("  rankdir=LR;\n")
+	buf.WriteString("  node [shape=box];\n")
+
+	var (
+		instances = make([]placement.Instance, 0, len(d.Instances))
+		diffs     = make(map[string]placement.InstanceDiff, len(d.Instances))
+	)
+	for _, instanceDiff := range d.Instances {
+		if instance, ok := instanceIn(instanceDiff.InstanceID, to, from); ok {
+			instances = append(instances, instance)
+			diffs[instanceDiff.InstanceID] = instanceDiff
+		}
+	}
+
+	racks, byRack := instancesByRack(instances)
+	for _, rack := range racks {
+		writeRack(&buf, rack, byRack[rack], func(instance placement.Instance) string {
+			instanceDiff := diffs[instance.ID()]
+			return fmt.Sprintf("%s\n+%d -%d ~%d",
+				instance.ID(), len(instanceDiff.AddedShards), len(instanceDiff.RemovedShards),
+				len(instanceDiff.StateChanges))
+		}, func(instance placement.Instance) string {
+			instanceDiff := diffs[instance.ID()]
+			switch {
+			case instanceDiff.IsAdded:
+				return "green"
+			case instanceDiff.IsRemoved:
+				return "red"
+			default:
+				return "orange"
+			}
+		})
+	}
+
+	moves := make(map[edge]int, len(d.Moves))
+	for _, m := range d.Moves {
+		e := edge{from: m.From, to: m.To}
+		if e.from == "" {
+			e.from = noInstance
+		}
+		if e.to == "" {
+			e.to = noInstance
+		}
+		moves[e]++
+	}
+	writeEdges(&buf, moves)
+
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+func writeRack(
+	buf *bytes.Buffer,
+	rack string,
+	instances []placement.Instance,
+	labelFn func(instance placement.Instance) string,
+	colorFn func(instance placement.Instance) string,
+) {
+	fmt.Fprintf(buf, "  subgraph %q {\n", "cluster_"+rack)
+	fmt.Fprintf(buf, "    label=%q;\n", rack)
+	for _, instance := range instances {
+		if colorFn == nil {
+			fmt.Fprintf(buf, "    %q [label=%q];\n", instance.ID(), labelFn(instance))
+			continue
+		}
+		fmt.Fprintf(buf, "    %q [label=%q, color=%s];\n", instance.ID(), labelFn(instance), colorFn(instance))
+	}
+	buf.WriteString("  }\n")
+}
+
+func writeEdges(buf *bytes.Buffer, counts map[edge]int) {
+	edges := make([]edge, 0, len(counts))
+	for e := range counts {
+		edges = append(edges, e)
+	}
+	sort.Sort(edgesByFromTo(edges))
+	for _, e := range edges {
+		fmt.Fprintf(buf, "  %q -> %q [label=%q];\n", e.from, e.to, shardCount(counts[e]))
+	}
+}
+
+type edgesByFromTo []edge
+
+func (s edgesByFromTo) Len() int { return len(s) }
+
+func (s edgesByFromTo) Less(i, j int) bool {
+	if s[i].from == s[j].from {
+		return s[i].to < s[j].to
+	}
+	return s[i].from < s[j].from
+}
+
+func (s edgesByFromTo) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func shardCount(n int) string {
+	if n == 1 {
+		return "1 shard"
+	}
+	return fmt.Sprintf("%d shards", n)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDOT(t *testing.T) {
+	_, p, _ := testPlacements()
+	expected := `digraph placement {
+  rankdir=LR;
+  node [shape=box];
+  subgraph "cluster_z1/r1" {
+    label="z1/r1";
+    "i1" [label="i1\nweight 1\n2 available, 0 initializing, 0 leaving"];
+  }
+  subgraph "cluster_z1/r2" {
+    label="z1/r2";
+    "i2" [label="i2\nweight 1\n1 available, 0 initializing, 1 leaving"];
+    "i3" [label="i3\nweight 2\n0 available, 1 initializing, 0 leaving"];
+  }
+  "i2" -> "i3" [label="1 shard"];
+}
+`
+	assert.Equal(t, expected, DOT(p))
+}
+
+func TestDiffDOT(t *testing.T) {
+	before, during, after := testPlacements()
+	expected := `digraph diff {
+  rankdir=LR;
+  node [shape=box];
+  subgraph "cluster_z1/r2" {
+    label="z1/r2";
+    "i2" [label="i2\n+0 -0 ~1", color=orange];
+    "i3" [label="i3\n+1 -0 ~0", color=green];
+  }
+  "i2" -> "i3" [label="1 shard"];
+}
+`
+	assert.Equal(t, expected, DiffDOT(before, during))
+
+	expected = `digraph diff {
+  rankdir=LR;
+  node [shape=box];
+  subgraph "cluster_z1/r2" {
+    label="z1/r2";
+    "i2" [label="i2\n+0 -1 ~0", color=orange];
+    "i3" [label="i3\n+0 -0 ~1", color=orange];
+  }
+}
+`
+	assert.Equal(t, expected, DiffDOT(during, after))
+}
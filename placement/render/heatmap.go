@@ -0,0 +1,80 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package render
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/m3db/m3cluster/placement"
+)
+
+// heatmapRamp are the cells of the heatmap from the lowest to the highest load.
+const heatmapRamp = " .:-=+*#%@"
+
+// Heatmap renders the load of the placement as a compact ASCII heatmap, with
+// a line per rack holding a cell per instance in id order. The cell of an
+// instance shades from blank for no load to @ for the highest load on any
+// instance, and each line ends with the load on the rack. The load counts the
+// shards that are not leaving.
+func Heatmap(p placement.Placement) string {
+	var (
+		instances      = p.Instances()
+		racks, byRack  = instancesByRack(instances)
+		maxLoad        = 0
+		rackNameLength = 0
+	)
+	for _, instance := range instances {
+		if l := load(instance); l > maxLoad {
+			maxLoad = l
+		}
+	}
+	for _, rack := range racks {
+		if len(rack) > rackNameLength {
+			rackNameLength = len(rack)
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "load per instance, %q from 0 to %d\n", heatmapRamp, maxLoad)
+	for _, rack := range racks {
+		var (
+			cells    = make([]byte, len(byRack[rack]))
+			rackLoad = 0
+		)
+		for i, instance := range byRack[rack] {
+			l := load(instance)
+			cells[i] = heatmapCell(l, maxLoad)
+			rackLoad += l
+		}
+		fmt.Fprintf(&buf, "%-*s |%s| load %d\n", rackNameLength, rack, cells, rackLoad)
+	}
+	return buf.String()
+}
+
+// heatmapCell returns the cell for the load, any load above zero is shaded.
+func heatmapCell(load, maxLoad int) byte {
+	if load <= 0 || maxLoad <= 0 {
+		return heatmapRamp[0]
+	}
+	idx := 1 + (load*(len(heatmapRamp)-1)-1)/maxLoad
+	return heatmapRamp[idx]
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeatmap(t *testing.T) {
+	before, p, _ := testPlacements()
+	expected := `load per instance, " .:-=+*#%@" from 0 to 2
+z1/r1 |@| load 2
+z1/r2 |++| load 2
+`
+	assert.Equal(t, expected, Heatmap(p))
+
+	expected = `load per instance, " .:-=+*#%@" from 0 to 2
+z1/r1 |@| load 2
+z1/r2 |@| load 2
+`
+	assert.Equal(t, expected, Heatmap(before))
+}
+
+func TestHeatmapCell(t *testing.T) {
+	assert.Equal(t, byte(' '), heatmapCell(0, 100))
+	assert.Equal(t, byte('.'), heatmapCell(1, 100))
+	assert.Equal(t, byte('+'), heatmapCell(50, 100))
+	assert.Equal(t, byte('@'), heatmapCell(100, 100))
+	assert.Equal(t, byte(' '), heatmapCell(0, 0))
+}
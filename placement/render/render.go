@@ -0,0 +1,104 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package render renders placements and the differences between placements
+// as Graphviz DOT graphs, shard by instance CSV matrices and ASCII heatmaps,
+// for use in tickets and runbooks.
+package render
+
+import (
+	"sort"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
+)
+
+// load returns the number of shards owned by the instance that are not leaving.
+func load(instance placement.Instance) int {
+	return instance.Shards().NumShards() - instance.Shards().NumShardsForState(shard.Leaving)
+}
+
+// rackOf returns the zone qualified rack of the instance.
+func rackOf(instance placement.Instance) string {
+	return instance.Zone() + "/" + instance.Rack()
+}
+
+// instancesByRack groups the instances by their zone qualified racks, and
+// returns the racks in order.
+func instancesByRack(instances []placement.Instance) ([]string, map[string][]placement.Instance) {
+	byRack := make(map[string][]placement.Instance)
+	for _, instance := range instances {
+		rack := rackOf(instance)
+		byRack[rack] = append(byRack[rack], instance)
+	}
+
+	racks := make([]string, 0, len(byRack))
+	for rack, instances := range byRack {
+		racks = append(racks, rack)
+		sort.Sort(placement.ByIDAscending(instances))
+	}
+	sort.Strings(racks)
+	return racks, byRack
+}
+
+// instanceIn returns the instance with the id from the first placement that
+// contains it.
+func instanceIn(id string, ps ...placement.Placement) (placement.Instance, bool) {
+	for _, p := range ps {
+		if instance, ok := p.Instance(id); ok {
+			return instance, true
+		}
+	}
+	return nil, false
+}
+
+// shardIDs returns the ids of the shards in any of the placements in order.
+func shardIDs(ps ...placement.Placement) []uint32 {
+	seen := make(map[uint32]struct{})
+	var ids []uint32
+	for _, p := range ps {
+		for _, id := range p.Shards() {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(shard.SortableIDsAsc(ids))
+	return ids
+}
+
+// instanceIDs returns the ids of the instances in any of the placements in order.
+func instanceIDs(ps ...placement.Placement) []string {
+	seen := make(map[string]struct{})
+	var ids []string
+	for _, p := range ps {
+		for _, instance := range p.Instances() {
+			if _, ok := seen[instance.ID()]; ok {
+				continue
+			}
+			seen[instance.ID()] = struct{}{}
+			ids = append(ids, instance.ID())
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package render
+
+import (
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
+)
+
+// testPlacements returns a placement of two shards on i1 and i2, the placement
+// where i2 hands shard 1 over to the new instance i3, and the placement once
+// the handoff has completed.
+func testPlacements() (before, during, after placement.Placement) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint1", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint2", 1)
+	i2.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i2.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	before = placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2}).
+		SetShards([]uint32{0, 1}).
+		SetReplicaFactor(2).
+		SetIsSharded(true)
+
+	during = before.Clone()
+	i2, _ = during.Instance("i2")
+	i2.Shards().Add(shard.NewShard(1).SetState(shard.Leaving))
+	i3 := placement.NewEmptyInstance("i3", "r2", "z1", "endpoint3", 2)
+	i3.Shards().Add(shard.NewShard(1).SetState(shard.Initializing).SetSourceID("i2"))
+	during = during.SetInstances(append(during.Instances(), i3))
+
+	after, err := placement.MarkAllShardsAsAvailable(during)
+	if err != nil {
+		panic(err)
+	}
+	return before, during, after
+}
@@ -68,6 +68,10 @@ func filterZones(
 
 	var validZone string
 	if opts != nil {
+		if spansZones(opts) {
+			// All zones are valid when spreading replicas across zones.
+			return candidates
+		}
 		validZone = opts.ValidZone()
 	}
 	if validZone == "" && len(p.Instances()) > 0 {
@@ -82,3 +86,17 @@ func filterZones(
 	}
 	return validInstances
 }
+
+// spansZones returns whether the replicas of each shard may be spread across zones.
+func spansZones(opts placement.Options) bool {
+	policy := opts.FailureDomainPolicy()
+	return opts.SpreadAcrossZones() ||
+		policy.HasLevel(placement.RegionLevel) ||
+		policy.HasLevel(placement.ZoneLevel)
+}
+
+// usesFailureDomains returns whether the replicas of each shard are isolated by
+// the failure domain policy rather than by racks alone.
+func usesFailureDomains(opts placement.Options) bool {
+	return opts.SpreadAcrossZones() || !opts.FailureDomainPolicy().IsEmpty()
+}
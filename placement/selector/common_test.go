@@ -52,3 +52,14 @@ func TestGetValidCandidates(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []placement.Instance{i3, i3, i4}, res)
 }
+
+func TestFilterZonesSpreadAcrossZones(t *testing.T) {
+	i1 := placement.NewInstance().SetID("i1").SetZone("z1")
+	p := placement.NewPlacement().SetInstances([]placement.Instance{i1})
+
+	i2 := placement.NewInstance().SetID("i2").SetZone("z2")
+	i3 := placement.NewInstance().SetID("i3").SetZone("z1")
+	candidates := []placement.Instance{i2, i3}
+	require.Equal(t, []placement.Instance{i3}, filterZones(p, candidates, placement.NewOptions()))
+	require.Equal(t, candidates, filterZones(p, candidates, placement.NewOptions().SetSpreadAcrossZones(true)))
+}
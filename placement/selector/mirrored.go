@@ -57,14 +57,16 @@ func (f *mirroredFilter) SelectInitialInstances(
 		return nil, err
 	}
 
-	weightToHostMap, err := groupHostsByWeight(candidates)
+	policy := f.opts.FailureDomainPolicy()
+	weightToHostMap, err := groupHostsByWeight(candidates, policy)
 	if err != nil {
 		return nil, err
 	}
 
+	hasConflict := newHostConflictFn(policy, rf, candidates)
 	var groups = make([][]placement.Instance, 0, len(candidates))
 	for _, hosts := range weightToHostMap {
-		groupedHosts, ungrouped := groupHostsWithRackCheck(hosts, rf)
+		groupedHosts, ungrouped := groupHostsWithRackCheck(hosts, rf, hasConflict)
 		if len(ungrouped) != 0 {
 			for _, host := range ungrouped {
 				f.logger.Warnf("could not group host %s, rack %s, weight %d", host.name, host.rack, host.weight)
@@ -95,19 +97,22 @@ func (f *mirroredFilter) SelectAddingInstances(
 	candidates []placement.Instance,
 	p placement.Placement,
 ) ([]placement.Instance, error) {
-	candidates, err := getValidCandidates(p, candidates, f.opts)
+	opts := placement.OptionsWithPlacementPolicy(p, f.opts)
+	candidates, err := getValidCandidates(p, candidates, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	weightToHostMap, err := groupHostsByWeight(candidates)
+	policy := opts.FailureDomainPolicy()
+	weightToHostMap, err := groupHostsByWeight(candidates, policy)
 	if err != nil {
 		return nil, err
 	}
 
+	hasConflict := newHostConflictFn(policy, p.ReplicaFactor(), append(p.Instances(), candidates...))
 	var groups = make([][]placement.Instance, 0, len(candidates))
 	for _, hosts := range weightToHostMap {
-		groupedHosts, _ := groupHostsWithRackCheck(hosts, p.ReplicaFactor())
+		groupedHosts, _ := groupHostsWithRackCheck(hosts, p.ReplicaFactor(), hasConflict)
 		if len(groupedHosts) == 0 {
 			continue
 		}
@@ -136,7 +141,8 @@ func (f *mirroredFilter) SelectReplaceInstances(
 	leavingInstanceIDs []string,
 	p placement.Placement,
 ) ([]placement.Instance, error) {
-	candidates, err := getValidCandidates(p, candidates, f.opts)
+	opts := placement.OptionsWithPlacementPolicy(p, f.opts)
+	candidates, err := getValidCandidates(p, candidates, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -167,7 +173,8 @@ func (f *mirroredFilter) SelectReplaceInstances(
 		ssIDs[instance.ShardSetID()] = struct{}{}
 	}
 
-	weightToHostMap, err := groupHostsByWeight(candidates)
+	policy := opts.FailureDomainPolicy()
+	weightToHostMap, err := groupHostsByWeight(candidates, policy)
 	if err != nil {
 		return nil, err
 	}
@@ -177,8 +184,13 @@ func (f *mirroredFilter) SelectReplaceInstances(
 		return nil, fmt.Errorf("could not find instances with weight %d in the candidate list", h.weight)
 	}
 
-	// Find out the racks that are already in the same shard set id with the leaving instances.
-	var conflictRacks = make(map[string]struct{})
+	// Find out the racks that are already in the same shard set id with the leaving instances,
+	// or the hosts holding the other replicas of each shard set when following a failure domain policy.
+	var (
+		conflictRacks = make(map[string]struct{})
+		shardSetHosts = make(map[uint32][]host, len(ssIDs))
+		hasConflict   = newHostConflictFn(policy, p.ReplicaFactor(), append(p.Instances(), candidates...))
+	)
 	for _, instance := range p.Instances() {
 		if _, ok := ssIDs[instance.ShardSetID()]; !ok {
 			continue
@@ -191,6 +203,9 @@ func (f *mirroredFilter) SelectReplaceInstances(
 		}
 
 		conflictRacks[instance.Rack()] = struct{}{}
+		otherHost := newHost(instance.Hostname(), instance.Rack(), instance.Weight())
+		otherHost.domains = hostDomains(policy, instance)
+		shardSetHosts[instance.ShardSetID()] = append(shardSetHosts[instance.ShardSetID()], otherHost)
 	}
 
 	var groups [][]placement.Instance
@@ -199,7 +214,11 @@ func (f *mirroredFilter) SelectReplaceInstances(
 			continue
 		}
 
-		if _, ok := conflictRacks[candidateHost.rack]; ok {
+		if policy.IsEmpty() {
+			if _, ok := conflictRacks[candidateHost.rack]; ok {
+				continue
+			}
+		} else if hasShardSetConflict(shardSetHosts, candidateHost, hasConflict) {
 			continue
 		}
 
@@ -247,7 +266,19 @@ func findIndex(ids []string, id string) int {
 	return -1
 }
 
-func groupHostsByWeight(candidates []placement.Instance) (map[uint32][]host, error) {
+func hasShardSetConflict(shardSetHosts map[uint32][]host, candidate host, hasConflict hostConflictFn) bool {
+	for _, hosts := range shardSetHosts {
+		if hasConflict(hosts, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func groupHostsByWeight(
+	candidates []placement.Instance,
+	policy placement.FailureDomainPolicy,
+) (map[uint32][]host, error) {
 	var (
 		uniqueHosts      = make(map[string]host, len(candidates))
 		weightToHostsMap = make(map[uint32][]host, len(candidates))
@@ -258,6 +289,7 @@ func groupHostsByWeight(candidates []placement.Instance) (map[uint32][]host, err
 		h, ok := uniqueHosts[hostname]
 		if !ok {
 			h = newHost(hostname, instance.Rack(), weight)
+			h.domains = hostDomains(policy, instance)
 			uniqueHosts[hostname] = h
 			weightToHostsMap[weight] = append(weightToHostsMap[weight], h)
 		}
@@ -269,10 +301,43 @@ func groupHostsByWeight(candidates []placement.Instance) (map[uint32][]host, err
 	return weightToHostsMap, nil
 }
 
+// hostConflictFn checks whether adding the host to the group of hosts would
+// violate the failure domain policy.
+type hostConflictFn func(group []host, h host) bool
+
+// newHostConflictFn returns a hostConflictFn for the failure domain policy, the
+// number of domains at each level is counted among the given instances.
+func newHostConflictFn(
+	policy placement.FailureDomainPolicy,
+	rf int,
+	instances []placement.Instance,
+) hostConflictFn {
+	if policy.IsEmpty() {
+		// The hosts in a group are on different racks, which is guaranteed by grouping.
+		return func([]host, host) bool { return false }
+	}
+	numDomains := policy.NumDomains(instances)
+	return func(group []host, h host) bool {
+		for level, domain := range h.domains {
+			replicas := 0
+			for _, other := range group {
+				if other.domains[level] == domain {
+					replicas++
+				}
+			}
+			if replicas >= policy.MaxReplicas(level, rf, numDomains[level]) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 // groupHostsWithRackCheck looks at the racks of the given hosts
 // and try to make as many groups as possible. The hosts in each group
-// must come from different racks.
-func groupHostsWithRackCheck(hosts []host, rf int) ([][]host, []host) {
+// must come from different racks, or from different innermost failure
+// domains and satisfy the failure domain policy when one is given.
+func groupHostsWithRackCheck(hosts []host, rf int, hasConflict hostConflictFn) ([][]host, []host) {
 	if len(hosts) < rf {
 		// When the number of hosts is less than rf, no groups can be made.
 		return nil, hosts
@@ -283,14 +348,15 @@ func groupHostsWithRackCheck(hosts []host, rf int) ([][]host, []host) {
 		rh        = racksByNumHost(make([]*rack, 0, len(hosts)))
 	)
 	for _, h := range hosts {
-		r, ok := uniqRacks[h.rack]
+		domain := h.domain()
+		r, ok := uniqRacks[domain]
 		if !ok {
 			r = &rack{
-				rack:  h.rack,
+				rack:  domain,
 				hosts: make([]host, 0, rf),
 			}
 
-			uniqRacks[h.rack] = r
+			uniqRacks[domain] = r
 			rh = append(rh, r)
 		}
 		r.hosts = append(r.hosts, h)
@@ -304,24 +370,43 @@ func groupHostsWithRackCheck(hosts []host, rf int) ([][]host, []host) {
 	res := make([][]host, 0, int(math.Ceil(float64(len(hosts))/float64(rf))))
 	for rh.Len() >= rf {
 		// When there are more than rf racks available, try to make a group.
-		seenRacks := make(map[string]*rack, rf)
-		groups := make([]host, 0, rf)
-		for i := 0; i < rf; i++ {
+		var (
+			seenRacks   = make(map[string]*rack, rf)
+			groups      = make([]host, 0, rf)
+			groupsRacks = make([]*rack, 0, rf)
+		)
+		for len(groups) < rf && rh.Len() > 0 {
 			r := heap.Pop(&rh).(*rack)
-			// Move the host from the rack to the group. The racks in the heap
-			// always have at least one host.
-			groups = append(groups, r.hosts[len(r.hosts)-1])
-			r.hosts = r.hosts[:len(r.hosts)-1]
 			seenRacks[r.rack] = r
+			// The racks in the heap always have at least one host.
+			h := r.hosts[len(r.hosts)-1]
+			if hasConflict(groups, h) {
+				// The hosts of the rack share the same failure domains, so the
+				// rack is skipped for this group.
+				continue
+			}
+			// Move the host from the rack to the group.
+			groups = append(groups, h)
+			groupsRacks = append(groupsRacks, r)
+			r.hosts = r.hosts[:len(r.hosts)-1]
 		}
-		if len(groups) == rf {
+		full := len(groups) == rf
+		if full {
 			res = append(res, groups)
+		} else {
+			// No more groups could be made, return the hosts back to their racks.
+			for i, h := range groups {
+				groupsRacks[i].hosts = append(groupsRacks[i].hosts, h)
+			}
 		}
 		for _, r := range seenRacks {
 			if len(r.hosts) > 0 {
 				heap.Push(&rh, r)
 			}
 		}
+		if !full {
+			break
+		}
 	}
 
 	ungrouped := make([]host, 0, rh.Len())
@@ -399,6 +484,7 @@ type host struct {
 	name           string
 	rack           string
 	weight         uint32
+	domains        []string
 	portToInstance map[uint32]placement.Instance
 }
 
@@ -411,6 +497,27 @@ func newHost(name, rack string, weight uint32) host {
 	}
 }
 
+// hostDomains returns the domains of the instance at each level of the policy.
+func hostDomains(policy placement.FailureDomainPolicy, instance placement.Instance) []string {
+	if policy.IsEmpty() {
+		return nil
+	}
+	domains := make([]string, policy.NumLevels())
+	for level := range domains {
+		domains[level] = policy.Domain(instance, level)
+	}
+	return domains
+}
+
+// domain returns the innermost failure domain of the host, which is
+// the rack of the host unless a failure domain policy is given.
+func (h host) domain() string {
+	if len(h.domains) == 0 {
+		return h.rack
+	}
+	return h.domains[len(h.domains)-1]
+}
+
 func (h host) addInstance(port uint32, instance placement.Instance) error {
 	if h.weight != instance.Weight() {
 		return fmt.Errorf("could not add instance %s to host %s, weight mismatch: %d and %d",
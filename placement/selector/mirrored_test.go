@@ -21,6 +21,7 @@
 package selector
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/m3db/m3cluster/placement"
@@ -117,6 +118,42 @@ func TestSelectInitialInstancesForMirror(t *testing.T) {
 	}
 }
 
+func TestSelectInitialInstancesForMirrorWithFailureDomainPolicy(t *testing.T) {
+	var candidates []placement.Instance
+	for i, domains := range [][]string{{"z1", "r1"}, {"z1", "r2"}, {"z2", "r1"}, {"z2", "r2"}} {
+		candidates = append(candidates, placement.NewInstance().
+			SetID(fmt.Sprintf("h%dp1", i)).
+			SetHostname(fmt.Sprintf("h%d", i)).
+			SetPort(1).
+			SetZone(domains[0]).
+			SetRack(domains[1]).
+			SetEndpoint(fmt.Sprintf("h%dp1e", i)).
+			SetWeight(1))
+	}
+
+	opts := placement.NewOptions().
+		SetFailureDomainPolicy(placement.NewFailureDomainPolicy(placement.ZoneLevel, placement.RackLevel))
+	selector := newMirroredSelector(opts)
+	res, err := selector.SelectInitialInstances(candidates, 2)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(res))
+
+	// Each shard set has one instance in each zone.
+	zonesBySSID := make(map[uint32]map[string]struct{})
+	for _, instance := range res {
+		zones, ok := zonesBySSID[instance.ShardSetID()]
+		if !ok {
+			zones = make(map[string]struct{})
+			zonesBySSID[instance.ShardSetID()] = zones
+		}
+		zones[instance.Zone()] = struct{}{}
+	}
+	require.Equal(t, 2, len(zonesBySSID))
+	for _, zones := range zonesBySSID {
+		require.Equal(t, 2, len(zones))
+	}
+}
+
 func TestSelectInitialInstancesForMirrorRF2(t *testing.T) {
 	h1p1 := placement.NewInstance().
 		SetID("h1p1").
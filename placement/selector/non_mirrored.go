@@ -53,16 +53,24 @@ func (f *nonMirroredFilter) SelectAddingInstances(
 	candidates []placement.Instance,
 	p placement.Placement,
 ) ([]placement.Instance, error) {
-	candidates, err := getValidCandidates(p, candidates, f.opts)
+	opts := placement.OptionsWithPlacementPolicy(p, f.opts)
+	candidates, err := getValidCandidates(p, candidates, opts)
 	if err != nil {
 		return nil, err
 	}
 
+	// NB: the racks are the domains at the outermost failure domain level,
+	// which defaults to racks.
+	policy := placement.PolicyForOptions(opts)
+	domainFn := func(instance placement.Instance) string {
+		return policy.Domain(instance, 0)
+	}
+
 	// build rack-instance map for candidate instances
-	candidateRackMap := buildRackMap(candidates)
+	candidateRackMap := buildDomainMap(candidates, domainFn)
 
 	// build rack-instance map for current placement
-	placementRackMap := buildRackMap(p.Instances())
+	placementRackMap := buildDomainMap(p.Instances(), domainFn)
 
 	// if there is a rack not in the current placement, prefer that rack
 	for r, instances := range candidateRackMap {
@@ -99,7 +107,8 @@ func (f *nonMirroredFilter) SelectReplaceInstances(
 	leavingInstanceIDs []string,
 	p placement.Placement,
 ) ([]placement.Instance, error) {
-	candidates, err := getValidCandidates(p, candidates, f.opts)
+	opts := placement.OptionsWithPlacementPolicy(p, f.opts)
+	candidates, err := getValidCandidates(p, candidates, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -113,27 +122,29 @@ func (f *nonMirroredFilter) SelectReplaceInstances(
 		leavingInstances = append(leavingInstances, leavingInstance)
 	}
 
-	// map rack to instances
-	rackMap := buildRackMap(candidates)
-
-	// otherwise sort the candidate instances by the number of conflicts
-	ph := algo.NewPlacementHelper(p, f.opts)
-	instances := make([]sortableValue, 0, len(rackMap))
-	for rack, instancesInRack := range rackMap {
+	// sort the candidate instances by the number of conflicts
+	ph := algo.NewPlacementHelper(p, opts)
+	hasConflict := func(shardID uint32, from, to placement.Instance) bool {
+		return ph.HasRackConflict(shardID, from, to.Rack())
+	}
+	if usesFailureDomains(opts) {
+		hasConflict = ph.HasDomainConflict
+	}
+	allowConflict := opts.LooseRackCheck() && opts.FailureDomainPolicy().IsEmpty()
+	instances := make([]sortableValue, 0, len(candidates))
+	for _, instance := range candidates {
 		conflicts := 0
 		for _, leaving := range leavingInstances {
 			for _, s := range leaving.Shards().All() {
-				if ph.HasRackConflict(s.ID(), leaving, rack) {
+				if hasConflict(s.ID(), leaving, instance) {
 					conflicts++
 				}
 			}
 		}
-		for _, instance := range instancesInRack {
-			instances = append(instances, sortableValue{value: instance, weight: conflicts})
-		}
+		instances = append(instances, sortableValue{value: instance, weight: conflicts})
 	}
 
-	groups := groupInstancesByConflict(instances, f.opts.LooseRackCheck())
+	groups := groupInstancesByConflict(instances, allowConflict)
 	if len(groups) == 0 {
 		return nil, errNoValidInstance
 	}
@@ -231,13 +242,17 @@ func knapsack(instances []placement.Instance, targetWeight int) ([]placement.Ins
 	panic("should never reach here")
 }
 
-func buildRackMap(candidates []placement.Instance) map[string][]placement.Instance {
+func buildDomainMap(
+	candidates []placement.Instance,
+	domainFn func(instance placement.Instance) string,
+) map[string][]placement.Instance {
 	result := make(map[string][]placement.Instance, len(candidates))
 	for _, instance := range candidates {
-		if _, exist := result[instance.Rack()]; !exist {
-			result[instance.Rack()] = make([]placement.Instance, 0)
+		domain := domainFn(instance)
+		if _, exist := result[domain]; !exist {
+			result[domain] = make([]placement.Instance, 0)
 		}
-		result[instance.Rack()] = append(result[instance.Rack()], instance)
+		result[domain] = append(result[domain], instance)
 	}
 	return result
 }
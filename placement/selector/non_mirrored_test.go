@@ -26,6 +26,7 @@ import (
 	"testing"
 
 	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -260,3 +261,57 @@ func TestFilterZones(t *testing.T) {
 		assert.Equal(t, exp, res)
 	}
 }
+
+func TestSelectReplaceInstancesWithFailureDomainPolicy(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i2 := placement.NewEmptyInstance("i2", "r1", "z2", "endpoint", 1)
+	i2.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2}).
+		SetShards([]uint32{0}).
+		SetReplicaFactor(2).
+		SetIsSharded(true)
+
+	c1 := placement.NewEmptyInstance("c1", "r2", "z2", "endpoint", 1)
+	c2 := placement.NewEmptyInstance("c2", "r2", "z1", "endpoint", 1)
+
+	// c1 would put both replicas of shard 0 in zone z2.
+	opts := placement.NewOptions().
+		SetFailureDomainPolicy(placement.NewFailureDomainPolicy(placement.ZoneLevel, placement.RackLevel))
+	res, err := newNonMirroredSelector(opts).SelectReplaceInstances([]placement.Instance{c1, c2}, []string{"i1"}, p)
+	assert.NoError(t, err)
+	assert.Equal(t, []placement.Instance{c2}, res)
+
+	// Rejected even though the rack check is loosened.
+	_, err = newNonMirroredSelector(opts.SetLooseRackCheck(true)).SelectReplaceInstances([]placement.Instance{c1}, []string{"i1"}, p)
+	assert.Error(t, err)
+}
+
+func TestSelectInstancesWithPlacementPolicy(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i2 := placement.NewEmptyInstance("i2", "r2", "z2", "endpoint", 1)
+	i2.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2}).
+		SetShards([]uint32{0}).
+		SetReplicaFactor(2).
+		SetIsSharded(true).
+		SetFailureDomainPolicy(placement.NewFailureDomainPolicy(placement.ZoneLevel, placement.RackLevel))
+
+	// The zone level policy recorded in the placement is followed even though
+	// the options do not set a policy.
+	c1 := placement.NewEmptyInstance("c1", "r3", "z2", "endpoint", 1)
+	c2 := placement.NewEmptyInstance("c2", "r4", "z3", "endpoint", 1)
+	selector := newNonMirroredSelector(placement.NewOptions())
+
+	res, err := selector.SelectAddingInstances([]placement.Instance{c2}, p)
+	assert.NoError(t, err)
+	assert.Equal(t, []placement.Instance{c2}, res)
+
+	// The candidate in the zone of the other replica conflicts with it.
+	res, err = selector.SelectReplaceInstances([]placement.Instance{c1, c2}, []string{"i1"}, p)
+	assert.NoError(t, err)
+	assert.Equal(t, []placement.Instance{c2}, res)
+}
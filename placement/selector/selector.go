@@ -24,8 +24,13 @@ import (
 	"github.com/m3db/m3cluster/placement"
 )
 
-// NewInstanceSelector creates an instance selector.
+// NewInstanceSelector creates an instance selector, the custom
+// selector in the options is returned if there is one.
 func NewInstanceSelector(opts placement.Options) placement.InstanceSelector {
+	if s := opts.InstanceSelector(); s != nil {
+		return s
+	}
+
 	if opts.IsMirrored() {
 		return newMirroredSelector(opts)
 	}
@@ -21,13 +21,23 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/m3db/m3cluster/generated/proto/placementpb"
+	"github.com/m3db/m3cluster/kv"
 	"github.com/m3db/m3cluster/placement"
 	"github.com/m3db/m3cluster/placement/algo"
 	"github.com/m3db/m3cluster/placement/selector"
 	"github.com/m3db/m3cluster/shard"
 	"github.com/m3db/m3x/log"
+
+	"github.com/golang/protobuf/proto"
+)
+
+var (
+	errPlacementNotStaged           = errors.New("placement is not staged")
+	errInvalidStagedPlacementsProto = errors.New("invalid proto for staged placements")
 )
 
 type placementService struct {
@@ -78,7 +88,7 @@ func (ps *placementService) BuildInitialPlacement(
 		return nil, err
 	}
 
-	if err := placement.Validate(p); err != nil {
+	if err := placement.ValidateWithOptions(p, ps.opts); err != nil {
 		return nil, err
 	}
 
@@ -102,7 +112,41 @@ func (ps *placementService) AddReplica() (placement.Placement, error) {
 		return nil, err
 	}
 
-	if err := placement.Validate(p); err != nil {
+	if err := placement.ValidateWithOptions(p, ps.opts); err != nil {
+		return nil, err
+	}
+
+	return p, ps.CheckAndSet(p, v)
+}
+
+func (ps *placementService) RemoveReplica() (placement.Placement, error) {
+	p, v, err := ps.Placement()
+	if err != nil {
+		return nil, err
+	}
+
+	if p, err = ps.algo.RemoveReplica(p); err != nil {
+		return nil, err
+	}
+
+	if err := placement.ValidateWithOptions(p, ps.opts); err != nil {
+		return nil, err
+	}
+
+	return p, ps.CheckAndSet(p, v)
+}
+
+func (ps *placementService) SplitShards(factor int) (placement.Placement, error) {
+	p, v, err := ps.Placement()
+	if err != nil {
+		return nil, err
+	}
+
+	if p, err = ps.algo.SplitShards(p, factor); err != nil {
+		return nil, err
+	}
+
+	if err := placement.ValidateWithOptions(p, ps.opts); err != nil {
 		return nil, err
 	}
 
@@ -117,6 +161,39 @@ func (ps *placementService) AddInstances(
 		return nil, nil, err
 	}
 
+	p, addingInstances, err := ps.addInstances(p, candidates)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p, addingInstances, ps.CheckAndSet(p, v)
+}
+
+func (ps *placementService) PlanAddInstances(
+	candidates []placement.Instance,
+	maxMoves int,
+) (placement.Plan, []placement.Instance, error) {
+	p, _, err := ps.Placement()
+	if err != nil {
+		return placement.Plan{}, nil, err
+	}
+
+	target, addingInstances, err := ps.addInstances(p, candidates)
+	if err != nil {
+		return placement.Plan{}, nil, err
+	}
+
+	plan, err := placement.NewPlan(p, target, maxMoves, ps.opts)
+	if err != nil {
+		return placement.Plan{}, nil, err
+	}
+	return plan, addingInstances, nil
+}
+
+func (ps *placementService) addInstances(
+	p placement.Placement,
+	candidates []placement.Instance,
+) (placement.Placement, []placement.Instance, error) {
 	addingInstances, err := ps.selector.SelectAddingInstances(candidates, p)
 	if err != nil {
 		return nil, nil, err
@@ -126,7 +203,7 @@ func (ps *placementService) AddInstances(
 		return nil, nil, err
 	}
 
-	if err := placement.Validate(p); err != nil {
+	if err := placement.ValidateWithOptions(p, ps.opts); err != nil {
 		return nil, nil, err
 	}
 
@@ -138,7 +215,7 @@ func (ps *placementService) AddInstances(
 		addingInstances[i] = addingInstance
 	}
 
-	return p, addingInstances, ps.CheckAndSet(p, v)
+	return p, addingInstances, nil
 }
 
 func (ps *placementService) RemoveInstances(instanceIDs []string) (placement.Placement, error) {
@@ -147,15 +224,41 @@ func (ps *placementService) RemoveInstances(instanceIDs []string) (placement.Pla
 		return nil, err
 	}
 
-	if p, err = ps.algo.RemoveInstances(p, instanceIDs); err != nil {
+	if p, err = ps.removeInstances(p, instanceIDs); err != nil {
+		return nil, err
+	}
+
+	return p, ps.CheckAndSet(p, v)
+}
+
+func (ps *placementService) PlanRemoveInstances(instanceIDs []string, maxMoves int) (placement.Plan, error) {
+	p, _, err := ps.Placement()
+	if err != nil {
+		return placement.Plan{}, err
+	}
+
+	target, err := ps.removeInstances(p, instanceIDs)
+	if err != nil {
+		return placement.Plan{}, err
+	}
+
+	return placement.NewPlan(p, target, maxMoves, ps.opts)
+}
+
+func (ps *placementService) removeInstances(
+	p placement.Placement,
+	instanceIDs []string,
+) (placement.Placement, error) {
+	p, err := ps.algo.RemoveInstances(p, instanceIDs)
+	if err != nil {
 		return nil, err
 	}
 
-	if err := placement.Validate(p); err != nil {
+	if err := placement.ValidateWithOptions(p, ps.opts); err != nil {
 		return nil, err
 	}
 
-	return p, ps.CheckAndSet(p, v)
+	return p, nil
 }
 
 func (ps *placementService) ReplaceInstances(
@@ -167,6 +270,41 @@ func (ps *placementService) ReplaceInstances(
 		return nil, nil, err
 	}
 
+	p, addedInstances, err := ps.replaceInstances(p, leavingInstanceIDs, candidates)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p, addedInstances, ps.CheckAndSet(p, v)
+}
+
+func (ps *placementService) PlanReplaceInstances(
+	leavingInstanceIDs []string,
+	candidates []placement.Instance,
+	maxMoves int,
+) (placement.Plan, []placement.Instance, error) {
+	p, _, err := ps.Placement()
+	if err != nil {
+		return placement.Plan{}, nil, err
+	}
+
+	target, addedInstances, err := ps.replaceInstances(p, leavingInstanceIDs, candidates)
+	if err != nil {
+		return placement.Plan{}, nil, err
+	}
+
+	plan, err := placement.NewPlan(p, target, maxMoves, ps.opts)
+	if err != nil {
+		return placement.Plan{}, nil, err
+	}
+	return plan, addedInstances, nil
+}
+
+func (ps *placementService) replaceInstances(
+	p placement.Placement,
+	leavingInstanceIDs []string,
+	candidates []placement.Instance,
+) (placement.Placement, []placement.Instance, error) {
 	addingInstances, err := ps.selector.SelectReplaceInstances(candidates, leavingInstanceIDs, p)
 	if err != nil {
 		return nil, nil, err
@@ -176,7 +314,7 @@ func (ps *placementService) ReplaceInstances(
 		return nil, nil, err
 	}
 
-	if err := placement.Validate(p); err != nil {
+	if err := placement.ValidateWithOptions(p, ps.opts); err != nil {
 		return nil, nil, err
 	}
 
@@ -189,7 +327,161 @@ func (ps *placementService) ReplaceInstances(
 		addedInstances = append(addedInstances, addedInstance)
 	}
 
-	return p, addedInstances, ps.CheckAndSet(p, v)
+	return p, addedInstances, nil
+}
+
+func (ps *placementService) ApplyPlanStep(plan placement.Plan, step int) (placement.Placement, error) {
+	p, v, err := ps.Placement()
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := plan.Expected(step)
+	if err != nil {
+		return nil, err
+	}
+
+	same, err := isSamePlacementIgnoringCutover(expected, p)
+	if err != nil {
+		return nil, err
+	}
+	if !same {
+		return nil, fmt.Errorf("could not apply plan step %d, the placement is not the one expected before it", step)
+	}
+
+	p = setPlanStepTimes(plan.Steps[step], ps.opts)
+	if err := placement.ValidateWithOptions(p, ps.opts); err != nil {
+		return nil, err
+	}
+
+	return p, ps.CheckAndSet(p, v)
+}
+
+// isSamePlacementIgnoringCutover compares the placements, including the weights
+// and attributes of their instances, but for the placement cutover times.
+func isSamePlacementIgnoringCutover(a, b placement.Placement) (bool, error) {
+	pa, err := a.Clone().SetCutoverNanos(0).Proto()
+	if err != nil {
+		return false, err
+	}
+	pb, err := b.Clone().SetCutoverNanos(0).Proto()
+	if err != nil {
+		return false, err
+	}
+	return proto.Equal(pa, pb), nil
+}
+
+// setPlanStepTimes sets the placement cutover time, the cutover times of the
+// Initializing shards and the cutoff times of the Leaving shards of the plan
+// step at the time it is applied.
+func setPlanStepTimes(p placement.Placement, opts placement.Options) placement.Placement {
+	var (
+		shardCutoverNanos = opts.ShardCutoverNanosFn()()
+		shardCutoffNanos  = opts.ShardCutoffNanosFn()()
+	)
+	p = p.Clone()
+	for _, instance := range p.Instances() {
+		for _, s := range instance.Shards().All() {
+			switch s.State() {
+			case shard.Initializing:
+				s.SetCutoverNanos(shardCutoverNanos)
+			case shard.Leaving:
+				s.SetCutoffNanos(shardCutoffNanos)
+			}
+		}
+	}
+	return p.SetCutoverNanos(opts.PlacementCutoverNanosFn()())
+}
+
+func (ps *placementService) Rollback(version int) (placement.Placement, error) {
+	p, v, err := ps.Placement()
+	if err != nil {
+		return nil, err
+	}
+
+	if version <= 0 || version >= v {
+		return nil, fmt.Errorf("could not roll back to version %d, current version is %d", version, v)
+	}
+
+	old, err := ps.PlacementForVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	// NB: the shard moves in progress in the old placement have since been
+	// completed or superseded, writing their states back would restart them,
+	// so the old placement is rolled back to with all its moves completed.
+	if old, err = placement.MarkAllShardsAsAvailable(old); err != nil {
+		return nil, err
+	}
+	if old, err = placement.RemoveAllDroppedReplicas(old); err != nil {
+		return nil, err
+	}
+
+	// NB: the instances added since the old placement would lose their shards
+	// without handing them off.
+	for _, instance := range p.Instances() {
+		if _, ok := old.Instance(instance.ID()); !ok && instance.Shards().NumShards() > 0 {
+			return nil, fmt.Errorf(
+				"could not roll back to version %d, instance %s has been added to the placement and owns shards",
+				version,
+				instance.ID(),
+			)
+		}
+	}
+
+	// NB: the instances removed since the old placement may have been
+	// decommissioned, rolling back would assign shards to them again.
+	for _, instance := range old.Instances() {
+		if _, ok := p.Instance(instance.ID()); !ok {
+			return nil, fmt.Errorf(
+				"could not roll back to version %d, instance %s has been removed from the placement",
+				version,
+				instance.ID(),
+			)
+		}
+	}
+
+	old = old.SetCutoverNanos(ps.opts.PlacementCutoverNanosFn()())
+	if err := placement.ValidateWithOptions(old, ps.opts); err != nil {
+		return nil, err
+	}
+
+	return old, ps.CheckAndSet(old, v)
+}
+
+func (ps *placementService) Rebalance(maxMoves int) (placement.Placement, error) {
+	p, v, err := ps.Placement()
+	if err != nil {
+		return nil, err
+	}
+
+	if p, err = ps.algo.Rebalance(p, maxMoves); err != nil {
+		return nil, err
+	}
+
+	if err := placement.ValidateWithOptions(p, ps.opts); err != nil {
+		return nil, err
+	}
+
+	return p, ps.CheckAndSet(p, v)
+}
+
+func (ps *placementService) UpdateInstanceWeights(weights map[string]uint32) (placement.Placement, error) {
+	p, v, err := ps.Placement()
+	if err != nil {
+		return nil, err
+	}
+
+	if p, err = ps.algo.UpdateInstanceWeights(p, weights); err != nil {
+		return nil, err
+	}
+
+	if err := placement.ValidateWithOptions(p, ps.opts); err != nil {
+		return nil, err
+	}
+
+	return p, ps.CheckAndSet(p, v)
 }
 
 func (ps *placementService) MarkShardAvailable(instanceID string, shardID uint32) error {
@@ -203,7 +495,11 @@ func (ps *placementService) MarkShardAvailable(instanceID string, shardID uint32
 		return err
 	}
 
-	if err := placement.Validate(p); err != nil {
+	if p, err = placement.RemoveDroppedReplicas(p, instanceID); err != nil {
+		return err
+	}
+
+	if err := placement.ValidateWithOptions(p, ps.opts); err != nil {
 		return err
 	}
 
@@ -230,9 +526,91 @@ func (ps *placementService) MarkInstanceAvailable(instanceID string) error {
 		}
 	}
 
-	if err := placement.Validate(p); err != nil {
+	if p, err = placement.RemoveDroppedReplicas(p, instanceID); err != nil {
+		return err
+	}
+
+	if err := placement.ValidateWithOptions(p, ps.opts); err != nil {
 		return err
 	}
 
 	return ps.CheckAndSet(p, v)
 }
+
+func (ps *placementService) StagedPlacements() (placement.Placements, int, error) {
+	if !ps.opts.IsStaged() {
+		return nil, 0, errPlacementNotStaged
+	}
+
+	m, v, err := ps.Proto()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	placementsProto, ok := m.(*placementpb.PlacementSnapshots)
+	if !ok {
+		return nil, 0, errInvalidStagedPlacementsProto
+	}
+
+	placements, err := placement.NewPlacementsFromProto(placementsProto)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i, p := range placements {
+		placements[i] = p.SetVersion(v)
+	}
+	return placements, v, nil
+}
+
+func (ps *placementService) AppendStagedPlacement(p placement.Placement) (placement.Placement, error) {
+	placements, v, err := ps.StagedPlacements()
+	if err != nil && err != kv.ErrNotFound {
+		return nil, err
+	}
+
+	cutoverNanos := ps.opts.PlacementCutoverNanosFn()()
+	if l := len(placements); l > 0 && cutoverNanos <= placements[l-1].CutoverNanos() {
+		return nil, fmt.Errorf(
+			"could not append staged placement, cutover time %d is not after the last cutover time %d",
+			cutoverNanos,
+			placements[l-1].CutoverNanos(),
+		)
+	}
+
+	p = p.Clone().SetCutoverNanos(cutoverNanos)
+	if err := placement.ValidateWithOptions(p, ps.opts); err != nil {
+		return nil, err
+	}
+
+	placementsProto, err := append(placements, p).Proto()
+	if err != nil {
+		return nil, err
+	}
+
+	return p, ps.CheckAndSetProto(placementsProto, v)
+}
+
+func (ps *placementService) PruneStagedPlacements(timeNanos int64) (placement.Placements, error) {
+	placements, v, err := ps.StagedPlacements()
+	if err != nil {
+		return nil, err
+	}
+
+	// NB: the active placement and the ones after it are kept, only the
+	// placements that can no longer take effect are removed.
+	idx := placements.ActiveIndex(timeNanos)
+	if idx <= 0 {
+		return nil, nil
+	}
+
+	placementsProto, err := placements[idx:].Proto()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ps.CheckAndSetProto(placementsProto, v); err != nil {
+		return nil, err
+	}
+	return placements[:idx], nil
+}
@@ -22,13 +22,17 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 
 	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/kv/mem"
 	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/placement/storage"
 	"github.com/m3db/m3cluster/shard"
 
+	"github.com/golang/mock/gomock"
 	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -470,6 +474,45 @@ func TestMarkShard(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestMarkShardRemovesDroppedReplicas(t *testing.T) {
+	ms := NewMockStorage()
+
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(2).SetState(shard.Leaving))
+	i1.Shards().Add(shard.NewShard(3).SetState(shard.Initializing).SetSourceID("i3"))
+
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i2.Shards().Add(shard.NewShard(1).SetState(shard.Leaving))
+	i2.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+
+	i3 := placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1)
+	i3.Shards().Add(shard.NewShard(3).SetState(shard.Leaving))
+
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2, i3}).
+		SetShards([]uint32{1, 2, 3}).
+		SetReplicaFactor(1).
+		SetIsSharded(true)
+	require.NoError(t, ms.SetIfNotExist(p))
+
+	ps := NewPlacementService(ms, placement.NewOptions().SetValidZone("z1"))
+	require.NoError(t, ps.MarkShardAvailable("i1", 3))
+	p, _, err := ms.Placement()
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+
+	// The replica of shard 2 dropped from i1 is removed, the one on i2 is kept
+	// until i2 is marked as available.
+	i1, ok := p.Instance("i1")
+	require.True(t, ok)
+	assert.Equal(t, []uint32{1, 3}, i1.Shards().AllIDs())
+	assert.Equal(t, 2, i1.Shards().NumShardsForState(shard.Available))
+	i2, ok = p.Instance("i2")
+	require.True(t, ok)
+	assert.Equal(t, 1, i2.Shards().NumShardsForState(shard.Leaving))
+}
+
 func TestMarkInstance(t *testing.T) {
 	ms := NewMockStorage()
 
@@ -523,6 +566,552 @@ func TestMarkInstance(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestRebalance(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i3 := placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1)
+	for id := uint32(0); id < 12; id++ {
+		switch {
+		case id < 8:
+			i1.Shards().Add(shard.NewShard(id).SetState(shard.Available))
+		case id < 10:
+			i2.Shards().Add(shard.NewShard(id).SetState(shard.Available))
+		default:
+			i3.Shards().Add(shard.NewShard(id).SetState(shard.Available))
+		}
+	}
+	ids := make([]uint32, 12)
+	for i := range ids {
+		ids[i] = uint32(i)
+	}
+	ms := NewMockStorage()
+	require.NoError(t, ms.SetIfNotExist(placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2, i3}).
+		SetShards(ids).
+		SetReplicaFactor(1).
+		SetIsSharded(true)))
+
+	ps := NewPlacementService(ms, placement.NewOptions().SetValidZone("z1"))
+	p, err := ps.Rebalance(3)
+	require.NoError(t, err)
+	i1, _ = p.Instance("i1")
+	assert.Equal(t, 3, i1.Shards().NumShardsForState(shard.Leaving))
+	numInitializing := 0
+	for _, instance := range p.Instances() {
+		for _, s := range instance.Shards().ShardsForState(shard.Initializing) {
+			assert.Equal(t, "i1", s.SourceID())
+			numInitializing++
+		}
+	}
+	assert.Equal(t, 3, numInitializing)
+
+	markAllInstancesAvailable(t, ps)
+	p, err = ps.Rebalance(0)
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	for _, instance := range p.Instances() {
+		assert.Equal(t, 4, instance.Shards().NumShards())
+	}
+
+	// Nothing to move in a balanced placement.
+	_, v, err := ps.Placement()
+	require.NoError(t, err)
+	p, err = ps.Rebalance(0)
+	require.NoError(t, err)
+	for _, instance := range p.Instances() {
+		assert.Equal(t, 4, instance.Shards().NumShardsForState(shard.Available))
+	}
+	_, newVersion, err := ps.Placement()
+	require.NoError(t, err)
+	assert.Equal(t, v+1, newVersion)
+
+	_, err = NewPlacementService(NewMockStorage(), placement.NewOptions()).Rebalance(0)
+	assert.Error(t, err)
+}
+
+func TestRemoveReplica(t *testing.T) {
+	ps := NewPlacementService(NewMockStorage(), placement.NewOptions().SetValidZone("z1"))
+	_, err := ps.RemoveReplica()
+	assert.Error(t, err)
+
+	_, err = ps.BuildInitialPlacement([]placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1),
+	}, 12, 2)
+	require.NoError(t, err)
+
+	p, err := ps.RemoveReplica()
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.ReplicaFactor())
+	for _, instance := range p.Instances() {
+		assert.Equal(t, 4, instance.Shards().NumShardsForState(shard.Available))
+		assert.Equal(t, 4, instance.Shards().NumShardsForState(shard.Leaving))
+	}
+
+	// The dropped replicas are removed once the instances are marked as available.
+	for _, instance := range p.Instances() {
+		require.NoError(t, ps.MarkInstanceAvailable(instance.ID()))
+	}
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	for _, instance := range p.Instances() {
+		assert.Equal(t, 4, instance.Shards().NumShards())
+		assert.Equal(t, 4, instance.Shards().NumShardsForState(shard.Available))
+	}
+
+	_, err = ps.RemoveReplica()
+	assert.Error(t, err)
+}
+
+func TestSplitShards(t *testing.T) {
+	ps := NewPlacementService(NewMockStorage(), placement.NewOptions().SetValidZone("z1"))
+	_, err := ps.SplitShards(2)
+	assert.Error(t, err)
+
+	_, err = ps.BuildInitialPlacement([]placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1),
+	}, 4, 1)
+	require.NoError(t, err)
+
+	_, err = ps.SplitShards(1)
+	assert.Error(t, err)
+
+	p, err := ps.SplitShards(2)
+	require.NoError(t, err)
+	assert.Equal(t, 8, p.NumShards())
+	for _, instance := range p.Instances() {
+		assert.Equal(t, 2, instance.Shards().NumShardsForState(shard.Available))
+		assert.Equal(t, 2, instance.Shards().NumShardsForState(shard.Initializing))
+		for _, s := range instance.Shards().ShardsForState(shard.Initializing) {
+			assert.True(t, instance.Shards().Contains(s.ID()%4))
+		}
+	}
+
+	// The shard splits are persisted with the placement.
+	markAllInstancesAvailable(t, ps)
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	assert.Equal(t, map[uint32][]uint32{0: {0, 4}, 1: {1, 5}, 2: {2, 6}, 3: {3, 7}}, p.ShardSplits())
+	for _, instance := range p.Instances() {
+		assert.Equal(t, 4, instance.Shards().NumShardsForState(shard.Available))
+	}
+}
+
+func TestUpdateInstanceWeights(t *testing.T) {
+	ps := NewPlacementService(NewMockStorage(), placement.NewOptions().SetValidZone("z1"))
+	_, err := ps.UpdateInstanceWeights(map[string]uint32{"i1": 2})
+	assert.Error(t, err)
+
+	_, err = ps.BuildInitialPlacement([]placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1),
+	}, 12, 1)
+	require.NoError(t, err)
+
+	_, err = ps.UpdateInstanceWeights(map[string]uint32{"i3": 2})
+	assert.Error(t, err)
+
+	p, err := ps.UpdateInstanceWeights(map[string]uint32{"i1": 2})
+	require.NoError(t, err)
+	i1, ok := p.Instance("i1")
+	require.True(t, ok)
+	assert.Equal(t, uint32(2), i1.Weight())
+	assert.Equal(t, 2, i1.Shards().NumShardsForState(shard.Initializing))
+
+	markAllInstancesAvailable(t, ps)
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	i1, _ = p.Instance("i1")
+	assert.Equal(t, 8, i1.Shards().NumShards())
+}
+
+func TestPlanAddInstances(t *testing.T) {
+	var nowNanos int64
+	opts := placement.NewOptions().
+		SetValidZone("z1").
+		SetPlacementCutoverNanosFn(func() int64 { return nowNanos }).
+		SetShardCutoverNanosFn(func() int64 { return nowNanos + 1 }).
+		SetShardCutoffNanosFn(func() int64 { return nowNanos + 2 })
+	ps := NewPlacementService(NewMockStorage(), opts)
+	_, err := ps.BuildInitialPlacement([]placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1),
+	}, 64, 2)
+	require.NoError(t, err)
+	before, _, err := ps.Placement()
+	require.NoError(t, err)
+
+	plan, added, err := ps.PlanAddInstances([]placement.Instance{
+		placement.NewEmptyInstance("i4", "r4", "z1", "endpoint", 1),
+	}, 5)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(added))
+	assert.Equal(t, "i4", added[0].ID())
+	require.True(t, len(plan.Steps) > 1)
+
+	// Planning does not change the placement.
+	p, _, err := ps.Placement()
+	require.NoError(t, err)
+	assert.True(t, placement.NewPlacementDiff(before, p).IsEmpty())
+
+	_, err = ps.ApplyPlanStep(plan, 1)
+	assert.Error(t, err)
+	_, err = ps.ApplyPlanStep(plan, len(plan.Steps))
+	assert.Error(t, err)
+
+	for i, step := range plan.Steps {
+		// The times are set when the step is applied, not when it is planned.
+		nowNanos = int64(1000 * (i + 1))
+		p, err := ps.ApplyPlanStep(plan, i)
+		require.NoError(t, err)
+		assert.Equal(t, nowNanos, p.CutoverNanos())
+		assert.Equal(t, step.NumInstances(), p.NumInstances())
+		for _, instance := range p.Instances() {
+			stepInstance, ok := step.Instance(instance.ID())
+			require.True(t, ok)
+			assert.Equal(t, stepInstance.Shards().NumShards(), instance.Shards().NumShards())
+			for _, s := range instance.Shards().ShardsForState(shard.Initializing) {
+				assert.Equal(t, nowNanos+1, s.CutoverNanos())
+			}
+			for _, s := range instance.Shards().ShardsForState(shard.Leaving) {
+				assert.Equal(t, nowNanos+2, s.CutoffNanos())
+			}
+		}
+
+		initializing := 0
+		for _, instance := range p.Instances() {
+			initializing += instance.Shards().NumShardsForState(shard.Initializing)
+		}
+		assert.True(t, initializing <= 5)
+
+		// The next step is only applied once the shards are available.
+		if i+1 < len(plan.Steps) {
+			_, err = ps.ApplyPlanStep(plan, i+1)
+			assert.Error(t, err)
+		}
+		markAllInstancesAvailable(t, ps)
+	}
+
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	i4, ok := p.Instance("i4")
+	require.True(t, ok)
+	assert.Equal(t, 32, i4.Shards().NumShardsForState(shard.Available))
+}
+
+func TestApplyPlanStepAfterWeightChange(t *testing.T) {
+	ms := NewMockStorage()
+	ps := NewPlacementService(ms, placement.NewOptions().SetValidZone("z1"))
+	_, err := ps.BuildInitialPlacement([]placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1),
+	}, 16, 1)
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+
+	plan, _, err := ps.PlanAddInstances([]placement.Instance{
+		placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1),
+	}, 2)
+	require.NoError(t, err)
+
+	// The weight change moves no shard, but the placement is no longer the
+	// one the plan was made from.
+	p, v, err := ms.Placement()
+	require.NoError(t, err)
+	p = p.Clone()
+	i1, ok := p.Instance("i1")
+	require.True(t, ok)
+	i1.SetWeight(2)
+	require.NoError(t, ms.CheckAndSet(p, v))
+
+	_, err = ps.ApplyPlanStep(plan, 0)
+	assert.Error(t, err)
+}
+
+func TestPlanRemoveAndReplaceInstances(t *testing.T) {
+	ps := NewPlacementService(NewMockStorage(), placement.NewOptions().SetValidZone("z1"))
+	_, err := ps.BuildInitialPlacement([]placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i4", "r4", "z1", "endpoint", 1),
+	}, 32, 2)
+	require.NoError(t, err)
+
+	plan, err := ps.PlanRemoveInstances([]string{"i4"}, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 4, len(plan.Steps))
+	for i := range plan.Steps {
+		_, err := ps.ApplyPlanStep(plan, i)
+		require.NoError(t, err)
+		markAllInstancesAvailable(t, ps)
+	}
+	p, _, err := ps.Placement()
+	require.NoError(t, err)
+	_, ok := p.Instance("i4")
+	assert.False(t, ok)
+	i3, ok := p.Instance("i3")
+	require.True(t, ok)
+
+	plan, added, err := ps.PlanReplaceInstances([]string{"i3"}, []placement.Instance{
+		placement.NewEmptyInstance("i5", "r3", "z1", "endpoint", 1),
+	}, 8)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(added))
+	assert.Equal(t, (i3.Shards().NumShards()+7)/8, len(plan.Steps))
+	for i := range plan.Steps {
+		_, err := ps.ApplyPlanStep(plan, i)
+		require.NoError(t, err)
+		markAllInstancesAvailable(t, ps)
+	}
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	_, ok = p.Instance("i3")
+	assert.False(t, ok)
+	i5, ok := p.Instance("i5")
+	require.True(t, ok)
+	assert.Equal(t, i3.Shards().NumShards(), i5.Shards().NumShardsForState(shard.Available))
+
+	_, err = ps.PlanRemoveInstances([]string{"bad"}, 4)
+	assert.Error(t, err)
+}
+
+func TestCustomAlgorithmAndSelector(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	candidates := []placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1),
+	}
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Initializing))
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Initializing))
+	expected := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1}).
+		SetShards([]uint32{0, 1}).
+		SetReplicaFactor(1).
+		SetIsSharded(true)
+
+	s := placement.NewMockInstanceSelector(ctrl)
+	s.EXPECT().SelectInitialInstances(candidates, 1).Return(candidates[:1], nil)
+	a := placement.NewMockAlgorithm(ctrl)
+	a.EXPECT().InitialPlacement(candidates[:1], []uint32{0, 1}, 1).Return(expected, nil)
+
+	ps := NewPlacementService(NewMockStorage(), placement.NewOptions().
+		SetValidZone("z1").
+		SetAlgorithm(a).
+		SetInstanceSelector(s))
+	p, err := ps.BuildInitialPlacement(candidates, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.NumInstances())
+	i1, ok := p.Instance("i1")
+	require.True(t, ok)
+	assert.Equal(t, 2, i1.Shards().NumShardsForState(shard.Available))
+}
+
+func TestStagedPlacements(t *testing.T) {
+	var nowNanos int64
+	opts := placement.NewOptions().
+		SetValidZone("z1").
+		SetIsStaged(true).
+		SetPlacementCutoverNanosFn(func() int64 { return nowNanos })
+	ps := NewPlacementService(storage.NewPlacementStorage(mem.NewStore(), "key", opts), opts)
+
+	_, _, err := ps.StagedPlacements()
+	assert.Equal(t, kv.ErrNotFound, err)
+	_, err = ps.PruneStagedPlacements(100)
+	assert.Equal(t, kv.ErrNotFound, err)
+
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1}).
+		SetShards([]uint32{0}).
+		SetReplicaFactor(1).
+		SetIsSharded(true)
+
+	for _, cutover := range []int64{100, 200, 300} {
+		nowNanos = cutover
+		appended, err := ps.AppendStagedPlacement(p)
+		require.NoError(t, err)
+		assert.Equal(t, cutover, appended.CutoverNanos())
+	}
+	assert.Equal(t, int64(0), p.CutoverNanos())
+
+	// The cutover time must be later than the last one.
+	_, err = ps.AppendStagedPlacement(p)
+	assert.Error(t, err)
+
+	placements, v, err := ps.StagedPlacements()
+	require.NoError(t, err)
+	assert.Equal(t, 3, v)
+	require.Len(t, placements, 3)
+	for i, cutover := range []int64{100, 200, 300} {
+		assert.Equal(t, cutover, placements[i].CutoverNanos())
+		assert.Equal(t, 3, placements[i].GetVersion())
+	}
+
+	last, _, err := ps.Placement()
+	require.NoError(t, err)
+	assert.Equal(t, int64(300), last.CutoverNanos())
+
+	removed, err := ps.PruneStagedPlacements(50)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+	removed, err = ps.PruneStagedPlacements(100)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+
+	removed, err = ps.PruneStagedPlacements(250)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, int64(100), removed[0].CutoverNanos())
+
+	placements, v, err = ps.StagedPlacements()
+	require.NoError(t, err)
+	assert.Equal(t, 4, v)
+	require.Len(t, placements, 2)
+	assert.Equal(t, int64(200), placements[0].CutoverNanos())
+	assert.Equal(t, int64(300), placements[1].CutoverNanos())
+}
+
+func TestRollback(t *testing.T) {
+	opts := placement.NewOptions().SetValidZone("z1")
+	ps := NewPlacementService(storage.NewPlacementStorage(mem.NewStore(), "key", opts), opts)
+
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i3 := placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1)
+	_, err := ps.BuildInitialPlacement([]placement.Instance{i1, i2}, 10, 1)
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+
+	_, _, err = ps.AddInstances([]placement.Instance{i3})
+	require.NoError(t, err)
+	_, addedVersion, err := ps.Placement()
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+
+	_, v, err := ps.Placement()
+	require.NoError(t, err)
+	_, err = ps.Rollback(v)
+	assert.Error(t, err)
+	_, err = ps.Rollback(0)
+	assert.Error(t, err)
+
+	// i3 owns shards that would be lost without a handoff.
+	_, err = ps.Rollback(1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "instance i3 has been added to the placement and owns shards")
+
+	_, err = ps.RemoveInstances([]string{"i3"})
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+
+	// The shards of the initial placement were Initializing, they are rolled
+	// back to as available.
+	p, err := ps.Rollback(1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.NumInstances())
+	current, _, err := ps.Placement()
+	require.NoError(t, err)
+	for _, instance := range current.Instances() {
+		assert.Equal(t, 5, instance.Shards().NumShards())
+		assert.Equal(t, 5, instance.Shards().NumShardsForState(shard.Available))
+	}
+
+	// i3 is not in the placement after the rollback, so rolling forward to
+	// the placements with i3 is not safe.
+	_, err = ps.Rollback(addedVersion)
+	assert.Error(t, err)
+
+	_, err = ps.RemoveInstances([]string{"i1"})
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+
+	_, err = ps.Rollback(1)
+	assert.Error(t, err)
+}
+
+func TestRollbackCompletesShardMoves(t *testing.T) {
+	opts := placement.NewOptions().SetValidZone("z1")
+	ps := NewPlacementService(storage.NewPlacementStorage(mem.NewStore(), "key", opts), opts)
+
+	_, err := ps.BuildInitialPlacement([]placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1),
+	}, 12, 1)
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+
+	// i3 hands its shards over to i1 and i2 in the version rolled back to.
+	_, err = ps.RemoveInstances([]string{"i3"})
+	require.NoError(t, err)
+	_, removedVersion, err := ps.Placement()
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+
+	p, err := ps.Rollback(removedVersion)
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.NumInstances())
+	for _, instance := range p.Instances() {
+		assert.Equal(t, 6, instance.Shards().NumShards())
+		assert.Equal(t, 6, instance.Shards().NumShardsForState(shard.Available))
+	}
+}
+
+func TestCustomValidators(t *testing.T) {
+	maxShards := placement.NewValidator("max-shards", func(p placement.Placement) error {
+		for _, instance := range p.Instances() {
+			if instance.Shards().NumShards() > 4 {
+				return fmt.Errorf("instance %s owns more than 4 shards", instance.ID())
+			}
+		}
+		return nil
+	})
+	opts := placement.NewOptions().
+		SetValidZone("z1").
+		SetValidators([]placement.Validator{maxShards})
+	ps := NewPlacementService(storage.NewPlacementStorage(mem.NewStore(), "key", opts), opts)
+
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i3 := placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1)
+	_, err := ps.BuildInitialPlacement([]placement.Instance{i1, i2}, 12, 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "validator max-shards failed: instance")
+
+	_, err = ps.BuildInitialPlacement([]placement.Instance{i1, i2, i3}, 12, 1)
+	require.NoError(t, err)
+
+	_, err = ps.RemoveInstances([]string{"i3"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "validator max-shards failed")
+
+	p, _, err := ps.Placement()
+	require.NoError(t, err)
+	assert.Equal(t, 3, p.NumInstances())
+}
+
+func TestStagedPlacementsNotStaged(t *testing.T) {
+	ps := NewPlacementService(NewMockStorage(), placement.NewOptions())
+
+	_, _, err := ps.StagedPlacements()
+	assert.Equal(t, errPlacementNotStaged, err)
+	_, err = ps.AppendStagedPlacement(placement.NewPlacement())
+	assert.Equal(t, errPlacementNotStaged, err)
+	_, err = ps.PruneStagedPlacements(0)
+	assert.Equal(t, errPlacementNotStaged, err)
+}
+
 func TestFindReplaceInstance(t *testing.T) {
 	i1 := placement.NewEmptyInstance("i1", "r11", "z1", "endpoint", 1)
 	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
@@ -868,6 +1457,14 @@ func (ms *mockStorage) Proto() (proto.Message, int, error) {
 	return nil, 0, errors.New("not implemented")
 }
 
+func (ms *mockStorage) History(from, to int) ([]placement.Placement, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (ms *mockStorage) PlacementForVersion(version int) (placement.Placement, error) {
+	return nil, errors.New("not implemented")
+}
+
 func markAllInstancesAvailable(
 	t *testing.T,
 	ps placement.Service,
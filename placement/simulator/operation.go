@@ -0,0 +1,299 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package simulator
+
+import (
+	"fmt"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
+)
+
+// Operation is a placement operation applied on a simulated cluster.
+type Operation interface {
+	fmt.Stringer
+
+	// Apply applies the operation on the cluster.
+	Apply(c *Cluster) error
+}
+
+// Cluster is a synthetic cluster whose placement is kept in memory.
+type Cluster struct {
+	ps     placement.Service
+	zone   string
+	nextID int
+}
+
+// Service returns the placement service of the cluster.
+func (c *Cluster) Service() placement.Service {
+	return c.ps
+}
+
+// NewInstances returns num new synthetic instances with weight 1, spread
+// in turn across numRacks racks named r0 to r<numRacks-1>.
+func (c *Cluster) NewInstances(num, numRacks int) []placement.Instance {
+	instances := make([]placement.Instance, num)
+	for i := range instances {
+		instances[i] = c.newInstance(fmt.Sprintf("r%d", c.nextID%numRacks))
+	}
+	return instances
+}
+
+func (c *Cluster) newInstance(rack string) placement.Instance {
+	id := fmt.Sprintf("i%d", c.nextID)
+	c.nextID++
+	return placement.NewEmptyInstance(id, rack, c.zone, id, 1).SetHostname(id)
+}
+
+// MarkAllAvailable marks all the Initializing shards in the placement as
+// available, which also drops the Leaving shards they take over.
+func (c *Cluster) MarkAllAvailable() error {
+	p, _, err := c.ps.Placement()
+	if err != nil {
+		return err
+	}
+	for _, instance := range p.Instances() {
+		if instance.Shards().NumShardsForState(shard.Initializing) == 0 {
+			continue
+		}
+		if err := c.ps.MarkInstanceAvailable(instance.ID()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nonLeavingInstanceIDs returns the ids of the first num instances of the
+// placement that are not leaving, in id order.
+func (c *Cluster) nonLeavingInstanceIDs(num int) ([]string, error) {
+	p, _, err := c.ps.Placement()
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, instance := range p.Instances() {
+		if len(ids) == num {
+			break
+		}
+		if !instance.IsLeaving() {
+			ids = append(ids, instance.ID())
+		}
+	}
+	if len(ids) < num {
+		return nil, fmt.Errorf("could not find %d instances in the placement, found %d", num, len(ids))
+	}
+	return ids, nil
+}
+
+type initialPlacementOp struct {
+	numInstances int
+	numRacks     int
+	numShards    int
+	rf           int
+}
+
+// InitialPlacement builds the initial placement with numShards shards and
+// replica factor rf on numInstances new instances across numRacks racks.
+func InitialPlacement(numInstances, numRacks, numShards, rf int) Operation {
+	return initialPlacementOp{numInstances: numInstances, numRacks: numRacks, numShards: numShards, rf: rf}
+}
+
+func (op initialPlacementOp) Apply(c *Cluster) error {
+	_, err := c.ps.BuildInitialPlacement(c.NewInstances(op.numInstances, op.numRacks), op.numShards, op.rf)
+	return err
+}
+
+func (op initialPlacementOp) String() string {
+	return fmt.Sprintf("initial placement of %d shards with rf %d on %d instances across %d racks",
+		op.numShards, op.rf, op.numInstances, op.numRacks)
+}
+
+type addInstancesOp struct {
+	num      int
+	numRacks int
+}
+
+// AddInstances adds num new instances across numRacks racks, one at a time,
+// letting the instance selector pick the next one from the remaining instances.
+func AddInstances(num, numRacks int) Operation {
+	return addInstancesOp{num: num, numRacks: numRacks}
+}
+
+func (op addInstancesOp) Apply(c *Cluster) error {
+	candidates := c.NewInstances(op.num, op.numRacks)
+	for len(candidates) > 0 {
+		_, added, err := c.ps.AddInstances(candidates)
+		if err != nil {
+			return err
+		}
+		if len(added) == 0 {
+			return fmt.Errorf("no instance added from %d candidates", len(candidates))
+		}
+		candidates = withoutInstances(candidates, added)
+	}
+	return nil
+}
+
+func (op addInstancesOp) String() string {
+	return fmt.Sprintf("add %d instances across %d racks", op.num, op.numRacks)
+}
+
+type removeInstancesOp struct {
+	num int
+}
+
+// RemoveInstances removes the first num instances of the placement in id order.
+func RemoveInstances(num int) Operation {
+	return removeInstancesOp{num: num}
+}
+
+func (op removeInstancesOp) Apply(c *Cluster) error {
+	ids, err := c.nonLeavingInstanceIDs(op.num)
+	if err != nil {
+		return err
+	}
+	_, err = c.ps.RemoveInstances(ids)
+	return err
+}
+
+func (op removeInstancesOp) String() string {
+	return fmt.Sprintf("remove %d instances", op.num)
+}
+
+type replaceInstancesOp struct {
+	num int
+}
+
+// ReplaceInstances replaces the first num instances of the placement in id
+// order one at a time, each with a new instance on the same rack.
+func ReplaceInstances(num int) Operation {
+	return replaceInstancesOp{num: num}
+}
+
+func (op replaceInstancesOp) Apply(c *Cluster) error {
+	ids, err := c.nonLeavingInstanceIDs(op.num)
+	if err != nil {
+		return err
+	}
+	p, _, err := c.ps.Placement()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		leaving, ok := p.Instance(id)
+		if !ok {
+			return fmt.Errorf("instance %s does not exist in placement", id)
+		}
+		candidates := []placement.Instance{c.newInstance(leaving.Rack())}
+		if _, _, err := c.ps.ReplaceInstances([]string{id}, candidates); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (op replaceInstancesOp) String() string {
+	return fmt.Sprintf("replace %d instances", op.num)
+}
+
+type addReplicaOp struct{}
+
+// AddReplica increases the replica factor by one.
+func AddReplica() Operation {
+	return addReplicaOp{}
+}
+
+func (op addReplicaOp) Apply(c *Cluster) error {
+	_, err := c.ps.AddReplica()
+	return err
+}
+
+func (op addReplicaOp) String() string {
+	return "add replica"
+}
+
+type removeReplicaOp struct{}
+
+// RemoveReplica decreases the replica factor by one.
+func RemoveReplica() Operation {
+	return removeReplicaOp{}
+}
+
+func (op removeReplicaOp) Apply(c *Cluster) error {
+	_, err := c.ps.RemoveReplica()
+	return err
+}
+
+func (op removeReplicaOp) String() string {
+	return "remove replica"
+}
+
+type rebalanceOp struct {
+	maxMoves int
+}
+
+// Rebalance rebalances the placement moving at most maxMoves shards, or as
+// many as needed when maxMoves is 0.
+func Rebalance(maxMoves int) Operation {
+	return rebalanceOp{maxMoves: maxMoves}
+}
+
+func (op rebalanceOp) Apply(c *Cluster) error {
+	_, err := c.ps.Rebalance(op.maxMoves)
+	return err
+}
+
+func (op rebalanceOp) String() string {
+	if op.maxMoves == 0 {
+		return "rebalance"
+	}
+	return fmt.Sprintf("rebalance at most %d shards", op.maxMoves)
+}
+
+type markAllAvailableOp struct{}
+
+// MarkAllAvailable marks all the shards as available, which is only needed
+// when the simulator does not do it after each operation.
+func MarkAllAvailable() Operation {
+	return markAllAvailableOp{}
+}
+
+func (op markAllAvailableOp) Apply(c *Cluster) error {
+	return c.MarkAllAvailable()
+}
+
+func (op markAllAvailableOp) String() string {
+	return "mark all shards available"
+}
+
+func withoutInstances(instances, removing []placement.Instance) []placement.Instance {
+	ids := make(map[string]struct{}, len(removing))
+	for _, instance := range removing {
+		ids[instance.ID()] = struct{}{}
+	}
+	var res []placement.Instance
+	for _, instance := range instances {
+		if _, ok := ids[instance.ID()]; !ok {
+			res = append(res, instance)
+		}
+	}
+	return res
+}
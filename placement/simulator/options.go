@@ -0,0 +1,119 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package simulator
+
+import (
+	"time"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3x/clock"
+)
+
+const (
+	defaultZone          = "zone1"
+	defaultPlacementKey  = "placement"
+	defaultMarkAvailable = true
+)
+
+// Options are the options for the placement simulator.
+type Options interface {
+	// PlacementOptions returns the options of the placement service the
+	// operations are run with.
+	PlacementOptions() placement.Options
+
+	// SetPlacementOptions sets the options of the placement service the
+	// operations are run with.
+	SetPlacementOptions(value placement.Options) Options
+
+	// Zone returns the zone of the synthetic instances.
+	Zone() string
+
+	// SetZone sets the zone of the synthetic instances.
+	SetZone(value string) Options
+
+	// MarkAvailable returns whether all the shards are marked as available
+	// after each operation, as if every handoff completed before the next
+	// operation starts.
+	MarkAvailable() bool
+
+	// SetMarkAvailable sets whether all the shards are marked as available
+	// after each operation.
+	SetMarkAvailable(value bool) Options
+
+	// NowFn returns the function used to time the operations.
+	NowFn() clock.NowFn
+
+	// SetNowFn sets the function used to time the operations.
+	SetNowFn(value clock.NowFn) Options
+}
+
+type options struct {
+	placementOpts placement.Options
+	zone          string
+	markAvailable bool
+	nowFn         clock.NowFn
+}
+
+// NewOptions returns a default Options.
+func NewOptions() Options {
+	return options{
+		placementOpts: placement.NewOptions(),
+		zone:          defaultZone,
+		markAvailable: defaultMarkAvailable,
+		nowFn:         time.Now,
+	}
+}
+
+func (o options) PlacementOptions() placement.Options {
+	return o.placementOpts
+}
+
+func (o options) SetPlacementOptions(value placement.Options) Options {
+	o.placementOpts = value
+	return o
+}
+
+func (o options) Zone() string {
+	return o.zone
+}
+
+func (o options) SetZone(value string) Options {
+	o.zone = value
+	return o
+}
+
+func (o options) MarkAvailable() bool {
+	return o.markAvailable
+}
+
+func (o options) SetMarkAvailable(value bool) Options {
+	o.markAvailable = value
+	return o
+}
+
+func (o options) NowFn() clock.NowFn {
+	return o.nowFn
+}
+
+func (o options) SetNowFn(value clock.NowFn) Options {
+	o.nowFn = value
+	return o
+}
@@ -0,0 +1,291 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package simulator simulates sequences of placement operations on synthetic
+// clusters, running the real placement service and algorithms on an in-memory
+// store, and reports the cost and the outcome of each operation.
+package simulator
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/placement/health"
+	"github.com/m3db/m3cluster/placement/service"
+	"github.com/m3db/m3cluster/placement/storage"
+	"github.com/m3db/m3cluster/shard"
+)
+
+// Scenario is a named sequence of operations run on a new cluster.
+type Scenario struct {
+	Name       string
+	Operations []Operation
+}
+
+// StepReport is the outcome of an operation in a scenario.
+type StepReport struct {
+	// Operation describes the operation.
+	Operation string
+
+	// ShardsMoved is the number of replicas placed on instances that did not
+	// own them before the operation.
+	ShardsMoved int
+
+	// NumInstances is the number of instances not leaving the placement.
+	NumInstances int
+
+	// MinLoad and MaxLoad are the lowest and the highest number of replicas
+	// owned by an instance not leaving the placement.
+	MinLoad int
+	MaxLoad int
+
+	// PeakToAverage is the highest ratio between the load on an instance and
+	// its share of all the replicas by weight, 1 being perfectly balanced.
+	PeakToAverage float64
+
+	// RackConflicts is the number of shards with more than one replica on
+	// the same rack.
+	RackConflicts int
+
+	// Duration is how long the operation took to run.
+	Duration time.Duration
+
+	// Err is the error the operation failed with, if any.
+	Err error
+}
+
+// Report is the outcome of a scenario. The steps after a failed operation
+// are not run.
+type Report struct {
+	Scenario string
+	Steps    []StepReport
+}
+
+// Err returns the error of the failed operation, if any.
+func (r Report) Err() error {
+	for _, step := range r.Steps {
+		if step.Err != nil {
+			return step.Err
+		}
+	}
+	return nil
+}
+
+// TotalShardsMoved returns the number of replicas moved by all the operations.
+func (r Report) TotalShardsMoved() int {
+	total := 0
+	for _, step := range r.Steps {
+		total += step.ShardsMoved
+	}
+	return total
+}
+
+// TotalDuration returns how long all the operations took to run.
+func (r Report) TotalDuration() time.Duration {
+	var total time.Duration
+	for _, step := range r.Steps {
+		total += step.Duration
+	}
+	return total
+}
+
+// String returns the summary of the scenario with a line per operation.
+func (r Report) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "scenario %s\n", r.Scenario)
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "step\toperation\tmoved\tinstances\tmin load\tmax load\tpeak/avg\track conflicts\tduration\terror")
+	for i, step := range r.Steps {
+		errMsg := "-"
+		if step.Err != nil {
+			errMsg = step.Err.Error()
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%d\t%.3f\t%d\t%v\t%s\n",
+			i+1, step.Operation, step.ShardsMoved, step.NumInstances, step.MinLoad, step.MaxLoad,
+			step.PeakToAverage, step.RackConflicts, step.Duration, errMsg)
+	}
+	w.Flush()
+	fmt.Fprintf(&buf, "total: %d shards moved in %v\n", r.TotalShardsMoved(), r.TotalDuration())
+	return buf.String()
+}
+
+// Simulator runs scenarios of placement operations.
+type Simulator interface {
+	// Run runs the scenario on a new cluster and returns its report.
+	Run(s Scenario) Report
+}
+
+type simulator struct {
+	opts Options
+}
+
+// NewSimulator returns a new placement Simulator.
+func NewSimulator(opts Options) Simulator {
+	return simulator{opts: opts}
+}
+
+func (s simulator) Run(scenario Scenario) Report {
+	popts := s.opts.PlacementOptions().SetValidZone(s.opts.Zone())
+	c := &Cluster{
+		ps: service.NewPlacementService(
+			storage.NewPlacementStorage(mem.NewStore(), defaultPlacementKey, popts),
+			popts,
+		),
+		zone: s.opts.Zone(),
+	}
+	analyzer := health.NewAnalyzer(health.NewOptions().SetPlacementOptions(popts))
+
+	r := Report{Scenario: scenario.Name}
+	for _, op := range scenario.Operations {
+		step := s.runStep(c, op, analyzer)
+		r.Steps = append(r.Steps, step)
+		if step.Err != nil {
+			break
+		}
+	}
+	return r
+}
+
+func (s simulator) runStep(c *Cluster, op Operation, analyzer health.Analyzer) StepReport {
+	step := StepReport{Operation: op.String()}
+	before, err := currentPlacement(c)
+	if err != nil {
+		step.Err = err
+		return step
+	}
+
+	start := s.opts.NowFn()()
+	err = op.Apply(c)
+	step.Duration = s.opts.NowFn()().Sub(start)
+	if err != nil {
+		step.Err = err
+		return step
+	}
+
+	after, err := currentPlacement(c)
+	if err != nil {
+		step.Err = err
+		return step
+	}
+	if after != nil {
+		step.ShardsMoved = shardsMoved(before, after)
+		setBalance(&step, after)
+		step.RackConflicts = rackConflicts(analyzer.Analyze(after))
+	}
+
+	if s.opts.MarkAvailable() && after != nil {
+		step.Err = c.MarkAllAvailable()
+	}
+	return step
+}
+
+// currentPlacement returns the placement of the cluster, or nil if it has
+// not been built yet.
+func currentPlacement(c *Cluster) (placement.Placement, error) {
+	p, _, err := c.ps.Placement()
+	if err == kv.ErrNotFound {
+		return nil, nil
+	}
+	return p, err
+}
+
+// shardsMoved returns the number of non leaving replicas in the placement
+// after the operation that were not owned by the same instance before it.
+func shardsMoved(before, after placement.Placement) int {
+	moved := 0
+	for _, instance := range after.Instances() {
+		for _, s := range instance.Shards().All() {
+			if s.State() == shard.Leaving {
+				continue
+			}
+			if before != nil && ownsShard(before, instance.ID(), s.ID()) {
+				continue
+			}
+			moved++
+		}
+	}
+	return moved
+}
+
+func ownsShard(p placement.Placement, instanceID string, shardID uint32) bool {
+	instance, ok := p.Instance(instanceID)
+	if !ok {
+		return false
+	}
+	s, ok := instance.Shards().Shard(shardID)
+	return ok && s.State() != shard.Leaving
+}
+
+func setBalance(step *StepReport, p placement.Placement) {
+	var (
+		loads       = make(map[string]int, p.NumInstances())
+		totalLoad   = 0
+		totalWeight = uint32(0)
+	)
+	for _, instance := range p.Instances() {
+		if instance.IsLeaving() {
+			continue
+		}
+		load := instance.Shards().NumShards() - instance.Shards().NumShardsForState(shard.Leaving)
+		loads[instance.ID()] = load
+		totalLoad += load
+		totalWeight += instance.Weight()
+	}
+
+	step.NumInstances = len(loads)
+	if len(loads) == 0 || totalLoad == 0 || totalWeight == 0 {
+		return
+	}
+
+	step.MinLoad = totalLoad
+	for _, instance := range p.Instances() {
+		load, ok := loads[instance.ID()]
+		if !ok {
+			continue
+		}
+		if load < step.MinLoad {
+			step.MinLoad = load
+		}
+		if load > step.MaxLoad {
+			step.MaxLoad = load
+		}
+		share := float64(totalLoad) * float64(instance.Weight()) / float64(totalWeight)
+		if share == 0 {
+			continue
+		}
+		if ratio := float64(load) / share; ratio > step.PeakToAverage {
+			step.PeakToAverage = ratio
+		}
+	}
+}
+
+func rackConflicts(r health.Report) int {
+	for _, f := range r.Findings {
+		if f.Check == health.CheckRackSpread {
+			return len(f.ShardIDs)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package simulator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3cluster/placement"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunScenario(t *testing.T) {
+	now := time.Unix(0, 0)
+	opts := NewOptions().SetNowFn(func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	})
+
+	r := NewSimulator(opts).Run(Scenario{
+		Name: "grow",
+		Operations: []Operation{
+			InitialPlacement(6, 3, 64, 2),
+			AddInstances(10, 4),
+			ReplaceInstances(3),
+			RemoveInstances(2),
+			AddReplica(),
+			Rebalance(0),
+		},
+	})
+	require.NoError(t, r.Err(), r.String())
+	require.Equal(t, 6, len(r.Steps))
+
+	initial := r.Steps[0]
+	assert.Equal(t, "initial placement of 64 shards with rf 2 on 6 instances across 3 racks", initial.Operation)
+	assert.Equal(t, 128, initial.ShardsMoved)
+	assert.Equal(t, 6, initial.NumInstances)
+	assert.True(t, initial.PeakToAverage >= 1)
+
+	add := r.Steps[1]
+	assert.Equal(t, 16, add.NumInstances)
+	assert.True(t, add.ShardsMoved > 0 && add.ShardsMoved < 128)
+
+	assert.Equal(t, 16, r.Steps[2].NumInstances)
+	assert.Equal(t, 14, r.Steps[3].NumInstances)
+	assert.Equal(t, 64, r.Steps[4].ShardsMoved)
+
+	for _, step := range r.Steps {
+		assert.Equal(t, 0, step.RackConflicts, step.Operation)
+		assert.Equal(t, time.Millisecond, step.Duration)
+		assert.True(t, step.MinLoad <= step.MaxLoad)
+	}
+	assert.Equal(t, 6*time.Millisecond, r.TotalDuration())
+
+	total := 0
+	for _, step := range r.Steps {
+		total += step.ShardsMoved
+	}
+	assert.Equal(t, total, r.TotalShardsMoved())
+
+	summary := r.String()
+	assert.True(t, strings.HasPrefix(summary, "scenario grow\n"))
+	assert.Contains(t, summary, "replace 3 instances")
+	assert.Equal(t, 9, strings.Count(summary, "\n"))
+}
+
+func TestRunScenarioStopsOnError(t *testing.T) {
+	r := NewSimulator(NewOptions()).Run(Scenario{
+		Name: "bad",
+		Operations: []Operation{
+			InitialPlacement(3, 3, 16, 3),
+			RemoveInstances(4),
+			AddReplica(),
+		},
+	})
+	require.Error(t, r.Err())
+	require.Equal(t, 2, len(r.Steps))
+	assert.NoError(t, r.Steps[0].Err)
+	assert.Error(t, r.Steps[1].Err)
+	assert.Contains(t, r.String(), r.Steps[1].Err.Error())
+}
+
+func TestRunScenarioWithoutMarkAvailable(t *testing.T) {
+	r := NewSimulator(NewOptions().SetMarkAvailable(false)).Run(Scenario{
+		Name: "handoff",
+		Operations: []Operation{
+			InitialPlacement(4, 4, 32, 2),
+			AddInstances(1, 1),
+			MarkAllAvailable(),
+			RemoveInstances(1),
+		},
+	})
+	require.NoError(t, r.Err())
+	assert.Equal(t, 0, r.Steps[2].ShardsMoved)
+	assert.Equal(t, 4, r.Steps[3].NumInstances)
+}
+
+func TestRunScenarioWithPlacementOptions(t *testing.T) {
+	popts := placement.NewOptions().SetIsConsistentHashing(true)
+	r := NewSimulator(NewOptions().SetPlacementOptions(popts)).Run(Scenario{
+		Name: "consistent hashing",
+		Operations: []Operation{
+			InitialPlacement(8, 4, 256, 3),
+			AddInstances(1, 4),
+			RemoveReplica(),
+		},
+	})
+	require.NoError(t, r.Err())
+	assert.True(t, r.Steps[1].ShardsMoved <= 2*256*3/9)
+	assert.Equal(t, 0, r.Steps[2].ShardsMoved)
+}
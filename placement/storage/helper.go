@@ -50,6 +50,9 @@ type Helper interface {
 
 	// ValidateProto validates if the given proto message is valid for placement.
 	ValidateProto(proto proto.Message) error
+
+	// History retrieves the placements with versions in range [from, to) from kv.Store.
+	History(from, to int) ([]placement.Placement, error)
 }
 
 // newHelper returns a new placement storage helper.
@@ -58,18 +61,20 @@ func newHelper(store kv.Store, key string, opts placement.Options) Helper {
 		return newStagedPlacementHelper(store, key)
 	}
 
-	return newPlacementHelper(store, key)
+	return newPlacementHelper(store, key, opts)
 }
 
 type placementHelper struct {
 	store kv.Store
 	key   string
+	opts  placement.Options
 }
 
-func newPlacementHelper(store kv.Store, key string) Helper {
+func newPlacementHelper(store kv.Store, key string, opts placement.Options) Helper {
 	return &placementHelper{
 		store: store,
 		key:   key,
+		opts:  opts,
 	}
 }
 
@@ -97,6 +102,10 @@ func (h *placementHelper) GenerateProto(p placement.Placement) (proto.Message, e
 	return p.Proto()
 }
 
+func (h *placementHelper) History(from, to int) ([]placement.Placement, error) {
+	return history(h.store, h.key, from, to, placementFromValue)
+}
+
 func (h *placementHelper) ValidateProto(proto proto.Message) error {
 	placementProto, ok := proto.(*placementpb.Placement)
 	if !ok {
@@ -108,7 +117,7 @@ func (h *placementHelper) ValidateProto(proto proto.Message) error {
 		return err
 	}
 
-	return placement.Validate(p)
+	return placement.ValidateWithOptions(p, h.opts)
 }
 
 type stagedPlacementHelper struct {
@@ -159,6 +168,22 @@ func (h *stagedPlacementHelper) GenerateProto(p placement.Placement) (proto.Mess
 	return ps.Proto()
 }
 
+// History returns the last placement in the snapshots of each version.
+func (h *stagedPlacementHelper) History(from, to int) ([]placement.Placement, error) {
+	return history(h.store, h.key, from, to, func(v kv.Value) (placement.Placement, error) {
+		ps, err := placementsFromValue(v)
+		if err != nil {
+			return nil, err
+		}
+
+		l := len(ps)
+		if l == 0 {
+			return nil, errNoPlacementInTheSnapshots
+		}
+		return ps[l-1], nil
+	})
+}
+
 func (h *stagedPlacementHelper) ValidateProto(proto proto.Message) error {
 	placementsProto, ok := proto.(*placementpb.PlacementSnapshots)
 	if !ok {
@@ -227,3 +252,25 @@ func placementsFromValue(v kv.Value) (placement.Placements, error) {
 	}
 	return ps, nil
 }
+
+func history(
+	store kv.Store,
+	key string,
+	from, to int,
+	fn func(v kv.Value) (placement.Placement, error),
+) ([]placement.Placement, error) {
+	values, err := store.History(key, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]placement.Placement, 0, len(values))
+	for _, v := range values {
+		p, err := fn(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
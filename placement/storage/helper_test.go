@@ -25,6 +25,7 @@ import (
 
 	"github.com/m3db/m3cluster/generated/proto/placementpb"
 	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/m3db/m3cluster/placement"
 
 	"github.com/stretchr/testify/require"
 )
@@ -62,7 +63,7 @@ func TestPlacementHelper(t *testing.T) {
 	_, err := store.Set(key, proto1)
 	require.NoError(t, err)
 
-	helper := newPlacementHelper(store, key)
+	helper := newPlacementHelper(store, key, placement.NewOptions())
 
 	p, v, err := helper.Placement()
 	require.NoError(t, err)
@@ -21,6 +21,8 @@
 package storage
 
 import (
+	"fmt"
+
 	"github.com/m3db/m3cluster/kv"
 	"github.com/m3db/m3cluster/placement"
 	"github.com/m3db/m3x/log"
@@ -78,7 +80,7 @@ func (s *storage) Proto() (proto.Message, int, error) {
 }
 
 func (s *storage) Set(p placement.Placement) error {
-	if err := placement.Validate(p); err != nil {
+	if err := placement.ValidateWithOptions(p, s.opts); err != nil {
 		return err
 	}
 
@@ -97,7 +99,7 @@ func (s *storage) Set(p placement.Placement) error {
 }
 
 func (s *storage) CheckAndSet(p placement.Placement, version int) error {
-	if err := placement.Validate(p); err != nil {
+	if err := placement.ValidateWithOptions(p, s.opts); err != nil {
 		return err
 	}
 
@@ -120,7 +122,7 @@ func (s *storage) CheckAndSet(p placement.Placement, version int) error {
 }
 
 func (s *storage) SetIfNotExist(p placement.Placement) error {
-	if err := placement.Validate(p); err != nil {
+	if err := placement.ValidateWithOptions(p, s.opts); err != nil {
 		return err
 	}
 
@@ -154,3 +156,19 @@ func (s *storage) Delete() error {
 func (s *storage) Placement() (placement.Placement, int, error) {
 	return s.helper.Placement()
 }
+
+func (s *storage) History(from, to int) ([]placement.Placement, error) {
+	return s.helper.History(from, to)
+}
+
+func (s *storage) PlacementForVersion(version int) (placement.Placement, error) {
+	ps, err := s.helper.History(version, version+1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ps) != 1 {
+		return nil, fmt.Errorf("could not find placement for version %d", version)
+	}
+	return ps[0], nil
+}
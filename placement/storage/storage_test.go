@@ -21,6 +21,7 @@
 package storage
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/m3db/m3cluster/generated/proto/placementpb"
@@ -147,6 +148,65 @@ func TestStorageWithPlacementSnapshots(t *testing.T) {
 	require.Equal(t, p.SetVersion(1), pGet3)
 }
 
+func TestHistory(t *testing.T) {
+	for _, opts := range []placement.Options{
+		placement.NewOptions(),
+		placement.NewOptions().SetIsStaged(true),
+	} {
+		ps := newTestPlacementStorage(mem.NewStore(), opts)
+
+		_, err := ps.History(1, 2)
+		require.Equal(t, kv.ErrNotFound, err)
+
+		p := placement.NewPlacement().
+			SetInstances([]placement.Instance{}).
+			SetShards([]uint32{}).
+			SetReplicaFactor(0)
+		require.NoError(t, ps.SetIfNotExist(p.SetCutoverNanos(100)))
+		require.NoError(t, ps.CheckAndSet(p.SetCutoverNanos(200), 1))
+		require.NoError(t, ps.CheckAndSet(p.SetCutoverNanos(300), 2))
+
+		history, err := ps.History(1, 4)
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		for i, cutover := range []int64{100, 200, 300} {
+			require.Equal(t, i+1, history[i].GetVersion())
+			require.Equal(t, cutover, history[i].CutoverNanos())
+		}
+
+		pGet, err := ps.PlacementForVersion(2)
+		require.NoError(t, err)
+		require.Equal(t, 2, pGet.GetVersion())
+		require.Equal(t, int64(200), pGet.CutoverNanos())
+
+		_, err = ps.PlacementForVersion(4)
+		require.Error(t, err)
+	}
+}
+
+func TestCustomValidators(t *testing.T) {
+	alwaysFail := placement.NewValidator("always-fail", func(placement.Placement) error {
+		return errors.New("failed")
+	})
+	ps := newTestPlacementStorage(mem.NewStore(), placement.NewOptions().
+		SetValidators([]placement.Validator{alwaysFail}))
+
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{}).
+		SetShards([]uint32{}).
+		SetReplicaFactor(0)
+
+	err := ps.SetIfNotExist(p)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "validator always-fail failed: failed")
+
+	pProto, err := p.Proto()
+	require.NoError(t, err)
+	err = ps.SetProto(pProto)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "always-fail")
+}
+
 func TestCheckAndSetProto(t *testing.T) {
 	m := mem.NewStore()
 	ps := newTestPlacementStorage(m, placement.NewOptions())
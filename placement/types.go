@@ -91,6 +91,17 @@ type Instance interface {
 	// SetPort sets the port of the instance.
 	SetPort(value uint32) Instance
 
+	// FailureDomains returns the failure domains of the instance keyed by level.
+	FailureDomains() map[string]string
+
+	// SetFailureDomains sets the failure domains of the instance keyed by level.
+	SetFailureDomains(value map[string]string) Instance
+
+	// FailureDomain returns the failure domain of the instance at the given level,
+	// the zone, rack and host levels fall back to the zone, rack and hostname of
+	// the instance when not set explicitly.
+	FailureDomain(level string) string
+
 	// Proto returns the proto representation for the Instance.
 	Proto() (*placementpb.Instance, error)
 
@@ -154,6 +165,19 @@ type Placement interface {
 	// SetIsMirrored() sets IsMirrored.
 	SetIsMirrored(v bool) Placement
 
+	// FailureDomainPolicy returns the failure domain policy the placement follows.
+	FailureDomainPolicy() FailureDomainPolicy
+
+	// SetFailureDomainPolicy sets the failure domain policy the placement follows.
+	SetFailureDomainPolicy(policy FailureDomainPolicy) Placement
+
+	// ShardSplits returns the child shards of each parent shard split by the latest
+	// shard split, the first child of a parent shard keeps the id of the parent.
+	ShardSplits() map[uint32][]uint32
+
+	// SetShardSplits sets the child shards of each parent shard.
+	SetShardSplits(splits map[uint32][]uint32) Placement
+
 	// String returns a description of the placement
 	String() string
 
@@ -297,6 +321,18 @@ type StagedPlacement interface {
 // TimeNanosFn returns the time in the format of Unix nanoseconds.
 type TimeNanosFn func() int64
 
+// ValidateFn validates a placement.
+type ValidateFn func(p Placement) error
+
+// Validator validates a placement with a custom rule.
+type Validator interface {
+	// Name returns the name of the validator, which is included in the validation errors.
+	Name() string
+
+	// Validate validates the placement.
+	Validate(p Placement) error
+}
+
 // Options is the interface for placement options.
 type Options interface {
 	// LooseRackCheck enables the placement to loose the rack check
@@ -339,6 +375,43 @@ type Options interface {
 	// SetIsStaged sets whether the placement should keep all the snapshots.
 	SetIsStaged(v bool) Options
 
+	// IsConsistentHashing returns whether the shards of a sharded placement
+	// should be assigned with consistent hashing rather than the greedy
+	// rack-aware algorithm.
+	IsConsistentHashing() bool
+
+	// SetIsConsistentHashing sets IsConsistentHashing.
+	SetIsConsistentHashing(v bool) Options
+
+	// VirtualNodesPerWeight returns the number of virtual nodes each unit of
+	// instance weight places on the hash ring when consistent hashing is used.
+	VirtualNodesPerWeight() int
+
+	// SetVirtualNodesPerWeight sets VirtualNodesPerWeight.
+	SetVirtualNodesPerWeight(n int) Options
+
+	// Algorithm returns the custom placement algorithm, which takes precedence
+	// over the algorithms chosen by IsSharded, IsMirrored and IsConsistentHashing.
+	// It is nil by default.
+	Algorithm() Algorithm
+
+	// SetAlgorithm sets the custom placement algorithm.
+	SetAlgorithm(algorithm Algorithm) Options
+
+	// InstanceSelector returns the custom instance selector, which takes
+	// precedence over the selector chosen by IsMirrored. It is nil by default.
+	InstanceSelector() InstanceSelector
+
+	// SetInstanceSelector sets the custom instance selector.
+	SetInstanceSelector(selector InstanceSelector) Options
+
+	// Validators returns the custom validators, which run after the
+	// built-in checks wherever placements are validated.
+	Validators() []Validator
+
+	// SetValidators sets the custom validators.
+	SetValidators(validators []Validator) Options
+
 	// InstrumentOptions is the options for instrument.
 	InstrumentOptions() instrument.Options
 
@@ -356,6 +429,26 @@ type Options interface {
 	// instance.
 	SetValidZone(z string) Options
 
+	// SpreadAcrossZones returns whether the replicas of each shard should be
+	// spread across zones, with racks providing the isolation within each
+	// zone, rather than spread across racks in a single zone.
+	SpreadAcrossZones() bool
+
+	// SetSpreadAcrossZones sets whether the replicas of each shard should be
+	// spread across zones. Instances from any zone may then be added to the
+	// placement, and the ValidZone is ignored.
+	SetSpreadAcrossZones(v bool) Options
+
+	// FailureDomainPolicy returns the failure domain policy the replicas of
+	// each shard are spread across.
+	FailureDomainPolicy() FailureDomainPolicy
+
+	// SetFailureDomainPolicy sets the failure domain policy the replicas of each
+	// shard are spread across. The policy is recorded on the placement and checked
+	// when the placement is validated, LooseRackCheck does not apply to it, and
+	// instances from any zone may be added when it has a region or zone level.
+	SetFailureDomainPolicy(policy FailureDomainPolicy) Options
+
 	// PlacementCutoverNanosFn returns the TimeNanosFn for placement cutover time.
 	PlacementCutoverNanosFn() TimeNanosFn
 
@@ -402,6 +495,17 @@ type Storage interface {
 
 	// Proto returns the placement proto.
 	Proto() (proto.Message, int, error)
+
+	// History returns the past placements with versions in range [from, to),
+	// each placement carries its version and its cutover time. The kv store does
+	// not record when each version was written, so the cutover time, which the
+	// placement service sets when it writes the placement, stands in for the write
+	// time. It is later than the write time for the placements set to take effect
+	// in the future.
+	History(from, to int) ([]Placement, error)
+
+	// PlacementForVersion returns the placement of the given version.
+	PlacementForVersion(version int) (Placement, error)
 }
 
 // Service handles the placement related operations for registered services
@@ -415,6 +519,14 @@ type Service interface {
 	// AddReplica up the replica factor by 1 in the placement.
 	AddReplica() (Placement, error)
 
+	// RemoveReplica lowers the replica factor by 1 in the placement, the dropped replicas
+	// are marked as Leaving and removed once their instances are marked as available.
+	RemoveReplica() (Placement, error)
+
+	// SplitShards increases the number of shards in the placement by splitting each
+	// shard into factor child shards owned by the same instances as the parent shard.
+	SplitShards(factor int) (Placement, error)
+
 	// AddInstances adds instances from the candidate list to the placement.
 	AddInstances(candidates []Instance) (newPlacement Placement, addedInstances []Instance, err error)
 
@@ -431,11 +543,65 @@ type Service interface {
 		err error,
 	)
 
-	// MarkShardAvailable marks the state of a shard as available.
+	// MarkShardAvailable marks the state of a shard as available, and removes
+	// the replicas dropped from the instance by lowering the replica factor.
 	MarkShardAvailable(instanceID string, shardID uint32) error
 
-	// MarkInstanceAvailable marks all the shards on a given instance as available.
+	// MarkInstanceAvailable marks all the shards on a given instance as available,
+	// and removes the replicas dropped from the instance by lowering the replica factor.
 	MarkInstanceAvailable(instanceID string) error
+
+	// Rebalance moves shards from the instances owning more than their share of shards
+	// by weight to those owning less, moving at most maxMoves shards when maxMoves is
+	// positive. The moving shards are Initializing on the new owners and Leaving on the
+	// old owners.
+	Rebalance(maxMoves int) (Placement, error)
+
+	// UpdateInstanceWeights updates the weights of the given instances keyed by instance id,
+	// and moves as many shards as needed for the instances to reach their new target loads.
+	UpdateInstanceWeights(weights map[string]uint32) (Placement, error)
+
+	// PlanAddInstances plans adding instances picked from the candidates in steps
+	// moving at most maxMoves shards each, without changing the placement.
+	PlanAddInstances(candidates []Instance, maxMoves int) (Plan, []Instance, error)
+
+	// PlanRemoveInstances plans removing the given instances in steps moving at
+	// most maxMoves shards each, without changing the placement.
+	PlanRemoveInstances(leavingInstanceIDs []string, maxMoves int) (Plan, error)
+
+	// PlanReplaceInstances plans replacing the given instances with instances
+	// picked from the candidates in steps moving at most maxMoves shards each,
+	// without changing the placement.
+	PlanReplaceInstances(
+		leavingInstanceIDs []string,
+		candidates []Instance,
+		maxMoves int,
+	) (Plan, []Instance, error)
+
+	// ApplyPlanStep applies the step of the plan with the given index. The
+	// placement must be the one expected before the step, with all the shards
+	// of the previous step marked as available. The cutover and cutoff times
+	// of the step are set when it is applied.
+	ApplyPlanStep(plan Plan, step int) (Placement, error)
+
+	// StagedPlacements returns the staged placements ordered by cutover time and
+	// their version, only available when the placement is staged.
+	StagedPlacements() (Placements, int, error)
+
+	// AppendStagedPlacement appends the placement to the staged placements with
+	// the cutover time from the PlacementCutoverNanosFn, which must be later than
+	// the cutover time of the last staged placement.
+	AppendStagedPlacement(p Placement) (Placement, error)
+
+	// Rollback writes the placement of the given past version as the current placement
+	// with a new cutover time, with the shard moves in progress in the past placement
+	// completed. It fails if any instance in the past placement has since been removed
+	// from the placement, or if any instance added since owns shards.
+	Rollback(version int) (Placement, error)
+
+	// PruneStagedPlacements removes the staged placements that can no longer take
+	// effect at timeNanos, i.e. the ones before the active placement, and returns them.
+	PruneStagedPlacements(timeNanos int64) (Placements, error)
 }
 
 // Algorithm places shards on instances.
@@ -446,6 +612,14 @@ type Algorithm interface {
 	// AddReplica up the replica factor by 1 in the placement.
 	AddReplica(p Placement) (Placement, error)
 
+	// RemoveReplica lowers the replica factor by 1 in the placement, picking the replica
+	// of each shard to drop so that the replicas stay spread and balanced.
+	RemoveReplica(p Placement) (Placement, error)
+
+	// SplitShards splits each shard in the placement into factor child shards and
+	// records the child shards of each parent shard in the placement.
+	SplitShards(p Placement, factor int) (Placement, error)
+
 	// AddInstances adds a list of instance to the placement.
 	AddInstances(p Placement, instances []Instance) (Placement, error)
 
@@ -461,6 +635,14 @@ type Algorithm interface {
 
 	// IsCompatibleWith checks whether the algorithm could be applied to given placement.
 	IsCompatibleWith(p Placement) error
+
+	// Rebalance moves at most maxMoves shards, or as many as needed when maxMoves
+	// is not positive, to balance the load on the instances by weight.
+	Rebalance(p Placement, maxMoves int) (Placement, error)
+
+	// UpdateInstanceWeights updates the weights of the given instances keyed by instance id,
+	// and moves shards for the load on the instances to match the new weights.
+	UpdateInstanceWeights(p Placement, weights map[string]uint32) (Placement, error)
 }
 
 // InstanceSelector selects valid instances for the placement change.